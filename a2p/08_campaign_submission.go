@@ -3,6 +3,7 @@
 package a2p
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -18,8 +19,8 @@ import (
 
 // Step 8.2: CheckA2PCampaignStatus checks the status of an A2P campaign(Optional)
 
-func (s *A2PService) CheckA2PCampaignStatus(messagingServiceSid string, campaignSid string) (string, error) {
-	resp, err := s.client.MessagingV1.FetchUsAppToPerson(messagingServiceSid, campaignSid)
+func (s *A2PService) CheckA2PCampaignStatus(ctx context.Context, messagingServiceSid string, campaignSid string) (string, error) {
+	resp, err := s.messaging.FetchUsAppToPerson(ctx, messagingServiceSid, campaignSid)
 	if err != nil {
 		return "", fmt.Errorf("failed to check A2P Campaign status: %w", err)
 	}
@@ -3,13 +3,14 @@
 package a2p
 
 import (
+	"context"
 	"fmt"
 
 	messaging "github.com/twilio/twilio-go/rest/messaging/v1"
 )
 
 // Step 9.1: Finalize Messaging Service Configuration
-func (s *A2PService) FinalizeMessagingServiceConfig(data FinalizeMessagingServiceConfigData) (string, error) {
+func (s *A2PService) FinalizeMessagingServiceConfig(ctx context.Context, data FinalizeMessagingServiceConfigData) (string, error) {
 	params := &messaging.UpdateServiceParams{}
 	params.SetStatusCallback(data.StatusCallback)
 	params.SetStickySender(data.StickySender)
@@ -21,7 +22,7 @@ func (s *A2PService) FinalizeMessagingServiceConfig(data FinalizeMessagingServic
 	params.SetValidityPeriod(data.ValidityPeriod)
 	params.SetSynchronousValidation(data.SynchronousValidation)
 
-	resp, err := s.client.MessagingV1.UpdateService(data.MessagingServiceSid, params)
+	resp, err := s.messaging.UpdateService(ctx, data.MessagingServiceSid, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to finalize Messaging Service config: %w", err)
 	}
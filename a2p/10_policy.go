@@ -2,6 +2,7 @@
 package a2p
 
 import (
+	"context"
 	openapi "github.com/twilio/twilio-go/rest/trusthub/v1"
 )
 
@@ -15,9 +16,9 @@ type PolicyInfo struct {
 // Step 10.1: Fetch Available Policies
 // PolicySId already set to hard coded value, so this function is not needed. (optional)
 // Do not include this function in the final code.
-func (s *A2PService) ListPolicies(sid string, pageSize, limit *int) (*openapi.TrusthubV1Policies, error) {
+func (s *A2PService) ListPolicies(ctx context.Context, sid string, pageSize, limit *int) (*openapi.TrusthubV1Policies, error) {
 
-	policies, err := s.client.TrusthubV1.FetchPolicies(sid)
+	policies, err := s.trustHub.FetchPolicies(ctx, sid)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package a2p
 
 import (
+	"log/slog"
 	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -12,6 +13,10 @@ type CustomerProfileData struct {
 	Email          string `json:"email"`
 	StatusCallback string `json:"status_callback"`
 	PolicySid      string `json:"policy_sid"`
+	// CorrelationKey, such as the LocationID, is appended to the
+	// FriendlyName of the created resource so create-or-reuse lookups only
+	// match this customer. The other Data types use it the same way.
+	CorrelationKey string `json:"correlation_key"`
 }
 
 type BusinessInfoData struct {
@@ -25,18 +30,20 @@ type BusinessInfoData struct {
 	BusinessIdentity           string `json:"business_identity"`
 	BusinessIndustry           string `json:"business_industry"`
 	BusinessRegistrationNumber string `json:"business_registration_number"`
+	CorrelationKey             string `json:"correlation_key"`
 }
 
 type EndUserAuthorizedRep1BusinessInfoData struct {
-	SID           string `json:"end_user_rep1_sid"`
-	Type          string `json:"type"`
-	Position      string `json:"position"`
-	FirstName     string `json:"first_name"`
-	LastName      string `json:"last_name"`
-	Email         string `json:"email"`
-	PhoneNumber   string `json:"phone"`
-	BusinessTitle string `json:"business_name"`
-	FriendlyName  string `json:"friendly_name"`
+	SID            string `json:"end_user_rep1_sid"`
+	Type           string `json:"type"`
+	Position       string `json:"position"`
+	FirstName      string `json:"first_name"`
+	LastName       string `json:"last_name"`
+	Email          string `json:"email"`
+	PhoneNumber    string `json:"phone"`
+	BusinessTitle  string `json:"business_name"`
+	FriendlyName   string `json:"friendly_name"`
+	CorrelationKey string `json:"correlation_key"`
 }
 
 type EndUserAssignmentData struct {
@@ -56,12 +63,14 @@ type AddressData struct {
 	IsoCountry      string `json:"iso_country"`
 	FriendlyName    string `json:"friendly_name"`
 	StreetSecondary string `json:"street_secondary"`
+	CorrelationKey  string `json:"correlation_key"`
 }
 
 type SupportingDocumentData struct {
-	SID          string `json:"supporting_document_sid"`
-	FriendlyName string `json:"friendly_name"`
-	AddressSid   string `json:"address_sid"`
+	SID            string `json:"supporting_document_sid"`
+	FriendlyName   string `json:"friendly_name"`
+	AddressSid     string `json:"address_sid"`
+	CorrelationKey string `json:"correlation_key"`
 }
 
 type TrustProductData struct {
@@ -70,13 +79,15 @@ type TrustProductData struct {
 	Email          string `json:"email"`
 	PolicySid      string `json:"policy_sid"`
 	StatusCallback string `json:"status_callback"`
+	CorrelationKey string `json:"correlation_key"`
 }
 
 type EndUserMessagingProfileData struct {
-	SID           string `json:"end_user_messaging_profile_sid"`
-	CompanyType   string `json:"company_type"`
-	StockExchange string `json:"stock_exchange"`
-	StockTicker   string `json:"stock_ticker"`
+	SID            string `json:"end_user_messaging_profile_sid"`
+	CompanyType    string `json:"company_type"`
+	StockExchange  string `json:"stock_exchange"`
+	StockTicker    string `json:"stock_ticker"`
+	CorrelationKey string `json:"correlation_key"`
 }
 
 /*
@@ -95,6 +106,7 @@ type MessagingServiceData struct {
 	FriendlyName      string `json:"friendly_name"`
 	InboundRequestUrl string `json:"inbound_request_url"`
 	FallbackUrl       string `json:"fallback_url"`
+	CorrelationKey    string `json:"correlation_key"`
 }
 
 type CampaignData struct {
@@ -125,6 +137,7 @@ type MessagingServiceAdditional struct {
 	ValidityPeriod        int    `json:"validity_period"`
 	SynchronousValidation bool   `json:"synchronous_validation"`
 	Usecase               string `json:"usecase"`
+	CorrelationKey        string `json:"correlation_key"`
 }
 
 type MessageStatusData struct {
@@ -201,7 +214,7 @@ type FullA2POnboardingParams struct {
 }
 
 func (f *FullA2POnboardingParams) Validate() error {
-	return validation.ValidateStruct(&f,
+	return validation.ValidateStruct(f,
 		validation.Field(&f.CustomerName, validation.Required),
 		validation.Field(&f.Email, validation.Required),
 		validation.Field(&f.PhoneNumber, validation.Required),
@@ -231,6 +244,19 @@ func (f *FullA2POnboardingParams) Validate() error {
 
 }
 
+// LogValue keeps credentials out of logs when the params are logged as a
+// whole; only identifying fields are emitted.
+func (f FullA2POnboardingParams) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("location_id", f.LocationID),
+		slog.String("subaccount_id", f.SubaccountID),
+		slog.String("friendly_name", f.FriendlyName),
+		slog.String("business_name", f.BusinessName),
+		slog.String("messaging_service_sid", f.MessagingServiceSID),
+		slog.String("brand_registration_sid", f.BrandRegistrationSID),
+	)
+}
+
 type FullA2POnboardingResponse struct {
 	Message string `json:"message"`
 	Data    *A2POnboardingResponse
@@ -255,4 +281,20 @@ type A2POnboardingResponse struct {
 	A2pMessageCampaignStatus     string    `json:"a2p_message_campaign_status"`
 	Created_At                   time.Time `json:"created_at"`
 	Updated_At                   time.Time `json:"updated_at"`
+	// State supersedes the AppliedFor flags, which are kept for existing
+	// readers; change it with Advance.
+	State OnboardingState `json:"state"`
+}
+
+// LogValue omits TwilioPassword so a logged response never leaks it.
+func (r A2POnboardingResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("location_id", r.LocationID),
+		slog.String("subaccount_id", r.SubaccountID),
+		slog.String("brand_registration_sid", r.BrandRegistrationSID),
+		slog.String("brand_registration_status", r.BrandRegistrationStatus),
+		slog.String("messaging_service_sid", r.MessagingServiceSID),
+		slog.String("a2p_message_campaign_sid", r.A2pMessageCampaignSID),
+		slog.String("state", string(r.State)),
+	)
 }
@@ -3,6 +3,7 @@
 package a2p
 
 import (
+	"context"
 	"fmt"
 
 	api "github.com/twilio/twilio-go/rest/api/v2010"
@@ -15,9 +16,9 @@ type SubAccountInfo struct {
 }
 
 // Step 10.1.1: View Subaccounts
-func (s *A2PService) ListSubAccounts() ([]SubAccountInfo, error) {
+func (s *A2PService) ListSubAccounts(ctx context.Context) ([]SubAccountInfo, error) {
 	params := &api.ListAccountParams{}
-	accounts, err := s.client.Api.ListAccount(params)
+	accounts, err := s.accounts.ListAccount(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list subaccounts: %w", err)
 	}
@@ -35,11 +36,11 @@ func (s *A2PService) ListSubAccounts() ([]SubAccountInfo, error) {
 }
 
 // Step 10.1.2: Manage Subaccounts
-func (s *A2PService) UpdateSubAccountStatus(subAccountSid, status string) (string, error) {
+func (s *A2PService) UpdateSubAccountStatus(ctx context.Context, subAccountSid, status string) (string, error) {
 	params := &api.UpdateAccountParams{}
 	params.SetStatus(status)
 
-	resp, err := s.client.Api.UpdateAccount(subAccountSid, params)
+	resp, err := s.accounts.UpdateAccount(ctx, subAccountSid, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to update subaccount status: %w", err)
 	}
@@ -2,6 +2,7 @@
 package a2p
 
 import (
+	"context"
 	"fmt"
 
 	messaging "github.com/twilio/twilio-go/rest/messaging/v1"
@@ -10,11 +11,11 @@ import (
 // Step 6.1: FetchA2PUseCases fetches the possible A2P campaign use cases for a given brand registration
 // Note : Do not complete this section until the BrandRegistration's status is APPROVED.
 // TODO: need to add logic at the time of creating subaccount to check the status of the BrandRegistration
-func (s *A2PService) FetchA2PUseCases(messagingServiceSid, brandRegistrationSid string) ([]messaging.MessagingV1UsAppToPersonUsecase, error) {
+func (s *A2PService) FetchA2PUseCases(ctx context.Context, messagingServiceSid, brandRegistrationSid string) ([]messaging.MessagingV1UsAppToPersonUsecase, error) {
 	params := &messaging.FetchUsAppToPersonUsecaseParams{}
 	params.SetBrandRegistrationSid(brandRegistrationSid)
 
-	resp, err := s.client.MessagingV1.FetchUsAppToPersonUsecase(messagingServiceSid, params)
+	resp, err := s.messaging.FetchUsAppToPersonUsecase(ctx, messagingServiceSid, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch A2P use cases: %w", err)
 	}
@@ -34,7 +35,7 @@ func (s *A2PService) FetchA2PUseCases(messagingServiceSid, brandRegistrationSid
 // Step 6.2: Create the A2P Campaign
 // Note : Do not complete this section until the BrandRegistration's status is APPROVED.
 // TODO: need to add logic at the time of creating subaccount to check the status of the BrandRegistration
-func (s *A2PService) CreateA2PCampaign(messagingServiceSid string, data CampaignData) (string, error) {
+func (s *A2PService) CreateA2PCampaign(ctx context.Context, messagingServiceSid string, data CampaignData) (string, error) {
 	params := &messaging.CreateUsAppToPersonParams{}
 	// params.SetDescription(data.Description)
 	// params.SetUsAppToPersonUsecase(data.Usecase)
@@ -51,14 +52,18 @@ func (s *A2PService) CreateA2PCampaign(messagingServiceSid string, data Campaign
 	params.SetMessageSamples([]string{"Your appointment diagnosis for [disease] at [hospital_name] has been booked at [timestamp]. Please reply with 'YES' to confirm. If you need to reschedule, please reply with 'NO'. If you need any further assistance please call us at [phone_number] between [time]am to [time]pm from Monday to Friday. Thank you."})
 	params.SetMessageFlow("Your appointment diagnosis for [disease] at [hospital_name] has been booked at [timestamp]. Please reply with 'YES' to confirm. If you need to reschedule, please reply with 'NO'. If you need any further assistance please call us at [phone_number] between [time]am to [time]pm from Monday to Friday. Thank you.")
 	params.SetBrandRegistrationSid(data.BrandRegistrationSid)
-	params.SetHelpKeywords([]string{"appointment", "diagnosis", "hospital", "booked", "timestamp", "confirm"})
-	params.SetHelpMessage("Your appointment diagnosis for [disease] at [hospital_name] has been booked at [timestamp]. Please reply with 'YES' to confirm. If you need to reschedule, please reply with 'NO'. If you need any further assistance please call us at [phone_number] between [time]am to [time]pm from Monday to Friday. Thank you.")
-	params.SetOptInKeywords([]string{"YES", "NO"})
-	params.SetOptInMessage("Your appointment diagnosis for [disease] at [hospital_name] has been booked at [timestamp]. Please reply with 'YES' to confirm. If you need to reschedule, please reply with 'NO'. If you need any further assistance please call us at [phone_number] between [time]am to [time]pm from Monday to Friday. Thank you.")
-	params.SetOptOutKeywords([]string{"STOP", "CANCEL"})
-	params.SetOptOutMessage("Your appointment diagnosis for [disease] at [hospital_name] has been booked at [timestamp]. Please reply with 'YES' to confirm. If you need to reschedule, please reply with 'NO'. If you need any further assistance please call us at [phone_number] between [time]am to [time]pm from Monday to Friday. Thank you.")
+	params.SetHelpKeywords(s.keywords.HelpKeywords)
+	params.SetHelpMessage(s.keywords.HelpMessage)
+	params.SetOptInKeywords(s.keywords.OptInKeywords)
+	params.SetOptInMessage(s.keywords.OptInMessage)
+	params.SetOptOutKeywords(s.keywords.OptOutKeywords)
+	params.SetOptOutMessage(s.keywords.OptOutMessage)
 
-	resp, err := s.client.MessagingV1.CreateUsAppToPerson(messagingServiceSid, params)
+	resp, err := reuseOrCreate(ctx, s, "A2P campaign", func(ctx context.Context) (*messaging.MessagingV1UsAppToPerson, error) {
+		return findUsAppToPerson(ctx, s.messaging, messagingServiceSid, params)
+	}, func(ctx context.Context) (*messaging.MessagingV1UsAppToPerson, error) {
+		return s.messaging.CreateUsAppToPerson(ctx, messagingServiceSid, params)
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to create A2P Campaign: %w", err)
 	}
@@ -67,9 +72,9 @@ func (s *A2PService) CreateA2PCampaign(messagingServiceSid string, data Campaign
 }
 
 // Step 10.2: View and Manage A2P Campaigns
-func (s *A2PService) ListA2PCampaigns(messagingServiceSid string) ([]CampaignData, error) {
+func (s *A2PService) ListA2PCampaigns(ctx context.Context, messagingServiceSid string) ([]CampaignData, error) {
 	params := &messaging.ListUsAppToPersonParams{}
-	campaigns, err := s.client.MessagingV1.ListUsAppToPerson(messagingServiceSid, params)
+	campaigns, err := s.messaging.ListUsAppToPerson(ctx, messagingServiceSid, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list A2P campaigns: %w", err)
 	}
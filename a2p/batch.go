@@ -0,0 +1,143 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// ErrOnboardingPanicked is the BatchResult.Err of a customer whose onboarding
+// panicked, for example in a custom pipeline step.
+var ErrOnboardingPanicked = errors.New("onboarding panicked")
+
+// DefaultBatchWorkers is the number of customers OnboardCustomers onboards at
+// once when BatchOptions.Workers is 0.
+const DefaultBatchWorkers = 4
+
+// BatchOptions configures OnboardCustomers.
+type BatchOptions struct {
+	// Workers is the number of customers onboarded at once.
+	Workers int
+	// RequestsPerSecond caps the Twilio requests, retries included, sent for
+	// each account. Customers in the same subaccount share its limit. 0
+	// means no limit.
+	RequestsPerSecond float64
+}
+
+// BatchResult is the outcome of onboarding one customer.
+type BatchResult struct {
+	LocationID   string `json:"location_id"`
+	SubaccountID string `json:"subaccount_id"`
+	// Response is set when the onboarding succeeded. Its TwilioPassword is
+	// cleared so a serialized report holds no credentials.
+	Response *A2POnboardingResponse `json:"response,omitempty"`
+	// StageError is set when a stage failed; Err is any failure, including
+	// invalid params and a batch cancelled before the customer started.
+	StageError *StageError   `json:"stage_error,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Err        error         `json:"-"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// Succeeded reports whether the customer was onboarded.
+func (r *BatchResult) Succeeded() bool {
+	return r.Err == nil
+}
+
+// BatchReport lists the results of OnboardCustomers in the order of its
+// params.
+type BatchReport struct {
+	Results   []BatchResult `json:"results"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+}
+
+// OnboardCustomers runs OnboardCustomer for every customer, opts.Workers at a
+// time. A failing customer does not stop the others; each outcome is in the
+// report, and a panic during one customer's onboarding is reported as its
+// ErrOnboardingPanicked. Customers not started when ctx is done fail with its
+// error. Like OnboardCustomer, it sets MessagingServiceSID in params.
+func (s *A2PService) OnboardCustomers(ctx context.Context, params []FullA2POnboardingParams, opts BatchOptions) *BatchReport {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultBatchWorkers
+	}
+	limiters := map[string]*rateLimiter{}
+	var limitersMu sync.Mutex
+	limiterFor := func(account string) *rateLimiter {
+		limitersMu.Lock()
+		defer limitersMu.Unlock()
+		if limiters[account] == nil {
+			limiters[account] = newRateLimiter(opts.RequestsPerSecond)
+		}
+		return limiters[account]
+	}
+
+	report := &BatchReport{Results: make([]BatchResult, len(params))}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(params); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				customerCtx := ctx
+				if opts.RequestsPerSecond > 0 {
+					customerCtx = withRateLimiter(ctx, limiterFor(params[i].TwilioUsername))
+				}
+				report.Results[i] = s.onboardBatchCustomer(customerCtx, &params[i])
+			}
+		}()
+	}
+	for i := range params {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, result := range report.Results {
+		if result.Succeeded() {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	return report
+}
+
+func (s *A2PService) onboardBatchCustomer(ctx context.Context, params *FullA2POnboardingParams) (result BatchResult) {
+	result = BatchResult{LocationID: params.LocationID, SubaccountID: params.SubaccountID}
+	if err := ctx.Err(); err != nil {
+		result.Err, result.Error = err, err.Error()
+		return result
+	}
+
+	start := time.Now()
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		stack := debug.Stack()
+		if sp, ok := p.(*stepPanic); ok {
+			p, stack = fmt.Sprintf("step %s: %v", sp.step, sp.value), sp.stack
+		}
+		err := fmt.Errorf("%w: %v", ErrOnboardingPanicked, p)
+		s.logger.Error("customer onboarding panicked", "location_id", params.LocationID, "error", err, "stack", string(stack))
+		result = BatchResult{LocationID: params.LocationID, SubaccountID: params.SubaccountID, Err: err, Error: err.Error(), Duration: time.Since(start)}
+	}()
+	resp, err := s.OnboardCustomer(ctx, params)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Err, result.Error = err, err.Error()
+		errors.As(err, &result.StageError)
+		return result
+	}
+	response := *resp.Data
+	response.TwilioPassword = ""
+	result.Response = &response
+	return result
+}
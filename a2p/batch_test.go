@@ -0,0 +1,134 @@
+package a2p
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOnboardCustomers(t *testing.T) {
+	ctx := context.Background()
+	trustHub, messagingFake, accounts := NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts()
+	accounts.AddIncomingPhoneNumber(testParams().TwilioPurchasedPhoneNumber)
+	// The custom step panics for location-2 only, in its own goroutine.
+	pipeline := DefaultPipeline()
+	err := pipeline.InsertAfter("2.1", &FuncStep{
+		ID:        "custom",
+		DependsOn: []string{"2.1"},
+		RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+			if st.Params.LocationID == "location-2" {
+				panic("custom step bug")
+			}
+			return "", nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewA2PService(trustHub, messagingFake, accounts, WithLogger(discardLogger()), WithPipeline(pipeline))
+
+	params := make([]FullA2POnboardingParams, 4)
+	for i := range params {
+		params[i] = *testParams()
+		params[i].LocationID = fmt.Sprintf("location-%d", i+1)
+		params[i].CustomerName = fmt.Sprintf("Customer %d", i+1)
+		params[i].FriendlyName = params[i].CustomerName
+	}
+	params[2].TwilioPurchasedPhoneNumberSID = ""
+
+	tests := []struct {
+		locationID string
+		wantErr    error
+	}{
+		{locationID: "location-1"},
+		{locationID: "location-2", wantErr: ErrOnboardingPanicked},
+		{locationID: "location-3", wantErr: ErrGetPhoneNumberSID},
+		{locationID: "location-4"},
+	}
+
+	report := s.OnboardCustomers(ctx, params, BatchOptions{Workers: 2})
+	if len(report.Results) != len(tests) {
+		t.Fatalf("%d results, want %d", len(report.Results), len(tests))
+	}
+	if report.Succeeded != 2 || report.Failed != 2 {
+		t.Errorf("succeeded %d, failed %d; want 2 and 2", report.Succeeded, report.Failed)
+	}
+	for i, tt := range tests {
+		result := report.Results[i]
+		if result.LocationID != tt.locationID {
+			t.Errorf("result %d is for %s, want %s", i, result.LocationID, tt.locationID)
+		}
+		if !errors.Is(result.Err, tt.wantErr) {
+			t.Errorf("%s: error = %v, want %v", tt.locationID, result.Err, tt.wantErr)
+		}
+		if result.Succeeded() && (result.Response == nil || result.Response.BrandRegistrationSID == "") {
+			t.Errorf("%s: succeeded without a brand registration: %+v", tt.locationID, result.Response)
+		}
+		if result.Response != nil && result.Response.TwilioPassword != "" {
+			t.Errorf("%s: result keeps the subaccount password", tt.locationID)
+		}
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), testParams().TwilioPassword) {
+		t.Errorf("serialized report contains the subaccount password:\n%s", data)
+	}
+}
+
+func TestOnboardCustomersCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := NewA2PService(NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts(), WithLogger(discardLogger()))
+	report := s.OnboardCustomers(ctx, []FullA2POnboardingParams{*testParams(), *testParams()}, BatchOptions{})
+	if report.Failed != 2 {
+		t.Errorf("failed %d, want 2", report.Failed)
+	}
+	for _, result := range report.Results {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("%s: error = %v, want context.Canceled", result.LocationID, result.Err)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		perSecond float64
+		requests  int
+		min       time.Duration
+	}{
+		{name: "first request is not delayed", perSecond: 1, requests: 1},
+		{name: "requests are spaced", perSecond: 20, requests: 5, min: 200 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := withRateLimiter(context.Background(), newRateLimiter(tt.perSecond))
+			start := time.Now()
+			for i := 0; i < tt.requests; i++ {
+				if err := waitRateLimit(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if elapsed := time.Since(start); elapsed < tt.min || elapsed > tt.min+time.Second/2 {
+				t.Errorf("%d requests took %v, want about %v", tt.requests, elapsed, tt.min)
+			}
+		})
+	}
+
+	// A cancelled wait returns the context error.
+	ctx, cancel := context.WithCancel(withRateLimiter(context.Background(), newRateLimiter(0.1)))
+	if err := waitRateLimit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := waitRateLimit(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled wait = %v, want context.Canceled", err)
+	}
+}
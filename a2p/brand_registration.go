@@ -3,6 +3,7 @@
 package a2p
 
 import (
+	"context"
 	"fmt"
 
 	messaging "github.com/twilio/twilio-go/rest/messaging/v1"
@@ -17,12 +18,12 @@ Skip_automatic_sec_vet is an optional Boolean.
 Sometimes, Brand vetting by TCR can take several days.
 If the BrandRegistration resources's status is IN_REVIEW for more than two days then please contact to the Twilio Support.
 */
-func (s *A2PService) CreateBrandRegistration(data BrandRegistrationData) (string, string, error) {
+func (s *A2PService) CreateBrandRegistration(ctx context.Context, data BrandRegistrationData) (string, string, error) {
 	params := &messaging.CreateBrandRegistrationsParams{}
 	params.SetCustomerProfileBundleSid(data.CustomerProfileBundleSid)
 	params.SetA2PProfileBundleSid(data.A2PProfileBundleSid)
 
-	resp, err := s.client.MessagingV1.CreateBrandRegistrations(params)
+	resp, err := s.reuseOrCreateBrandRegistration(ctx, params)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create BrandRegistration: %w", err)
 	}
@@ -31,13 +32,13 @@ func (s *A2PService) CreateBrandRegistration(data BrandRegistrationData) (string
 
 // Step 4.2: Skipping Secondary Vetting
 // (Optional) Do Not Use This Function If You Are Not Sure
-func (s *A2PService) CreateBrandRegistrationWithSkipVetting(data BrandRegistrationData, skipVetting bool) (string, error) {
+func (s *A2PService) CreateBrandRegistrationWithSkipVetting(ctx context.Context, data BrandRegistrationData, skipVetting bool) (string, error) {
 	params := &messaging.CreateBrandRegistrationsParams{}
 	params.SetCustomerProfileBundleSid(data.CustomerProfileBundleSid)
 	params.SetA2PProfileBundleSid(data.A2PProfileBundleSid)
 	params.SetSkipAutomaticSecVet(skipVetting)
 
-	resp, err := s.client.MessagingV1.CreateBrandRegistrations(params)
+	resp, err := s.reuseOrCreateBrandRegistration(ctx, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to create BrandRegistration with skip vetting: %w", err)
 	}
@@ -45,16 +46,55 @@ func (s *A2PService) CreateBrandRegistrationWithSkipVetting(data BrandRegistrati
 	return *resp.Sid, nil
 }
 
-func (s *A2PService) FetchBrandRegistration(sid string) (string, error) {
-	resp, err := s.client.MessagingV1.FetchBrandRegistrations(sid)
+// reuseOrCreateBrandRegistration registers a brand unless the two bundles
+// already have one. Brand registrations are billed, so this lookup is never
+// skipped.
+func (s *A2PService) reuseOrCreateBrandRegistration(ctx context.Context, params *messaging.CreateBrandRegistrationsParams) (*messaging.MessagingV1BrandRegistrations, error) {
+	return reuseOrCreate(ctx, s, "brand registration", func(ctx context.Context) (*messaging.MessagingV1BrandRegistrations, error) {
+		return findBrandRegistration(ctx, s.messaging, params)
+	}, func(ctx context.Context) (*messaging.MessagingV1BrandRegistrations, error) {
+		return s.messaging.CreateBrandRegistrations(ctx, params)
+	})
+}
+
+func (s *A2PService) FetchBrandRegistration(ctx context.Context, sid string) (string, error) {
+	resp, err := s.messaging.FetchBrandRegistrations(ctx, sid)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch BrandRegistration: %w", err)
 	}
 	return *resp.Status, nil
 }
 
-func (s *A2PService) ListBrandRegistrations() ([]messaging.MessagingV1BrandRegistrations, error) {
-	resp, err := s.client.MessagingV1.ListBrandRegistrations(nil)
+// BrandRegistrationFeedback explains why a brand registration failed or
+// scored low, using the error catalog for each brand_feedback value. Unknown
+// values and the failure reason are passed through as descriptions.
+func (s *A2PService) BrandRegistrationFeedback(ctx context.Context, sid string) ([]ErrorInfo, error) {
+	resp, err := s.messaging.FetchBrandRegistrations(ctx, sid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch BrandRegistration: %w", err)
+	}
+	return brandFeedback(resp), nil
+}
+
+func brandFeedback(resp *messaging.MessagingV1BrandRegistrations) []ErrorInfo {
+	var feedback []ErrorInfo
+	if resp.BrandFeedback != nil {
+		for _, value := range *resp.BrandFeedback {
+			info, ok := LookupBrandFeedback(value)
+			if !ok {
+				info = ErrorInfo{Category: CategoryCompliance, Description: value, Remediation: "Review the brand details in the customer profile and resubmit."}
+			}
+			feedback = append(feedback, info)
+		}
+	}
+	if resp.FailureReason != nil && *resp.FailureReason != "" {
+		feedback = append(feedback, ErrorInfo{Category: CategoryCompliance, Description: *resp.FailureReason, Remediation: "Fix the reported brand details and resubmit the brand registration."})
+	}
+	return feedback
+}
+
+func (s *A2PService) ListBrandRegistrations(ctx context.Context) ([]messaging.MessagingV1BrandRegistrations, error) {
+	resp, err := s.messaging.ListBrandRegistrations(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list BrandRegistrations: %w", err)
 	}
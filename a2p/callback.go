@@ -0,0 +1,127 @@
+package a2p
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// BundleStatus is the last TrustHub status received for a customer profile or
+// trust product.
+type BundleStatus struct {
+	Kind          ResourceKind `json:"kind"`
+	Status        string       `json:"status"`
+	FailureReason string       `json:"failure_reason,omitempty"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// StatusCallbackHandler returns an http.Handler for the TrustHub status
+// callbacks of the customer profiles and trust products created by
+// OnboardCustomer. Serve it at the StatusCallback URL given with
+// WithCallbackURLs. Each callback is stored in the record owning the bundle,
+// and moves its state from wherever the run got to:
+//
+//   - "pending-review" to ProfileSubmitted or TrustProductSubmitted, for
+//     records that have not got there yet;
+//   - "twilio-rejected" to ProfileRejected or TrustProductRejected.
+//
+// Callbacks for bundles no record owns are logged and acknowledged, so
+// Twilio does not retry them. onChange, if not nil, is called with every
+// status change. The service needs a Store, see WithStore.
+func (s *A2PService) StatusCallbackHandler(onChange func(StatusChange)) (http.Handler, error) {
+	if s.store == nil {
+		return nil, ErrStoreRequired
+	}
+	return &statusCallbackHandler{service: s, onChange: onChange}, nil
+}
+
+type statusCallbackHandler struct {
+	service  *A2PService
+	onChange func(StatusChange)
+}
+
+func (h *statusCallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	bundleSid, status := r.PostForm.Get("BundleSid"), r.PostForm.Get("Status")
+	if bundleSid == "" || status == "" {
+		http.Error(w, "BundleSid and Status are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	record, kind, err := h.findBundle(ctx, bundleSid)
+	if err != nil {
+		h.service.logger.Error("status callback not handled", "bundle_sid", bundleSid, "error", err)
+		http.Error(w, "failed to load onboarding records", http.StatusInternalServerError)
+		return
+	}
+	if record == nil {
+		h.service.logger.Warn("status callback for unknown bundle", "bundle_sid", bundleSid, "status", status)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	log := h.service.logger.With("location_id", record.LocationID, "bundle_sid", bundleSid)
+	log.Info("status callback received", "kind", kind, "status", status)
+	failureReason := r.PostForm.Get("FailureReason")
+	var change *StatusChange
+	params := &FullA2POnboardingParams{LocationID: record.LocationID, SubaccountID: record.SubaccountID}
+	h.service.updateRecord(ctx, log, params, func(stored *OnboardingRecord) {
+		if stored.Bundles == nil {
+			stored.Bundles = map[string]BundleStatus{}
+		}
+		old := stored.Bundles[bundleSid]
+		stored.Bundles[bundleSid] = BundleStatus{Kind: kind, Status: status, FailureReason: failureReason, UpdatedAt: time.Now().UTC()}
+		if old.Status != status {
+			change = &StatusChange{
+				LocationID: record.LocationID,
+				Kind:       kind,
+				SID:        bundleSid,
+				OldStatus:  old.Status,
+				NewStatus:  status,
+				Final:      finalStatuses[kind][status],
+				At:         time.Now().UTC(),
+			}
+			if failureReason != "" {
+				change.Reasons = []ErrorInfo{{Category: CategoryCompliance, Description: failureReason, Remediation: "Fix the reported details and submit the resource for review again."}}
+			}
+		}
+
+		if next := bundleState(kind, status); next != "" {
+			stored.response(params).advanceThrough(log, statesTo(next)...)
+		}
+	})
+	if change != nil && h.onChange != nil {
+		h.onChange(*change)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// findBundle returns the record whose checkpoint holds bundleSid as its
+// customer profile (stage 2.1) or trust product (stage 3.1).
+func (h *statusCallbackHandler) findBundle(ctx context.Context, bundleSid string) (*OnboardingRecord, ResourceKind, error) {
+	records, err := h.service.store.List(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, record := range records {
+		if record.Checkpoint == nil {
+			continue
+		}
+		switch bundleSid {
+		case record.Checkpoint.Stages["2.1"]:
+			return record, ResourceCustomerProfile, nil
+		case record.Checkpoint.Stages["3.1"]:
+			return record, ResourceTrustProduct, nil
+		}
+	}
+	return nil, "", nil
+}
@@ -0,0 +1,135 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func postStatusCallback(handler http.Handler, form url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/status", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestStatusCallbackHandlerRequiresStore(t *testing.T) {
+	s := NewA2PService(NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts(), WithLogger(discardLogger()))
+	if _, err := s.StatusCallbackHandler(nil); !errors.Is(err, ErrStoreRequired) {
+		t.Errorf("StatusCallbackHandler without a store = %v, want ErrStoreRequired", err)
+	}
+}
+
+func TestStatusCallbackHandler(t *testing.T) {
+	ctx := context.Background()
+	s, _, _, target := newWatchedOnboarding(t)
+	var changes []StatusChange
+	handler, err := s.StatusCallbackHandler(func(change StatusChange) {
+		changes = append(changes, change)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name       string
+		method     string
+		form       url.Values
+		wantStatus int
+		// wantStored is whether the status is stored in the record.
+		wantStored bool
+		wantChange *StatusChange
+		wantState  OnboardingState
+	}{
+		{
+			name:       "not a POST",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+			wantState:  StateBrandPending,
+		},
+		{
+			name:       "missing status",
+			form:       url.Values{"BundleSid": {target.CustomerProfileSID}},
+			wantStatus: http.StatusBadRequest,
+			wantState:  StateBrandPending,
+		},
+		{
+			name:       "unknown bundle",
+			form:       url.Values{"BundleSid": {"BU00000000000000000000000000000000"}, "Status": {"twilio-rejected"}},
+			wantStatus: http.StatusOK,
+			wantState:  StateBrandPending,
+		},
+		{
+			name:       "trust product in review",
+			form:       url.Values{"BundleSid": {target.TrustProductSID}, "Status": {"in-review"}},
+			wantStatus: http.StatusOK,
+			wantStored: true,
+			wantChange: &StatusChange{Kind: ResourceTrustProduct, SID: target.TrustProductSID, NewStatus: "in-review"},
+			wantState:  StateBrandPending,
+		},
+		{
+			name:       "same status again",
+			form:       url.Values{"BundleSid": {target.TrustProductSID}, "Status": {"in-review"}},
+			wantStatus: http.StatusOK,
+			wantStored: true,
+			wantState:  StateBrandPending,
+		},
+		{
+			name:       "customer profile rejected",
+			form:       url.Values{"BundleSid": {target.CustomerProfileSID}, "Status": {"twilio-rejected"}, "FailureReason": {"Address mismatch"}},
+			wantStatus: http.StatusOK,
+			wantStored: true,
+			wantChange: &StatusChange{Kind: ResourceCustomerProfile, SID: target.CustomerProfileSID, NewStatus: "twilio-rejected", Final: true},
+			wantState:  StateProfileRejected,
+		},
+	}
+	for _, step := range steps {
+		changes = nil
+		var w *httptest.ResponseRecorder
+		if step.method == http.MethodGet {
+			w = httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+		} else {
+			w = postStatusCallback(handler, step.form)
+		}
+		if w.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d", step.name, w.Code, step.wantStatus)
+		}
+
+		if step.wantChange == nil {
+			if len(changes) != 0 {
+				t.Errorf("%s: changes = %+v, want none", step.name, changes)
+			}
+		} else {
+			if len(changes) != 1 {
+				t.Fatalf("%s: got %d changes, want 1", step.name, len(changes))
+			}
+			got, want := changes[0], step.wantChange
+			if got.LocationID != target.LocationID || got.Kind != want.Kind || got.SID != want.SID || got.NewStatus != want.NewStatus || got.Final != want.Final {
+				t.Errorf("%s: change = %+v, want %+v", step.name, got, *want)
+			}
+			if reason := step.form.Get("FailureReason"); reason != "" && (len(got.Reasons) != 1 || got.Reasons[0].Description != reason) {
+				t.Errorf("%s: reasons = %+v, want %q", step.name, got.Reasons, reason)
+			}
+		}
+
+		record, err := s.store.Load(ctx, target.LocationID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Response.State != step.wantState {
+			t.Errorf("%s: state = %s, want %s", step.name, record.Response.State, step.wantState)
+		}
+		if step.wantStored {
+			sid, status := step.form.Get("BundleSid"), step.form.Get("Status")
+			if got := record.Bundles[sid]; got.Status != status || got.FailureReason != step.form.Get("FailureReason") {
+				t.Errorf("%s: stored bundle = %+v, want status %q", step.name, got, status)
+			}
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// ErrCheckpointMismatch is returned by ResumeOnboarding when the checkpoint
+// belongs to another customer.
+var ErrCheckpointMismatch = errors.New("checkpoint does not belong to this location and subaccount")
+
+// OnboardingCheckpoint records the progress of OnboardCustomer so a failed
+// run can be continued with ResumeOnboarding instead of starting over. It is
+// plain data and can be stored as JSON.
+type OnboardingCheckpoint struct {
+	LocationID   string `json:"location_id"`
+	SubaccountID string `json:"subaccount_id"`
+	// Stages maps every completed stage ID ("2.1" ... "5.1") to the SID it
+	// produced.
+	Stages map[string]string `json:"stages"`
+	// BrandRegistrationStatus is the status returned by stage 4.1.
+	BrandRegistrationStatus string `json:"brand_registration_status,omitempty"`
+	// FailedStage is the stage the last run stopped at, if any.
+	FailedStage string    `json:"failed_stage,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// NewOnboardingCheckpoint returns an empty checkpoint for params.
+func NewOnboardingCheckpoint(params *FullA2POnboardingParams) *OnboardingCheckpoint {
+	return &OnboardingCheckpoint{
+		LocationID:   params.LocationID,
+		SubaccountID: params.SubaccountID,
+		Stages:       map[string]string{},
+	}
+}
+
+// Completed reports whether stage finished in an earlier run.
+func (c *OnboardingCheckpoint) Completed(stage string) bool {
+	_, ok := c.Stages[stage]
+	return ok
+}
+
+func (c *OnboardingCheckpoint) clone() *OnboardingCheckpoint {
+	copied := *c
+	copied.Stages = make(map[string]string, len(c.Stages))
+	for stage, sid := range c.Stages {
+		copied.Stages[stage] = sid
+	}
+	return &copied
+}
+
+// onboardingRun executes pipeline steps against a checkpoint: steps already
+// in the checkpoint are skipped and return their recorded SID. Its fields are
+// guarded by the state lock.
+type onboardingRun struct {
+	log   *slog.Logger
+	state *PipelineState
+	// created holds the SIDs reported in StageError.CreatedSIDs.
+	created map[string]string
+	// ran lists the steps completed by this run, in the order they finished.
+	// Steps that reused an existing resource are left out, so a rollback
+	// only removes what this run created.
+	ran []Step
+	// rollback undoes ran when a step fails.
+	rollback  bool
+	observers observers
+	// total is the number of steps, reported in StageEvent.Total.
+	total int
+	// steps and deps are the pipeline being executed and the indexes of the
+	// steps each one waits for; undo keeps what a permanent step needs.
+	steps []Step
+	deps  [][]int
+}
+
+func newOnboardingRun(log *slog.Logger, state *PipelineState) *onboardingRun {
+	return &onboardingRun{
+		log:       log,
+		state:     state,
+		created:   map[string]string{},
+		rollback:  state.Service.rollback,
+		observers: state.Service.observers,
+	}
+}
+
+// stage runs one step, the index-th of the pipeline, unless the checkpoint
+// has it. A failure is returned as a *StageError and recorded as the
+// checkpoint's FailedStage unless another step failed first.
+func (r *onboardingRun) stage(ctx context.Context, step Step, index int) error {
+	name := step.Name()
+	operation, key, _, _ := stepInfo(step)
+	event := StageEvent{
+		LocationID: r.state.Params.LocationID,
+		Stage:      name,
+		Operation:  operation,
+		Index:      index,
+		Total:      r.total,
+	}
+	r.state.mu.Lock()
+	sid, done := r.state.checkpoint.Stages[name]
+	if done {
+		if key != "" {
+			r.created[key] = sid
+		}
+		if r.advance(step) {
+			r.persist(ctx)
+		}
+	}
+	r.state.mu.Unlock()
+	if done {
+		r.log.Info("onboarding stage skipped", "stage", name, "operation", operation, "sid", sid)
+		event.SID, event.Skipped = sid, true
+		r.observers.succeeded(event)
+		return nil
+	}
+
+	r.observers.started(event)
+	start := time.Now()
+	recorder := &reuseRecorder{}
+	sid, err := step.Run(context.WithValue(ctx, reuseKey{}, recorder), r.state)
+	event.Duration = time.Since(start)
+	if err != nil {
+		stageErr := r.failed(ctx, name, operation, err)
+		event.Err = stageErr
+		r.observers.failed(event)
+		return stageErr
+	}
+	r.succeeded(ctx, step, key, sid, !recorder.get())
+	event.SID = sid
+	r.observers.succeeded(event)
+	return nil
+}
+
+func (r *onboardingRun) succeeded(ctx context.Context, step Step, key, sid string, created bool) {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	checkpoint := r.state.checkpoint
+	checkpoint.UpdatedAt = time.Now().UTC()
+	checkpoint.Stages[step.Name()] = sid
+	if created {
+		r.ran = append(r.ran, step)
+	}
+	r.advance(step)
+	operation, _, _, _ := stepInfo(step)
+	attrs := []any{"sid", sid}
+	if key != "" {
+		r.created[key] = sid
+		attrs = []any{key, sid}
+	}
+	if !created {
+		attrs = append(attrs, "reused", true)
+	}
+	logStage(r.log, step.Name(), operation, nil, attrs...)
+	r.persist(ctx)
+}
+
+func (r *onboardingRun) failed(ctx context.Context, name, operation string, err error) *StageError {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	checkpoint := r.state.checkpoint
+	checkpoint.UpdatedAt = time.Now().UTC()
+	if checkpoint.FailedStage == "" {
+		checkpoint.FailedStage = name
+	}
+	r.persist(ctx)
+	return stageFailed(r.log, name, operation, err, nil)
+}
+
+// advance moves the onboarding to the state step reaches, with the state
+// lock held, and reports whether it changed.
+func (r *onboardingRun) advance(step Step) bool {
+	next := stepAdvances(step)
+	if next == "" {
+		return false
+	}
+	state, err := r.state.onboarding.advanceTo(next)
+	if err != nil {
+		r.log.Warn("onboarding state not advanced", "stage", step.Name(), "state", r.state.onboarding, "next_state", next, "error", err)
+		return false
+	}
+	changed := state != r.state.onboarding
+	r.state.onboarding = state
+	return changed
+}
+
+// persist writes the checkpoint and the onboarding state through to the
+// store, with the state lock held so saves happen in order.
+func (r *onboardingRun) persist(ctx context.Context) {
+	checkpoint := r.state.checkpoint.clone()
+	state := r.state.onboarding
+	r.state.Service.updateRecord(ctx, r.log, r.state.Params, func(record *OnboardingRecord) {
+		record.Checkpoint = checkpoint
+		if state != "" {
+			record.response(r.state.Params).advanceTo(r.log, state)
+		}
+	})
+}
+
+// fail completes the error of the step that stopped the run once every
+// running step has finished: it reports all SIDs created, rolls back when
+// asked and attaches the checkpoint.
+func (r *onboardingRun) fail(ctx context.Context, stageErr *StageError) *StageError {
+	for key, sid := range r.created {
+		stageErr.CreatedSIDs[key] = sid
+	}
+	if r.rollback {
+		stageErr.Rollback = r.undo(ctx)
+		r.persist(ctx)
+	}
+	stageErr.Checkpoint = r.state.checkpoint.clone()
+	return stageErr
+}
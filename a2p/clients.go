@@ -0,0 +1,73 @@
+package a2p
+
+import (
+	"context"
+
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+	messaging "github.com/twilio/twilio-go/rest/messaging/v1"
+	trusthub "github.com/twilio/twilio-go/rest/trusthub/v1"
+)
+
+// TrustHubClient is the subset of the TrustHub v1 API used by A2PService.
+// Every call takes the caller's context so deadlines and cancellation reach
+// the HTTP request. It is satisfied by FakeTrustHub and by the REST adapter
+// behind NewA2PServiceInstance.
+type TrustHubClient interface {
+	CreateCustomerProfile(ctx context.Context, params *trusthub.CreateCustomerProfileParams) (*trusthub.TrusthubV1CustomerProfile, error)
+	ListCustomerProfile(ctx context.Context, params *trusthub.ListCustomerProfileParams) ([]trusthub.TrusthubV1CustomerProfile, error)
+	FetchCustomerProfile(ctx context.Context, sid string) (*trusthub.TrusthubV1CustomerProfile, error)
+	UpdateCustomerProfile(ctx context.Context, sid string, params *trusthub.UpdateCustomerProfileParams) (*trusthub.TrusthubV1CustomerProfile, error)
+	DeleteCustomerProfile(ctx context.Context, sid string) error
+	CreateCustomerProfileEntityAssignment(ctx context.Context, customerProfileSid string, params *trusthub.CreateCustomerProfileEntityAssignmentParams) (*trusthub.TrusthubV1CustomerProfileEntityAssignment, error)
+	ListCustomerProfileEntityAssignment(ctx context.Context, customerProfileSid string, params *trusthub.ListCustomerProfileEntityAssignmentParams) ([]trusthub.TrusthubV1CustomerProfileEntityAssignment, error)
+	DeleteCustomerProfileEntityAssignment(ctx context.Context, customerProfileSid string, sid string) error
+	CreateCustomerProfileEvaluation(ctx context.Context, customerProfileSid string, params *trusthub.CreateCustomerProfileEvaluationParams) (*trusthub.TrusthubV1CustomerProfileEvaluation, error)
+	CreateEndUser(ctx context.Context, params *trusthub.CreateEndUserParams) (*trusthub.TrusthubV1EndUser, error)
+	ListEndUser(ctx context.Context, params *trusthub.ListEndUserParams) ([]trusthub.TrusthubV1EndUser, error)
+	DeleteEndUser(ctx context.Context, sid string) error
+	CreateSupportingDocument(ctx context.Context, params *trusthub.CreateSupportingDocumentParams) (*trusthub.TrusthubV1SupportingDocument, error)
+	ListSupportingDocument(ctx context.Context, params *trusthub.ListSupportingDocumentParams) ([]trusthub.TrusthubV1SupportingDocument, error)
+	DeleteSupportingDocument(ctx context.Context, sid string) error
+	CreateTrustProduct(ctx context.Context, params *trusthub.CreateTrustProductParams) (*trusthub.TrusthubV1TrustProduct, error)
+	ListTrustProduct(ctx context.Context, params *trusthub.ListTrustProductParams) ([]trusthub.TrusthubV1TrustProduct, error)
+	FetchTrustProduct(ctx context.Context, sid string) (*trusthub.TrusthubV1TrustProduct, error)
+	UpdateTrustProduct(ctx context.Context, sid string, params *trusthub.UpdateTrustProductParams) (*trusthub.TrusthubV1TrustProduct, error)
+	DeleteTrustProduct(ctx context.Context, sid string) error
+	CreateTrustProductEntityAssignment(ctx context.Context, trustProductSid string, params *trusthub.CreateTrustProductEntityAssignmentParams) (*trusthub.TrusthubV1TrustProductEntityAssignment, error)
+	ListTrustProductEntityAssignment(ctx context.Context, trustProductSid string, params *trusthub.ListTrustProductEntityAssignmentParams) ([]trusthub.TrusthubV1TrustProductEntityAssignment, error)
+	DeleteTrustProductEntityAssignment(ctx context.Context, trustProductSid string, sid string) error
+	CreateTrustProductEvaluation(ctx context.Context, trustProductSid string, params *trusthub.CreateTrustProductEvaluationParams) (*trusthub.TrusthubV1TrustProductEvaluation, error)
+	FetchPolicies(ctx context.Context, sid string) (*trusthub.TrusthubV1Policies, error)
+}
+
+// MessagingClient is the subset of the Messaging v1 API used by A2PService.
+// It is satisfied by FakeMessaging and by the REST adapter.
+type MessagingClient interface {
+	CreateBrandRegistrations(ctx context.Context, params *messaging.CreateBrandRegistrationsParams) (*messaging.MessagingV1BrandRegistrations, error)
+	FetchBrandRegistrations(ctx context.Context, sid string) (*messaging.MessagingV1BrandRegistrations, error)
+	ListBrandRegistrations(ctx context.Context, params *messaging.ListBrandRegistrationsParams) ([]messaging.MessagingV1BrandRegistrations, error)
+	CreateService(ctx context.Context, params *messaging.CreateServiceParams) (*messaging.MessagingV1Service, error)
+	ListService(ctx context.Context, params *messaging.ListServiceParams) ([]messaging.MessagingV1Service, error)
+	UpdateService(ctx context.Context, sid string, params *messaging.UpdateServiceParams) (*messaging.MessagingV1Service, error)
+	DeleteService(ctx context.Context, sid string) error
+	CreatePhoneNumber(ctx context.Context, serviceSid string, params *messaging.CreatePhoneNumberParams) (*messaging.MessagingV1PhoneNumber, error)
+	ListPhoneNumber(ctx context.Context, serviceSid string, params *messaging.ListPhoneNumberParams) ([]messaging.MessagingV1PhoneNumber, error)
+	FetchUsAppToPersonUsecase(ctx context.Context, messagingServiceSid string, params *messaging.FetchUsAppToPersonUsecaseParams) (*messaging.MessagingV1UsAppToPersonUsecase, error)
+	CreateUsAppToPerson(ctx context.Context, messagingServiceSid string, params *messaging.CreateUsAppToPersonParams) (*messaging.MessagingV1UsAppToPerson, error)
+	FetchUsAppToPerson(ctx context.Context, messagingServiceSid string, sid string) (*messaging.MessagingV1UsAppToPerson, error)
+	ListUsAppToPerson(ctx context.Context, messagingServiceSid string, params *messaging.ListUsAppToPersonParams) ([]messaging.MessagingV1UsAppToPerson, error)
+}
+
+// AccountsClient is the subset of the API 2010 (accounts, addresses and phone
+// numbers) used by A2PService. It is satisfied by FakeAccounts and by the
+// REST adapter.
+type AccountsClient interface {
+	CreateAccount(ctx context.Context, params *api.CreateAccountParams) (*api.ApiV2010Account, error)
+	ListAccount(ctx context.Context, params *api.ListAccountParams) ([]api.ApiV2010Account, error)
+	UpdateAccount(ctx context.Context, sid string, params *api.UpdateAccountParams) (*api.ApiV2010Account, error)
+	CreateAddress(ctx context.Context, params *api.CreateAddressParams) (*api.ApiV2010Address, error)
+	ListAddress(ctx context.Context, params *api.ListAddressParams) ([]api.ApiV2010Address, error)
+	DeleteAddress(ctx context.Context, sid string, params *api.DeleteAddressParams) error
+	ListIncomingPhoneNumber(ctx context.Context, params *api.ListIncomingPhoneNumberParams) ([]api.ApiV2010IncomingPhoneNumber, error)
+	ListAvailablePhoneNumberLocal(ctx context.Context, countryCode string, params *api.ListAvailablePhoneNumberLocalParams) ([]api.ApiV2010AvailablePhoneNumberLocal, error)
+}
@@ -0,0 +1,132 @@
+package a2p
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrConsentNotFound is returned by a ConsentStore that has no record for
+	// the sender.
+	ErrConsentNotFound = errors.New("consent record not found")
+	// ErrConsentStoreRequired is returned when InboundMessageHandler has no
+	// ConsentStore to record opt-outs in.
+	ErrConsentStoreRequired = errors.New("a consent store is required")
+	// ErrConsentKey is returned when saving or loading consent without a
+	// scope or phone.
+	ErrConsentKey = errors.New("consent record has no scope or phone")
+)
+
+// ConsentRecord is the messaging consent of one sender.
+type ConsentRecord struct {
+	// Scope is the messaging service SID the sender wrote to, or the number
+	// it wrote to when the message did not go through a messaging service.
+	Scope      string `json:"scope"`
+	Phone      string `json:"phone"`
+	AccountSid string `json:"account_sid,omitempty"`
+	OptedOut   bool   `json:"opted_out"`
+	// Keyword is the message that changed the consent, such as "STOP".
+	Keyword   string    `json:"keyword"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ConsentStore keeps opt-outs and opt-ins per sender. Implementations must be
+// safe for concurrent use. FileStore is one.
+type ConsentStore interface {
+	SaveConsent(ctx context.Context, record *ConsentRecord) error
+	// LoadConsent returns ErrConsentNotFound when the sender never opted in
+	// or out.
+	LoadConsent(ctx context.Context, scope, phone string) (*ConsentRecord, error)
+}
+
+// MemoryConsentStore is a ConsentStore that forgets everything on restart.
+type MemoryConsentStore struct {
+	mu      sync.RWMutex
+	records map[[2]string]ConsentRecord
+}
+
+// NewMemoryConsentStore returns an empty MemoryConsentStore.
+func NewMemoryConsentStore() *MemoryConsentStore {
+	return &MemoryConsentStore{records: map[[2]string]ConsentRecord{}}
+}
+
+func (m *MemoryConsentStore) SaveConsent(ctx context.Context, record *ConsentRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[[2]string{record.Scope, record.Phone}] = *record
+	return nil
+}
+
+func (m *MemoryConsentStore) LoadConsent(ctx context.Context, scope, phone string) (*ConsentRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	record, ok := m.records[[2]string{scope, phone}]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrConsentNotFound, phone)
+	}
+	return &record, nil
+}
+
+// SaveConsent keeps record in the consents directory of the store.
+func (f *FileStore) SaveConsent(ctx context.Context, record *ConsentRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode consent record: %w", err)
+	}
+
+	path, err := f.consentPath(record.Scope, record.Phone)
+	if err != nil {
+		return err
+	}
+	defer f.lock(path)()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to save consent record: %w", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to save consent record: %w", err)
+	}
+	return nil
+}
+
+func (f *FileStore) LoadConsent(ctx context.Context, scope, phone string) (*ConsentRecord, error) {
+	path, err := f.consentPath(scope, phone)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrConsentNotFound, phone)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read consent record: %w", err)
+	}
+	var record ConsentRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse consent record: %w", err)
+	}
+	return &record, nil
+}
+
+// consentPath returns the file of the sender's consent. Scope and phone come
+// from the inbound request, so both are base64url-encoded: the encoding has no
+// path separators and never yields "." or "..", which keeps every path inside
+// the consents directory.
+func (f *FileStore) consentPath(scope, phone string) (string, error) {
+	if scope == "" || phone == "" {
+		return "", ErrConsentKey
+	}
+	encode := base64.RawURLEncoding.EncodeToString
+	return filepath.Join(f.dir, "consents", encode([]byte(scope)), encode([]byte(phone))+".json"), nil
+}
+
+var (
+	_ ConsentStore = (*MemoryConsentStore)(nil)
+	_ ConsentStore = (*FileStore)(nil)
+)
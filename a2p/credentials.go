@@ -0,0 +1,184 @@
+package a2p
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrMissingCredentials is returned when a CredentialsProvider has nothing to
+// authenticate with.
+var ErrMissingCredentials = errors.New("twilio credentials are not configured")
+
+// Credentials authenticate requests for one Twilio account. Username is the
+// account SID when authenticating with an auth token, or an API key SID
+// (SK...) when Password is the API key secret.
+type Credentials struct {
+	AccountSid string
+	Username   string
+	Password   string
+}
+
+// AuthTokenCredentials authenticates as accountSid with its auth token.
+func AuthTokenCredentials(accountSid, authToken string) Credentials {
+	return Credentials{AccountSid: accountSid, Username: accountSid, Password: authToken}
+}
+
+// APIKeyCredentials authenticates with an API key and secret on behalf of
+// accountSid.
+func APIKeyCredentials(accountSid, apiKey, apiSecret string) Credentials {
+	return Credentials{AccountSid: accountSid, Username: apiKey, Password: apiSecret}
+}
+
+func (c Credentials) validate() error {
+	if c.AccountSid == "" || c.Username == "" || c.Password == "" {
+		return ErrMissingCredentials
+	}
+	if !strings.HasPrefix(c.AccountSid, "AC") {
+		return fmt.Errorf("invalid account SID %q: must start with AC", c.AccountSid)
+	}
+	return nil
+}
+
+// CredentialsProvider supplies the credentials for every Twilio request. It
+// is consulted per request, so a provider that returns new values rotates the
+// credentials without rebuilding the service.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// StaticCredentials always returns the same credentials.
+type StaticCredentials Credentials
+
+func (c StaticCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	creds := Credentials(c)
+	if err := creds.validate(); err != nil {
+		return Credentials{}, err
+	}
+	return creds, nil
+}
+
+// EnvCredentials reads TWILIO_ACCOUNT_SID together with either TWILIO_API_KEY
+// and TWILIO_API_SECRET or TWILIO_AUTH_TOKEN, the API key taking precedence.
+// The environment is read on every request.
+type EnvCredentials struct{}
+
+func (EnvCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	accountSid := os.Getenv("TWILIO_ACCOUNT_SID")
+	var creds Credentials
+	if apiKey := os.Getenv("TWILIO_API_KEY"); apiKey != "" {
+		creds = APIKeyCredentials(accountSid, apiKey, os.Getenv("TWILIO_API_SECRET"))
+	} else {
+		creds = AuthTokenCredentials(accountSid, os.Getenv("TWILIO_AUTH_TOKEN"))
+	}
+	if err := creds.validate(); err != nil {
+		return Credentials{}, fmt.Errorf("credentials from environment: %w", err)
+	}
+	return creds, nil
+}
+
+// FileCredentials reads a JSON file such as a mounted secret:
+//
+//	{"account_sid": "AC...", "auth_token": "..."}
+//	{"account_sid": "AC...", "api_key": "SK...", "api_secret": "..."}
+//
+// The file is read again whenever its modification time or size changes, so
+// replacing it rotates the credentials.
+type FileCredentials struct {
+	Path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	creds   Credentials
+}
+
+// NewFileCredentials returns a FileCredentials for path.
+func NewFileCredentials(path string) *FileCredentials {
+	return &FileCredentials{Path: path}
+}
+
+type credentialsFile struct {
+	AccountSid string `json:"account_sid"`
+	AuthToken  string `json:"auth_token"`
+	APIKey     string `json:"api_key"`
+	APISecret  string `json:"api_secret"`
+}
+
+func (f *FileCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size && f.creds.AccountSid != "" {
+		return f.creds, nil
+	}
+
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	var file credentialsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Credentials{}, fmt.Errorf("failed to parse credentials file %s: %w", f.Path, err)
+	}
+
+	creds := AuthTokenCredentials(file.AccountSid, file.AuthToken)
+	if file.APIKey != "" {
+		creds = APIKeyCredentials(file.AccountSid, file.APIKey, file.APISecret)
+	}
+	if err := creds.validate(); err != nil {
+		return Credentials{}, fmt.Errorf("credentials file %s: %w", f.Path, err)
+	}
+
+	f.creds, f.modTime, f.size = creds, info.ModTime(), info.Size()
+	return creds, nil
+}
+
+// RotatingCredentials holds credentials that can be replaced at runtime, for
+// example by a secret manager callback. Requests already in flight keep the
+// credentials they started with.
+type RotatingCredentials struct {
+	mu    sync.RWMutex
+	creds Credentials
+}
+
+// NewRotatingCredentials starts with initial.
+func NewRotatingCredentials(initial Credentials) *RotatingCredentials {
+	return &RotatingCredentials{creds: initial}
+}
+
+// Rotate replaces the credentials used by later requests.
+func (r *RotatingCredentials) Rotate(creds Credentials) error {
+	if err := creds.validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.creds = creds
+	return nil
+}
+
+func (r *RotatingCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if err := r.creds.validate(); err != nil {
+		return Credentials{}, err
+	}
+	return r.creds, nil
+}
+
+var (
+	_ CredentialsProvider = StaticCredentials{}
+	_ CredentialsProvider = EnvCredentials{}
+	_ CredentialsProvider = (*FileCredentials)(nil)
+	_ CredentialsProvider = (*RotatingCredentials)(nil)
+)
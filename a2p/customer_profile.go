@@ -1,23 +1,29 @@
 package a2p
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	api "github.com/twilio/twilio-go/rest/api/v2010"
 	trusthub "github.com/twilio/twilio-go/rest/trusthub/v1"
 )
 
 // Step 2.1: Create a Secondary Customer Profile
-func (s *A2PService) CreateSecondaryCustomerProfile(data CustomerProfileData) (string, error) {
+func (s *A2PService) CreateSecondaryCustomerProfile(ctx context.Context, data CustomerProfileData) (string, error) {
 	params := &trusthub.CreateCustomerProfileParams{}
 	params.SetPolicySid(data.PolicySid)
-	params.SetFriendlyName(data.FriendlyName)
+	params.SetFriendlyName(correlatedName(data.FriendlyName, data.CorrelationKey))
 	params.SetEmail(data.Email)
 	if data.StatusCallback != "" {
 		params.SetStatusCallback(data.StatusCallback)
 	}
 
-	resp, err := s.client.TrusthubV1.CreateCustomerProfile(params)
+	resp, err := reuseOrCreate(ctx, s, "customer profile", func(ctx context.Context) (*trusthub.TrusthubV1CustomerProfile, error) {
+		return findCustomerProfile(ctx, s.trustHub, params, time.Time{})
+	}, func(ctx context.Context) (*trusthub.TrusthubV1CustomerProfile, error) {
+		return s.trustHub.CreateCustomerProfile(ctx, params)
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to create customer profile: %w", err)
 	}
@@ -26,7 +32,7 @@ func (s *A2PService) CreateSecondaryCustomerProfile(data CustomerProfileData) (s
 }
 
 // Step 2.2: Create an EndUser Resource of Type
-func (s *A2PService) CreateEndUserBusinessInfo(data BusinessInfoData) (string, error) {
+func (s *A2PService) CreateEndUserBusinessInfo(ctx context.Context, data BusinessInfoData) (string, error) {
 	params := &trusthub.CreateEndUserParams{}
 	params.SetAttributes(map[string]interface{}{
 		"business_name":                    data.BusinessName,
@@ -39,10 +45,10 @@ func (s *A2PService) CreateEndUserBusinessInfo(data BusinessInfoData) (string, e
 		"business_industry":                data.BusinessIndustry,
 		"business_registration_number":     data.BusinessRegistrationNumber,
 	})
-	params.SetFriendlyName(fmt.Sprintf("%s - Business Information EndUser resource", data.BusinessName))
+	params.SetFriendlyName(correlatedName(fmt.Sprintf("%s - Business Information EndUser resource", data.BusinessName), data.CorrelationKey))
 	params.SetType("customer_profile_business_information")
 
-	resp, err := s.client.TrusthubV1.CreateEndUser(params)
+	resp, err := s.reuseOrCreateEndUser(ctx, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to create EndUser business information: %w", err)
 	}
@@ -51,11 +57,11 @@ func (s *A2PService) CreateEndUserBusinessInfo(data BusinessInfoData) (string, e
 }
 
 // Step 2.3: Attach the EndUser to the Secondary Customer Profile
-func (s *A2PService) AttachEndUserToProfile(data EndUserAssignmentData) (string, error) {
+func (s *A2PService) AttachEndUserToProfile(ctx context.Context, data EndUserAssignmentData) (string, error) {
 	params := &trusthub.CreateCustomerProfileEntityAssignmentParams{}
 	params.SetObjectSid(data.EndUserSid)
 
-	resp, err := s.client.TrusthubV1.CreateCustomerProfileEntityAssignment(data.CustomerProfileSid, params)
+	resp, err := s.reuseOrAttachToProfile(ctx, data.CustomerProfileSid, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to attach EndUser to customer profile: %w", err)
 	}
@@ -64,7 +70,7 @@ func (s *A2PService) AttachEndUserToProfile(data EndUserAssignmentData) (string,
 }
 
 // Step 2.4. Create an EndUser resource of type: authorized_representative_1
-func (s *A2PService) CreateEndUserAuthorizedRep1(data EndUserAuthorizedRep1BusinessInfoData) (string, error) {
+func (s *A2PService) CreateEndUserAuthorizedRep1(ctx context.Context, data EndUserAuthorizedRep1BusinessInfoData) (string, error) {
 	params := &trusthub.CreateEndUserParams{
 		Type:         &data.Type,
 		FriendlyName: &data.FriendlyName,
@@ -77,10 +83,10 @@ func (s *A2PService) CreateEndUserAuthorizedRep1(data EndUserAuthorizedRep1Busin
 		"email":          data.Email,
 		"business_title": data.BusinessTitle,
 	})
-	params.SetFriendlyName(fmt.Sprintf("%s - Authorized Representative 1", data.BusinessTitle))
+	params.SetFriendlyName(correlatedName(fmt.Sprintf("%s - Authorized Representative 1", data.BusinessTitle), data.CorrelationKey))
 	params.SetType("authorized_representative_1")
 
-	resp, err := s.client.TrusthubV1.CreateEndUser(params)
+	resp, err := s.reuseOrCreateEndUser(ctx, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to create EndUser authorized representative 1: %w", err)
 	}
@@ -89,11 +95,11 @@ func (s *A2PService) CreateEndUserAuthorizedRep1(data EndUserAuthorizedRep1Busin
 }
 
 // Step 2.5 Attach the EndUser to the Secondary Customer Profile
-func (s *A2PService) AttachEndUserAuthorizedRep1ToProfile(data EndUserAssignmentData) (string, error) {
+func (s *A2PService) AttachEndUserAuthorizedRep1ToProfile(ctx context.Context, data EndUserAssignmentData) (string, error) {
 	params := &trusthub.CreateCustomerProfileEntityAssignmentParams{}
 	params.SetObjectSid(data.EndUserSid)
 
-	resp, err := s.client.TrusthubV1.CreateCustomerProfileEntityAssignment(data.CustomerProfileSid, params)
+	resp, err := s.reuseOrAttachToProfile(ctx, data.CustomerProfileSid, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to attach EndUser authorized representative 1 to customer profile: %w", err)
 	}
@@ -102,7 +108,7 @@ func (s *A2PService) AttachEndUserAuthorizedRep1ToProfile(data EndUserAssignment
 }
 
 // Step 2.6 Create An Address Resource and returns address sid
-func (s *A2PService) CreateAddressResource(data AddressData) (string, error) {
+func (s *A2PService) CreateAddressResource(ctx context.Context, data AddressData) (string, error) {
 	params := &api.CreateAddressParams{}
 	// params.SetPathAccountSid(data.PathAccountSid)
 	params.SetCustomerName(data.CustomerName)
@@ -111,11 +117,15 @@ func (s *A2PService) CreateAddressResource(data AddressData) (string, error) {
 	params.SetRegion(data.Region)
 	params.SetPostalCode(data.PostalCode)
 	params.SetIsoCountry(data.IsoCountry)
-	params.SetFriendlyName(data.FriendlyName)
+	params.SetFriendlyName(correlatedName(data.FriendlyName, data.CorrelationKey))
 	params.SetStreetSecondary(data.StreetSecondary)
 	params.SetAutoCorrectAddress(true)
 
-	resp, err := s.client.Api.CreateAddress(params)
+	resp, err := reuseOrCreate(ctx, s, "address", func(ctx context.Context) (*api.ApiV2010Address, error) {
+		return findAddress(ctx, s.accounts, params, time.Time{})
+	}, func(ctx context.Context) (*api.ApiV2010Address, error) {
+		return s.accounts.CreateAddress(ctx, params)
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to create Address: %w", err)
 	}
@@ -124,15 +134,19 @@ func (s *A2PService) CreateAddressResource(data AddressData) (string, error) {
 }
 
 // Step 2.7 Create a supporting document resource and returns supporting_document_sid
-func (s *A2PService) CreateSupportingDocumentResource(data SupportingDocumentData) (string, error) {
+func (s *A2PService) CreateSupportingDocumentResource(ctx context.Context, data SupportingDocumentData) (string, error) {
 	params := &trusthub.CreateSupportingDocumentParams{}
-	params.SetFriendlyName(data.FriendlyName)
+	params.SetFriendlyName(correlatedName(data.FriendlyName, data.CorrelationKey))
 	params.SetType("customer_profile_address")
 	params.SetAttributes(map[string]interface{}{
 		"address_sids": data.AddressSid,
 	})
 
-	resp, err := s.client.TrusthubV1.CreateSupportingDocument(params)
+	resp, err := reuseOrCreate(ctx, s, "supporting document", func(ctx context.Context) (*trusthub.TrusthubV1SupportingDocument, error) {
+		return findSupportingDocument(ctx, s.trustHub, params, time.Time{})
+	}, func(ctx context.Context) (*trusthub.TrusthubV1SupportingDocument, error) {
+		return s.trustHub.CreateSupportingDocument(ctx, params)
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to create Supporting Document: %w", err)
 	}
@@ -140,11 +154,11 @@ func (s *A2PService) CreateSupportingDocumentResource(data SupportingDocumentDat
 }
 
 // Step 2.8 Attach the SupportingDocument resource to the Secondary Customer Profile
-func (s *A2PService) AttachSupportingDocumentToProfile(secondaryProfileSID string, supportingDocumentSID *string) (string, error) {
+func (s *A2PService) AttachSupportingDocumentToProfile(ctx context.Context, secondaryProfileSID string, supportingDocumentSID *string) (string, error) {
 	params := &trusthub.CreateCustomerProfileEntityAssignmentParams{}
 	params.SetObjectSid(*supportingDocumentSID)
 
-	resp, err := s.client.TrusthubV1.CreateCustomerProfileEntityAssignment(secondaryProfileSID, params)
+	resp, err := s.reuseOrAttachToProfile(ctx, secondaryProfileSID, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to attach Supporting Document to customer profile: %w", err)
 	}
@@ -153,27 +167,50 @@ func (s *A2PService) AttachSupportingDocumentToProfile(secondaryProfileSID strin
 }
 
 // Step 2.9. Evaluate the Secondary Customer Profile
-func (s *A2PService) EvaluateSecondaryCustomerProfile(secondaryProfileSID string) (string, error) {
+func (s *A2PService) EvaluateSecondaryCustomerProfile(ctx context.Context, secondaryProfileSID string) (string, error) {
 	params := &trusthub.CreateCustomerProfileEvaluationParams{}
 	params.SetPolicySid("RNdfbf3fae0e1107f8aded0e7cead80bf5")
 
-	resp, err := s.client.TrusthubV1.CreateCustomerProfileEvaluation(secondaryProfileSID, params)
+	resp, err := s.trustHub.CreateCustomerProfileEvaluation(ctx, secondaryProfileSID, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to evaluate Secondary Customer Profile: %w", err)
 	}
+	if err := evaluationError(secondaryProfileSID, resp.Sid, resp.Status, resp.Results); err != nil {
+		return "", fmt.Errorf("failed to evaluate Secondary Customer Profile: %w", err)
+	}
 
 	return *resp.Sid, nil
 }
 
 // Step 2.10. Submit the Secondary Customer Profile for review  - status must be set to pending-review
-func (s *A2PService) SubmitSecondaryCustomerProfileForReview(secondaryProfileSID string) (string, error) {
+func (s *A2PService) SubmitSecondaryCustomerProfileForReview(ctx context.Context, secondaryProfileSID string) (string, error) {
 	params := &trusthub.UpdateCustomerProfileParams{}
 	params.SetStatus("pending-review")
 
-	resp, err := s.client.TrusthubV1.UpdateCustomerProfile(secondaryProfileSID, params)
+	resp, err := s.trustHub.UpdateCustomerProfile(ctx, secondaryProfileSID, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to submit Secondary Customer Profile for review: %w", err)
 	}
 
 	return *resp.Sid, nil
 }
+
+// reuseOrCreateEndUser creates an EndUser unless one with the same
+// FriendlyName and Type exists.
+func (s *A2PService) reuseOrCreateEndUser(ctx context.Context, params *trusthub.CreateEndUserParams) (*trusthub.TrusthubV1EndUser, error) {
+	return reuseOrCreate(ctx, s, "end user", func(ctx context.Context) (*trusthub.TrusthubV1EndUser, error) {
+		return findEndUser(ctx, s.trustHub, params, time.Time{})
+	}, func(ctx context.Context) (*trusthub.TrusthubV1EndUser, error) {
+		return s.trustHub.CreateEndUser(ctx, params)
+	})
+}
+
+// reuseOrAttachToProfile attaches params.ObjectSid to the customer profile
+// unless it is attached already.
+func (s *A2PService) reuseOrAttachToProfile(ctx context.Context, customerProfileSid string, params *trusthub.CreateCustomerProfileEntityAssignmentParams) (*trusthub.TrusthubV1CustomerProfileEntityAssignment, error) {
+	return reuseOrCreate(ctx, s, "customer profile entity assignment", func(ctx context.Context) (*trusthub.TrusthubV1CustomerProfileEntityAssignment, error) {
+		return findCustomerProfileEntityAssignment(ctx, s.trustHub, customerProfileSid, params)
+	}, func(ctx context.Context) (*trusthub.TrusthubV1CustomerProfileEntityAssignment, error) {
+		return s.trustHub.CreateCustomerProfileEntityAssignment(ctx, customerProfileSid, params)
+	})
+}
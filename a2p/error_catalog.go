@@ -0,0 +1,292 @@
+package a2p
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/twilio/twilio-go/client"
+)
+
+// ErrorCategory groups Twilio failures by who has to act on them.
+type ErrorCategory string
+
+const (
+	// CategoryAuthentication: the credentials are wrong or lack access.
+	CategoryAuthentication ErrorCategory = "authentication"
+	// CategoryAccount: the account is suspended, closed or on a trial.
+	CategoryAccount ErrorCategory = "account"
+	// CategoryValidation: the customer data sent to Twilio is invalid.
+	CategoryValidation ErrorCategory = "validation"
+	// CategoryCompliance: TrustHub or 10DLC review rejected the submission.
+	CategoryCompliance ErrorCategory = "compliance"
+	// CategoryConfiguration: the Twilio resources are not set up as required.
+	CategoryConfiguration ErrorCategory = "configuration"
+	// CategoryConflict: the resource is already in the requested state.
+	CategoryConflict ErrorCategory = "conflict"
+	// CategoryNotFound: a SID in the request does not exist.
+	CategoryNotFound ErrorCategory = "not_found"
+	// CategoryRateLimit: Twilio throttled the request.
+	CategoryRateLimit ErrorCategory = "rate_limit"
+	// CategoryTwilioOutage: Twilio failed to process the request.
+	CategoryTwilioOutage ErrorCategory = "twilio_outage"
+	// CategoryNetwork: the request did not reach Twilio or the response was
+	// lost.
+	CategoryNetwork ErrorCategory = "network"
+	// CategoryUnknown is used when nothing better is known.
+	CategoryUnknown ErrorCategory = "unknown"
+)
+
+// ErrorInfo is a catalog entry for a Twilio error code.
+type ErrorInfo struct {
+	// Code is the Twilio error code, or 0 for entries derived from the HTTP
+	// status alone.
+	Code        int           `json:"code,omitempty"`
+	Category    ErrorCategory `json:"category"`
+	Description string        `json:"description"`
+	Remediation string        `json:"remediation"`
+}
+
+var (
+	errorCatalogMu sync.RWMutex
+	errorCatalog   = map[int]ErrorInfo{
+		20001: {Category: CategoryValidation, Description: "Unknown parameter", Remediation: "A field name was not recognised by Twilio; check the request parameters against the API version in use."},
+		20003: {Category: CategoryAuthentication, Description: "Authentication failed", Remediation: "Check the account SID and auth token or API key for the (sub)account the request was sent as."},
+		20005: {Category: CategoryAccount, Description: "Account not active", Remediation: "Reactivate the (sub)account in the Twilio console or with UpdateSubaccountStatus before retrying."},
+		20008: {Category: CategoryAuthentication, Description: "Resource not accessible with test account credentials", Remediation: "Use live credentials; test credentials cannot create TrustHub or 10DLC resources."},
+		20404: {Category: CategoryNotFound, Description: "Resource not found", Remediation: "A SID in the request does not exist in this account; make sure it was created under the same subaccount."},
+		20429: {Category: CategoryRateLimit, Description: "Too many requests", Remediation: "Twilio is throttling this account; retry later or lower the onboarding concurrency."},
+		20500: {Category: CategoryTwilioOutage, Description: "Internal server error", Remediation: "Retry later and check status.twilio.com if it persists."},
+		20503: {Category: CategoryTwilioOutage, Description: "Service unavailable", Remediation: "Retry later and check status.twilio.com if it persists."},
+		21421: {Category: CategoryValidation, Description: "Phone number is invalid", Remediation: "Send phone numbers in E.164 format, for example +15551234567."},
+		21606: {Category: CategoryConfiguration, Description: "From number is not a valid message-capable Twilio number", Remediation: "Use an SMS-capable number owned by the sending subaccount."},
+		21610: {Category: CategoryCompliance, Description: "Recipient has opted out", Remediation: "The recipient replied STOP; do not message them until they reply START."},
+		21614: {Category: CategoryValidation, Description: "To number is not a valid mobile number", Remediation: "Check the recipient number; landlines cannot receive SMS."},
+		21701: {Category: CategoryNotFound, Description: "Messaging service not found", Remediation: "The messaging service SID does not exist in this subaccount; create it again with CreateMessagingService."},
+		21710: {Category: CategoryConflict, Description: "Phone number is already in the messaging service", Remediation: "Nothing to do; the number is attached already."},
+		21712: {Category: CategoryConflict, Description: "Phone number is associated with another messaging service", Remediation: "Remove the number from its current messaging service before adding it to this one."},
+		22216: {Category: CategoryCompliance, Description: "TrustHub bundle evaluation failed", Remediation: "The bundle does not satisfy its policy; fix the end users, address and documents named in the evaluation results and evaluate again."},
+		30007: {Category: CategoryCompliance, Description: "Message filtered by the carrier", Remediation: "Review the message content and the campaign use case; carriers block content that does not match the registered campaign."},
+		30034: {Category: CategoryCompliance, Description: "Message sent from an unregistered number", Remediation: "Attach the number to a messaging service with an approved A2P 10DLC campaign."},
+	}
+)
+
+// errorCodeRanges are the fallback entries for codes missing from
+// errorCatalog, by the Twilio product they belong to.
+var errorCodeRanges = []struct {
+	from, to int
+	info     ErrorInfo
+}{
+	{21700, 21799, ErrorInfo{Category: CategoryConfiguration, Description: "Messaging service error", Remediation: "Check the messaging service, its sender pool and its A2P campaign in the Twilio console."}},
+	{22200, 22299, ErrorInfo{Category: CategoryCompliance, Description: "TrustHub bundle or evaluation error", Remediation: "Check the customer profile or trust product, its evaluation results and its status in the TrustHub console, fix the customer data and submit it again."}},
+	{30700, 30899, ErrorInfo{Category: CategoryCompliance, Description: "A2P 10DLC brand or campaign registration error", Remediation: "Check the brand registration and campaign in the Twilio console; fix the reported brand or campaign details and register again."}},
+}
+
+// brandFeedbackCatalog explains the brand_feedback values TCR returns on a
+// failed or low-scoring brand registration.
+var brandFeedbackCatalog = map[string]ErrorInfo{
+	"TAX_ID":            {Category: CategoryValidation, Description: "Tax ID (EIN) could not be verified", Remediation: "The EIN and legal business name must match IRS records exactly, including suffixes such as LLC or Inc."},
+	"STOCK_SYMBOL":      {Category: CategoryValidation, Description: "Stock symbol could not be verified", Remediation: "Check the stock ticker and exchange, or register as a private company."},
+	"GOVERNMENT_ENTITY": {Category: CategoryValidation, Description: "Government entity could not be verified", Remediation: "Only government organisations may register as GOVERNMENT; otherwise choose another company type."},
+	"NONPROFIT":         {Category: CategoryValidation, Description: "Non-profit status could not be verified", Remediation: "Check the tax-exempt status and EIN of the non-profit."},
+	"OTHERS":            {Category: CategoryCompliance, Description: "Brand details could not be verified", Remediation: "Review the business name, address, website and EIN in the customer profile and resubmit."},
+}
+
+// LookupErrorCode returns the catalog entry for a Twilio error code. Codes
+// without an entry of their own get the entry of their product's range.
+func LookupErrorCode(code int) (ErrorInfo, bool) {
+	errorCatalogMu.RLock()
+	defer errorCatalogMu.RUnlock()
+	info, ok := errorCatalog[code]
+	if !ok {
+		for _, r := range errorCodeRanges {
+			if code >= r.from && code <= r.to {
+				info, ok = r.info, true
+				break
+			}
+		}
+	}
+	if ok {
+		info.Code = code
+	}
+	return info, ok
+}
+
+// RegisterErrorCode adds or replaces a catalog entry, for codes specific to
+// a deployment.
+func RegisterErrorCode(info ErrorInfo) {
+	errorCatalogMu.Lock()
+	defer errorCatalogMu.Unlock()
+	errorCatalog[info.Code] = info
+}
+
+// LookupBrandFeedback explains a brand_feedback value of a brand
+// registration, such as "TAX_ID".
+func LookupBrandFeedback(feedback string) (ErrorInfo, bool) {
+	info, ok := brandFeedbackCatalog[feedback]
+	return info, ok
+}
+
+// EvaluationError is returned by EvaluateSecondaryCustomerProfile and
+// EvaluateTrustProduct when TrustHub evaluates the bundle as noncompliant.
+// Submitting such a bundle for review would fail.
+type EvaluationError struct {
+	BundleSID     string
+	EvaluationSID string
+	Status        string
+	// Reasons lists the requirements the bundle does not meet.
+	Reasons []ErrorInfo
+}
+
+func (e *EvaluationError) Error() string {
+	descriptions := make([]string, 0, len(e.Reasons))
+	for _, reason := range e.Reasons {
+		descriptions = append(descriptions, reason.Description)
+	}
+	if len(descriptions) == 0 {
+		return fmt.Sprintf("bundle %s evaluated as %s", e.BundleSID, e.Status)
+	}
+	return fmt.Sprintf("bundle %s evaluated as %s: %s", e.BundleSID, e.Status, strings.Join(descriptions, "; "))
+}
+
+// noncompliantInfo is the StageError.Info of an EvaluationError.
+var noncompliantInfo = ErrorInfo{
+	Category:    CategoryCompliance,
+	Description: "Bundle evaluated as noncompliant",
+	Remediation: "Fix the customer data named in the reasons and run the onboarding again; the bundle was not submitted for review.",
+}
+
+// evaluationError returns an *EvaluationError for a noncompliant
+// evaluation, or nil.
+func evaluationError(bundleSID string, evaluationSID, status *string, results *[]interface{}) error {
+	if status == nil || *status != "noncompliant" {
+		return nil
+	}
+	return &EvaluationError{
+		BundleSID:     bundleSID,
+		EvaluationSID: deref(evaluationSID),
+		Status:        *status,
+		Reasons:       evaluationReasons(results),
+	}
+}
+
+// evaluationReasons reads the failed requirements out of the results of an
+// evaluation: every invalid field of a requirement that did not pass, or the
+// requirement itself when it names no field.
+func evaluationReasons(results *[]interface{}) []ErrorInfo {
+	if results == nil {
+		return nil
+	}
+	var reasons []ErrorInfo
+	for _, result := range *results {
+		requirement, ok := result.(map[string]interface{})
+		if !ok || requirement["passed"] == true {
+			continue
+		}
+		invalid, _ := requirement["invalid"].([]interface{})
+		if len(invalid) == 0 {
+			reasons = append(reasons, evaluationReason(requirement))
+			continue
+		}
+		for _, field := range invalid {
+			if field, ok := field.(map[string]interface{}); ok {
+				reasons = append(reasons, evaluationReason(field))
+			}
+		}
+	}
+	return reasons
+}
+
+func evaluationReason(entry map[string]interface{}) ErrorInfo {
+	var code int
+	if value, ok := entry["error_code"].(float64); ok {
+		code = int(value)
+	}
+	description, _ := entry["failure_reason"].(string)
+	if name, _ := entry["friendly_name"].(string); name != "" {
+		description = name + ": " + description
+	}
+	info, ok := LookupErrorCode(code)
+	if !ok {
+		info = ErrorInfo{Code: code, Category: CategoryCompliance, Remediation: "Correct this detail in the onboarding params and run the onboarding again."}
+	}
+	info.Description = description
+	return info
+}
+
+// APIError is how A2PService reports a failed Twilio request: the original
+// error with its catalog entry. errors.As still reaches the underlying
+// *client.TwilioRestError.
+type APIError struct {
+	Info ErrorInfo
+	// Status is the HTTP status, or 0 for network failures.
+	Status int
+	Err    error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%v [%s: %s]", e.Err, e.Info.Category, e.Info.Remediation)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// describeError wraps a client error in an *APIError. Errors that did not
+// come from a Twilio request, such as cancellation, are returned unchanged.
+func describeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return err
+	}
+
+	var restErr *client.TwilioRestError
+	if errors.As(err, &restErr) {
+		info, ok := LookupErrorCode(restErr.Code)
+		if !ok {
+			info = statusErrorInfo(restErr.Status)
+			info.Code = restErr.Code
+		}
+		return &APIError{Info: info, Status: restErr.Status, Err: err}
+	}
+	if m := undecodedStatusPattern.FindStringSubmatch(err.Error()); m != nil {
+		status, _ := strconv.Atoi(m[1])
+		return &APIError{Info: statusErrorInfo(status), Status: status, Err: err}
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && classifyRetry(err) != retryNever {
+		return &APIError{Info: ErrorInfo{
+			Category:    CategoryNetwork,
+			Description: "Request to Twilio failed",
+			Remediation: "Check connectivity to api.twilio.com; the request is safe to retry.",
+		}, Err: err}
+	}
+	return err
+}
+
+// statusErrorInfo is the fallback entry for codes missing from the catalog.
+func statusErrorInfo(status int) ErrorInfo {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrorInfo{Category: CategoryAuthentication, Description: http.StatusText(status), Remediation: "Check the credentials and that the account may access this resource."}
+	case status == http.StatusNotFound:
+		return ErrorInfo{Category: CategoryNotFound, Description: http.StatusText(status), Remediation: "A SID in the request does not exist in this account."}
+	case status == http.StatusConflict:
+		return ErrorInfo{Category: CategoryConflict, Description: http.StatusText(status), Remediation: "The resource already exists or is in use."}
+	case status == http.StatusTooManyRequests:
+		return ErrorInfo{Category: CategoryRateLimit, Description: http.StatusText(status), Remediation: "Retry later or lower the request rate."}
+	case status >= 500:
+		return ErrorInfo{Category: CategoryTwilioOutage, Description: http.StatusText(status), Remediation: "Retry later and check status.twilio.com if it persists."}
+	case status >= 400:
+		return ErrorInfo{Category: CategoryValidation, Description: http.StatusText(status), Remediation: "Twilio rejected the request data; see the error message for the field."}
+	default:
+		return ErrorInfo{Category: CategoryUnknown, Description: http.StatusText(status), Remediation: "See the Twilio error message."}
+	}
+}
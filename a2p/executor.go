@@ -0,0 +1,165 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// stepPanic carries the panic of a step run in its own goroutine to the
+// goroutine that runs the pipeline.
+type stepPanic struct {
+	step  string
+	value any
+	stack []byte
+}
+
+func (p *stepPanic) String() string {
+	return fmt.Sprintf("onboarding step %s panicked: %v\n\n%s", p.step, p.value, p.stack)
+}
+
+// execute runs steps as their dependencies allow, at most limit at a time,
+// preferring pipeline order. After a failure no further step starts; the
+// running ones finish and the first failure is returned. A step that panics
+// stops the run the same way, and the panic is raised again in the caller's
+// goroutine, where it can be recovered.
+func (r *onboardingRun) execute(ctx context.Context, steps []Step, limit int) error {
+	deps, err := stepDependencies(steps)
+	if err != nil {
+		return err
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	r.total = len(steps)
+	r.steps, r.deps = steps, deps
+	r.state.update(func(checkpoint *OnboardingCheckpoint) {
+		checkpoint.FailedStage = ""
+	})
+
+	type result struct {
+		i   int
+		err error
+		// panicked is set when the step panicked.
+		panicked *stepPanic
+	}
+	results := make(chan result)
+	started := make([]bool, len(steps))
+	finished := make([]bool, len(steps))
+	running := 0
+	var failed *StageError
+	var panicked *stepPanic
+	for {
+		for i, step := range steps {
+			if failed != nil || panicked != nil || running == limit {
+				break
+			}
+			if started[i] || !allFinished(deps[i], finished) {
+				continue
+			}
+			started[i] = true
+			running++
+			go func() {
+				res := result{i: i}
+				defer func() {
+					if p := recover(); p != nil {
+						res.panicked = &stepPanic{step: step.Name(), value: p, stack: debug.Stack()}
+					}
+					results <- res
+				}()
+				res.err = r.stage(ctx, step, i+1)
+			}()
+		}
+		if running == 0 {
+			break
+		}
+
+		res := <-results
+		running--
+		if res.panicked != nil {
+			if panicked == nil {
+				panicked = res.panicked
+			}
+			continue
+		}
+		if res.err == nil {
+			finished[res.i] = true
+			continue
+		}
+		if failed == nil {
+			errors.As(res.err, &failed)
+		}
+	}
+	if panicked != nil {
+		panic(panicked)
+	}
+	if failed != nil {
+		return r.fail(ctx, failed)
+	}
+	return nil
+}
+
+// stepDependencies returns the indexes of the steps each step waits for, see
+// FuncStep.DependsOn.
+func stepDependencies(steps []Step) ([][]int, error) {
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		index[step.Name()] = i
+	}
+
+	deps := make([][]int, len(steps))
+	barrier := -1
+	for i, step := range steps {
+		f, ok := step.(*FuncStep)
+		if !ok || f.DependsOn == nil {
+			for j := 0; j < i; j++ {
+				deps[i] = append(deps[i], j)
+			}
+			barrier = i
+			continue
+		}
+		if barrier >= 0 {
+			deps[i] = append(deps[i], barrier)
+		}
+		for _, name := range f.DependsOn {
+			if j, ok := index[name]; ok {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+
+	// Every step must be able to start once the steps before it in some
+	// order have finished.
+	finished := make([]bool, len(steps))
+	for remaining := len(steps); remaining > 0; {
+		progressed := false
+		for i := range steps {
+			if !finished[i] && allFinished(deps[i], finished) {
+				finished[i] = true
+				remaining--
+				progressed = true
+			}
+		}
+		if !progressed {
+			var stuck []string
+			for i, step := range steps {
+				if !finished[i] {
+					stuck = append(stuck, step.Name())
+				}
+			}
+			return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(stuck, ", "))
+		}
+	}
+	return deps, nil
+}
+
+func allFinished(deps []int, finished []bool) bool {
+	for _, j := range deps {
+		if !finished[j] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStepDependencies(t *testing.T) {
+	step := func(id string, dependsOn ...string) *FuncStep {
+		if dependsOn == nil {
+			dependsOn = []string{}
+		}
+		return &FuncStep{ID: id, DependsOn: dependsOn}
+	}
+	sequential := func(id string) *FuncStep {
+		return &FuncStep{ID: id}
+	}
+
+	tests := []struct {
+		name    string
+		steps   []Step
+		want    [][]int
+		wantErr error
+	}{
+		{
+			name:  "sequential by default",
+			steps: []Step{sequential("a"), sequential("b"), sequential("c")},
+			want:  [][]int{{}, {0}, {0, 1}},
+		},
+		{
+			name:  "declared dependencies",
+			steps: []Step{step("a"), step("b", "a"), step("c", "a"), step("d", "b", "c")},
+			want:  [][]int{{}, {0}, {0}, {1, 2}},
+		},
+		{
+			name:  "sequential step is a barrier",
+			steps: []Step{step("a"), step("b"), sequential("c"), step("d"), step("e", "d")},
+			want:  [][]int{{}, {}, {0, 1}, {2}, {2, 3}},
+		},
+		{
+			name:  "unknown dependencies ignored",
+			steps: []Step{step("a", "skipped"), step("b", "a")},
+			want:  [][]int{{}, {0}},
+		},
+		{
+			name:    "cycle",
+			steps:   []Step{step("a"), step("b", "a", "c"), step("c", "b")},
+			wantErr: ErrDependencyCycle,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stepDependencies(tt.steps)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("stepDependencies error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal[[]int]) {
+				t.Errorf("stepDependencies = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// executorTrace records the steps run by a pipeline.
+type executorTrace struct {
+	mu       sync.Mutex
+	started  []string
+	finished []string
+	running  int
+	peak     int
+}
+
+// step returns a step that records when it runs. Steps that succeed wait
+// briefly, so independent steps overlap; failing steps fail at once.
+func (tr *executorTrace) step(id string, err error, dependsOn ...string) *FuncStep {
+	return &FuncStep{
+		ID:        id,
+		DependsOn: append([]string{}, dependsOn...),
+		RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+			tr.mu.Lock()
+			tr.started = append(tr.started, id)
+			tr.running++
+			tr.peak = max(tr.peak, tr.running)
+			tr.mu.Unlock()
+
+			if err == nil {
+				time.Sleep(20 * time.Millisecond)
+			}
+
+			tr.mu.Lock()
+			defer tr.mu.Unlock()
+			tr.running--
+			if err == nil {
+				tr.finished = append(tr.finished, id)
+			}
+			return "", err
+		},
+	}
+}
+
+func TestExecutorConcurrency(t *testing.T) {
+	for _, limit := range []int{1, 2, 4} {
+		tr := &executorTrace{}
+		pipeline, err := NewPipeline(
+			tr.step("root", nil),
+			tr.step("a", nil, "root"),
+			tr.step("b", nil, "root"),
+			tr.step("c", nil, "root"),
+			tr.step("d", nil, "root"),
+			tr.step("join", nil, "a", "b", "c", "d"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := NewA2PService(NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts(), WithLogger(discardLogger()), WithPipeline(pipeline), WithConcurrency(limit))
+		if _, err := s.OnboardCustomer(context.Background(), testParams()); err != nil {
+			t.Fatalf("limit %d: OnboardCustomer: %v", limit, err)
+		}
+
+		if tr.peak != limit {
+			t.Errorf("limit %d: %d steps ran at once", limit, tr.peak)
+		}
+		if tr.started[0] != "root" || tr.finished[len(tr.finished)-1] != "join" || len(tr.finished) != 6 {
+			t.Errorf("limit %d: finished %v, want root first and join last", limit, tr.finished)
+		}
+	}
+}
+
+func TestExecutorStopsAfterFailure(t *testing.T) {
+	errStep := errors.New("step failed")
+	tr := &executorTrace{}
+	pipeline, err := NewPipeline(
+		tr.step("fails", errStep),
+		tr.step("independent", nil),
+		tr.step("after-failure", nil, "fails"),
+		tr.step("queued", nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewA2PService(NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts(), WithLogger(discardLogger()), WithPipeline(pipeline), WithConcurrency(2))
+
+	_, err = s.OnboardCustomer(context.Background(), testParams())
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) || stageErr.Stage != "fails" || !errors.Is(err, errStep) {
+		t.Fatalf("OnboardCustomer error = %v, want a StageError for fails", err)
+	}
+	// The step running next to the failing one finishes; nothing starts
+	// after the failure.
+	if !slices.Equal(tr.started, []string{"fails", "independent"}) && !slices.Equal(tr.started, []string{"independent", "fails"}) {
+		t.Errorf("started %v, want fails and independent only", tr.started)
+	}
+	if !slices.Equal(tr.finished, []string{"independent"}) {
+		t.Errorf("finished %v, want independent", tr.finished)
+	}
+}
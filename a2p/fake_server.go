@@ -0,0 +1,561 @@
+package a2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/twilio/twilio-go"
+	"github.com/twilio/twilio-go/client"
+)
+
+// FakeTwilioServer is an httptest stand-in for the TrustHub v1, Messaging v1
+// and API 2010 endpoints used by A2PService. It keeps every resource it
+// creates, answers with the same JSON shapes as Twilio and lets tests script
+// status changes, so OnboardCustomer and CompleteOnboarding can run end to
+// end with no network.
+//
+//	srv := NewFakeTwilioServer()
+//	defer srv.Close()
+//	svc := srv.Service("ACxxxxxxxx", "token")
+type FakeTwilioServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	seq         int
+	resources   map[string]map[string]interface{} // keyed by collection + "/" + sid
+	collections map[string][]string
+	statuses    map[string][]string
+	failures    []fakeFailure
+}
+
+type fakeFailure struct {
+	method string
+	path   string
+	err    client.TwilioRestError
+}
+
+// fakeRoute describes one Twilio collection. Segments written as "*" match
+// any SID; the last one is stored on created resources under parentField.
+type fakeRoute struct {
+	pattern     string
+	prefix      string
+	listKey     string
+	api2010     bool
+	parentField string
+	defaults    map[string]interface{}
+}
+
+var fakeRoutes = []fakeRoute{
+	{pattern: "v1/CustomerProfiles", prefix: "BU", listKey: "results", defaults: map[string]interface{}{"status": "draft"}},
+	{pattern: "v1/CustomerProfiles/*/EntityAssignments", prefix: "BV", listKey: "results", parentField: "customer_profile_sid"},
+	{pattern: "v1/CustomerProfiles/*/Evaluations", prefix: "EL", listKey: "results", parentField: "customer_profile_sid", defaults: map[string]interface{}{"status": "compliant", "results": []interface{}{}}},
+	{pattern: "v1/EndUsers", prefix: "IT", listKey: "results"},
+	{pattern: "v1/SupportingDocuments", prefix: "RD", listKey: "results", defaults: map[string]interface{}{"status": "draft"}},
+	{pattern: "v1/TrustProducts", prefix: "BU", listKey: "results", defaults: map[string]interface{}{"status": "draft"}},
+	{pattern: "v1/TrustProducts/*/EntityAssignments", prefix: "BV", listKey: "results", parentField: "trust_product_sid"},
+	{pattern: "v1/TrustProducts/*/Evaluations", prefix: "EL", listKey: "results", parentField: "trust_product_sid", defaults: map[string]interface{}{"status": "compliant", "results": []interface{}{}}},
+	{pattern: "v1/Policies", prefix: "RN", listKey: "results"},
+	{pattern: "v1/a2p/BrandRegistrations", prefix: "BN", listKey: "data", defaults: map[string]interface{}{"status": "PENDING", "brand_type": "STANDARD"}},
+	{pattern: "v1/Services", prefix: "MG", listKey: "services"},
+	{pattern: "v1/Services/*/PhoneNumbers", prefix: "PN", listKey: "phone_numbers", parentField: "service_sid"},
+	{pattern: "v1/Services/*/Compliance/Usa2p", prefix: "QE", listKey: "compliance", parentField: "messaging_service_sid", defaults: map[string]interface{}{"campaign_status": "IN_PROGRESS"}},
+	{pattern: "2010-04-01/Accounts", prefix: "AC", listKey: "accounts", api2010: true, defaults: map[string]interface{}{"status": "active", "type": "Full"}},
+	{pattern: "2010-04-01/Accounts/*/Addresses", prefix: "AD", listKey: "addresses", api2010: true, parentField: "account_sid"},
+	{pattern: "2010-04-01/Accounts/*/IncomingPhoneNumbers", prefix: "PN", listKey: "incoming_phone_numbers", api2010: true, parentField: "account_sid"},
+	{pattern: "2010-04-01/Accounts/*/AvailablePhoneNumbers/*/Local", listKey: "available_phone_numbers", api2010: true},
+}
+
+// Form fields that Twilio returns as JSON numbers or arrays.
+var (
+	fakeIntFields  = map[string]bool{"validity_period": true}
+	fakeListFields = map[string]bool{"message_samples": true, "opt_in_keywords": true, "opt_out_keywords": true, "help_keywords": true}
+)
+
+func NewFakeTwilioServer() *FakeTwilioServer {
+	f := &FakeTwilioServer{
+		resources:   map[string]map[string]interface{}{},
+		collections: map[string][]string{},
+		statuses:    map[string][]string{},
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	return f
+}
+
+// RestClient returns a twilio-go client authenticated as accountSid whose
+// requests to any *.twilio.com host are sent to the fake server instead.
+func (f *FakeTwilioServer) RestClient(accountSid, authToken string) *twilio.RestClient {
+	o := defaultServiceOptions()
+	WithHTTPClient(f.Client())(&o)
+	WithBaseURL(f.URL)(&o)
+	return newTwilioRestClient(AuthTokenCredentials(accountSid, authToken), o)
+}
+
+// Service returns an A2PService authenticated as accountSid and pointed at
+// the fake server with WithBaseURL. opts are applied after that.
+func (f *FakeTwilioServer) Service(accountSid, authToken string, opts ...Option) *A2PService {
+	opts = append([]Option{WithHTTPClient(f.Client()), WithBaseURL(f.URL)}, opts...)
+	return NewA2PServiceInstance(accountSid, authToken, opts...)
+}
+
+// Resource returns a copy of the stored JSON object for sid, or nil.
+func (f *FakeTwilioServer) Resource(sid string) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resource := f.lookup(sid)
+	if resource == nil {
+		return nil
+	}
+	return copyResource(resource)
+}
+
+// Count returns how many resources exist under a collection path such as
+// "v1/EndUsers" or "v1/CustomerProfiles/BU.../EntityAssignments".
+func (f *FakeTwilioServer) Count(collection string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.collections[strings.Trim(collection, "/")])
+}
+
+// SetStatus changes the status of a stored resource. Campaigns store it in
+// campaign_status, everything else in status.
+func (f *FakeTwilioServer) SetStatus(sid, status string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resource := f.lookup(sid)
+	if resource == nil {
+		return fmt.Errorf("resource %s not found", sid)
+	}
+	setResourceStatus(resource, status)
+	return nil
+}
+
+// SetStatusSequence makes each following fetch of sid move the resource to
+// the next status in order, e.g. ("IN_REVIEW", "APPROVED") for a brand. The
+// last status sticks once the sequence is exhausted.
+func (f *FakeTwilioServer) SetStatusSequence(sid string, statuses ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses[sid] = append([]string(nil), statuses...)
+}
+
+// AddIncomingPhoneNumber registers a purchased number on accountSid and
+// returns its PN SID.
+func (f *FakeTwilioServer) AddIncomingPhoneNumber(accountSid, phoneNumber string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	collection := "2010-04-01/Accounts/" + accountSid + "/IncomingPhoneNumbers"
+	return f.store(collection, "PN", map[string]interface{}{
+		"account_sid":   accountSid,
+		"phone_number":  phoneNumber,
+		"friendly_name": phoneNumber,
+		"status":        "in-use",
+	}, true)
+}
+
+// AddAvailablePhoneNumber makes phoneNumber show up in local number searches.
+func (f *FakeTwilioServer) AddAvailablePhoneNumber(countryCode, phoneNumber string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	collection := "2010-04-01/Accounts/*/AvailablePhoneNumbers/" + countryCode + "/Local"
+	f.store(collection, "", map[string]interface{}{
+		"phone_number":  phoneNumber,
+		"friendly_name": phoneNumber,
+		"iso_country":   countryCode,
+	}, true)
+}
+
+// FailNext makes the next request whose method matches and whose path ends
+// with pathSuffix fail with the given Twilio error. Failures are consumed in
+// the order they were added.
+func (f *FakeTwilioServer) FailNext(method, pathSuffix string, status, code int, message string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.failures, fakeFailure{
+		method: method,
+		path:   strings.Trim(pathSuffix, "/"),
+		err: client.TwilioRestError{
+			Status:   status,
+			Code:     code,
+			Message:  message,
+			MoreInfo: fmt.Sprintf("https://www.twilio.com/docs/errors/%d", code),
+		},
+	})
+}
+
+func (f *FakeTwilioServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	accountSid, _, ok := r.BasicAuth()
+	if !ok || accountSid == "" {
+		writeFakeError(w, client.TwilioRestError{Status: http.StatusUnauthorized, Code: 20003, Message: "Authenticate"})
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeFakeError(w, client.TwilioRestError{Status: http.StatusBadRequest, Code: 20001, Message: err.Error()})
+		return
+	}
+
+	path := strings.TrimSuffix(strings.Trim(r.URL.Path, "/"), ".json")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if failure, ok := f.takeFailure(r.Method, path); ok {
+		writeFakeError(w, failure.err)
+		return
+	}
+
+	if strings.HasSuffix(path, "/Compliance/Usa2p/Usecases") && r.Method == http.MethodGet {
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+			"us_app_to_person_usecases": []interface{}{
+				map[string]interface{}{"code": "DELIVERY_NOTIFICATION", "name": "Delivery Notification", "post_approval_required": false},
+				map[string]interface{}{"code": "CUSTOMER_CARE", "name": "Customer Care", "post_approval_required": false},
+			},
+		})
+		return
+	}
+
+	route, collection, sid, ok := matchFakeRoute(path)
+	if !ok {
+		writeFakeError(w, client.TwilioRestError{Status: http.StatusNotFound, Code: 20404, Message: "The requested resource " + r.URL.Path + " was not found"})
+		return
+	}
+
+	switch {
+	case sid == "" && r.Method == http.MethodPost:
+		f.create(w, r, route, collection, accountSid)
+	case sid == "" && r.Method == http.MethodGet:
+		f.list(w, r, route, collection)
+	case sid != "" && r.Method == http.MethodGet:
+		f.fetch(w, r, route, collection, sid)
+	case sid != "" && r.Method == http.MethodPost:
+		f.update(w, r, collection, sid)
+	case sid != "" && r.Method == http.MethodDelete:
+		f.delete(w, r, collection, sid)
+	default:
+		writeFakeError(w, client.TwilioRestError{Status: http.StatusMethodNotAllowed, Code: 20004, Message: "Method not allowed"})
+	}
+}
+
+func (f *FakeTwilioServer) takeFailure(method, path string) (fakeFailure, bool) {
+	for i, failure := range f.failures {
+		if failure.method == method && strings.HasSuffix(path, failure.path) {
+			f.failures = append(f.failures[:i], f.failures[i+1:]...)
+			return failure, true
+		}
+	}
+	return fakeFailure{}, false
+}
+
+func (f *FakeTwilioServer) create(w http.ResponseWriter, r *http.Request, route fakeRoute, collection, accountSid string) {
+	if route.prefix == "" {
+		writeFakeError(w, client.TwilioRestError{Status: http.StatusMethodNotAllowed, Code: 20004, Message: "Method not allowed"})
+		return
+	}
+
+	resource := map[string]interface{}{"account_sid": accountSid}
+	for key, value := range route.defaults {
+		resource[key] = value
+	}
+	for key, value := range formToResource(r.PostForm) {
+		resource[key] = value
+	}
+	if route.parentField != "" {
+		resource[route.parentField] = parentSid(route.pattern, collection)
+	}
+
+	prefix := route.prefix
+	switch route.pattern {
+	case "v1/Services/*/PhoneNumbers":
+		// Attaching a number keeps the incoming number's PN SID.
+		resource["sid"] = resource["phone_number_sid"]
+		delete(resource, "phone_number_sid")
+		for key := range f.resources {
+			if strings.HasPrefix(key, "v1/Services/") && strings.HasSuffix(key, "/PhoneNumbers/"+fmt.Sprint(resource["sid"])) {
+				writeFakeError(w, client.TwilioRestError{Status: http.StatusConflict, Code: 21710, Message: "Phone Number is already in the Messaging Service"})
+				return
+			}
+		}
+		prefix = ""
+	case "2010-04-01/Accounts":
+		resource["owner_account_sid"] = accountSid
+		resource["auth_token"] = fmt.Sprintf("%032x", f.seq+1)
+	}
+
+	f.store(collection, prefix, resource, route.api2010)
+	writeFakeJSON(w, http.StatusCreated, resource)
+}
+
+func (f *FakeTwilioServer) list(w http.ResponseWriter, r *http.Request, route fakeRoute, collection string) {
+	filters := formToResource(r.URL.Query())
+	for _, key := range []string{"page_size", "page", "page_token"} {
+		delete(filters, key)
+	}
+
+	match := matchesFilters
+	if strings.Contains(route.pattern, "AvailablePhoneNumbers") {
+		match = matchesSearch
+	}
+
+	records := []interface{}{}
+	for _, sid := range f.collections[collection] {
+		resource := f.resources[collection+"/"+sid]
+		if match(resource, filters) {
+			records = append(records, resource)
+		}
+	}
+
+	body := map[string]interface{}{route.listKey: records}
+	if route.api2010 {
+		body["page"] = 0
+		body["page_size"] = len(records)
+		body["uri"] = r.URL.Path
+		body["first_page_uri"] = r.URL.Path
+		body["next_page_uri"] = nil
+	} else {
+		body["meta"] = map[string]interface{}{
+			"key":            route.listKey,
+			"page":           0,
+			"page_size":      len(records),
+			"url":            r.URL.Path,
+			"first_page_url": r.URL.Path,
+			"next_page_url":  nil,
+		}
+	}
+	writeFakeJSON(w, http.StatusOK, body)
+}
+
+func (f *FakeTwilioServer) fetch(w http.ResponseWriter, r *http.Request, route fakeRoute, collection, sid string) {
+	resource, ok := f.resources[collection+"/"+sid]
+	if !ok && route.pattern == "v1/Policies" {
+		resource = map[string]interface{}{"sid": sid, "friendly_name": "Secondary Customer Profile of type Business", "requirements": map[string]interface{}{}}
+		ok = true
+	}
+	if !ok {
+		writeFakeError(w, client.TwilioRestError{Status: http.StatusNotFound, Code: 20404, Message: "The requested resource " + r.URL.Path + " was not found"})
+		return
+	}
+	if next := f.statuses[sid]; len(next) > 0 {
+		setResourceStatus(resource, next[0])
+		if len(next) > 1 {
+			f.statuses[sid] = next[1:]
+		} else {
+			delete(f.statuses, sid)
+		}
+	}
+	writeFakeJSON(w, http.StatusOK, resource)
+}
+
+func (f *FakeTwilioServer) update(w http.ResponseWriter, r *http.Request, collection, sid string) {
+	resource, ok := f.resources[collection+"/"+sid]
+	if !ok {
+		writeFakeError(w, client.TwilioRestError{Status: http.StatusNotFound, Code: 20404, Message: "The requested resource " + r.URL.Path + " was not found"})
+		return
+	}
+	for key, value := range formToResource(r.PostForm) {
+		resource[key] = value
+	}
+	writeFakeJSON(w, http.StatusOK, resource)
+}
+
+func (f *FakeTwilioServer) delete(w http.ResponseWriter, r *http.Request, collection, sid string) {
+	if _, ok := f.resources[collection+"/"+sid]; !ok {
+		writeFakeError(w, client.TwilioRestError{Status: http.StatusNotFound, Code: 20404, Message: "The requested resource " + r.URL.Path + " was not found"})
+		return
+	}
+	delete(f.resources, collection+"/"+sid)
+	sids := f.collections[collection]
+	for i, existing := range sids {
+		if existing == sid {
+			f.collections[collection] = append(sids[:i], sids[i+1:]...)
+			break
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// store saves resource under a new SID (or the SID it already carries when
+// prefix is empty) and stamps the creation dates.
+func (f *FakeTwilioServer) store(collection, prefix string, resource map[string]interface{}, api2010 bool) string {
+	f.seq++
+	if prefix != "" {
+		resource["sid"] = fmt.Sprintf("%s%032x", prefix, f.seq)
+	}
+	sid, _ := resource["sid"].(string)
+	if sid == "" {
+		sid = fmt.Sprintf("%032x", f.seq)
+	}
+
+	now := time.Now().UTC()
+	if api2010 {
+		resource["date_created"] = now.Format(time.RFC1123Z)
+		resource["date_updated"] = now.Format(time.RFC1123Z)
+	} else {
+		resource["date_created"] = now.Format(time.RFC3339)
+		resource["date_updated"] = now.Format(time.RFC3339)
+		resource["url"] = "https://fake.twilio.com/" + collection + "/" + sid
+	}
+
+	f.resources[collection+"/"+sid] = resource
+	f.collections[collection] = append(f.collections[collection], sid)
+	return sid
+}
+
+// lookup finds a resource by SID alone. A number attached to a messaging
+// service shares its SID with the incoming number; the latter wins.
+func (f *FakeTwilioServer) lookup(sid string) map[string]interface{} {
+	var found map[string]interface{}
+	for key, resource := range f.resources {
+		if !strings.HasSuffix(key, "/"+sid) {
+			continue
+		}
+		if found == nil || strings.HasPrefix(key, "2010-04-01/") {
+			found = resource
+		}
+	}
+	return found
+}
+
+// matchFakeRoute resolves a request path to its route, the concrete
+// collection path and, for instance requests, the resource SID.
+func matchFakeRoute(path string) (fakeRoute, string, string, bool) {
+	segments := strings.Split(path, "/")
+	for _, route := range fakeRoutes {
+		pattern := strings.Split(route.pattern, "/")
+		if len(segments) != len(pattern) && len(segments) != len(pattern)+1 {
+			continue
+		}
+		matched := true
+		for i, part := range pattern {
+			if part != "*" && part != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		collection := strings.Join(segments[:len(pattern)], "/")
+		if strings.Contains(route.pattern, "AvailablePhoneNumbers") {
+			// Searches are shared by every account.
+			collection = "2010-04-01/Accounts/*/" + strings.Join(segments[3:len(pattern)], "/")
+		}
+		if len(segments) == len(pattern) {
+			return route, collection, "", true
+		}
+		return route, collection, segments[len(segments)-1], true
+	}
+	return fakeRoute{}, "", "", false
+}
+
+func parentSid(pattern, collection string) string {
+	segments := strings.Split(collection, "/")
+	parent := ""
+	for i, part := range strings.Split(pattern, "/") {
+		if part == "*" {
+			parent = segments[i]
+		}
+	}
+	return parent
+}
+
+// formToResource converts Twilio's PascalCase form fields to the snake_case
+// JSON fields of the resource, decoding JSON, boolean and numeric values.
+func formToResource(form url.Values) map[string]interface{} {
+	resource := map[string]interface{}{}
+	for key, values := range form {
+		field := snakeCase(key)
+		switch {
+		case fakeListFields[field] || len(values) > 1:
+			resource[field] = append([]string(nil), values...)
+		case field == "attributes":
+			var attributes interface{}
+			if err := json.Unmarshal([]byte(values[0]), &attributes); err != nil {
+				attributes = values[0]
+			}
+			resource[field] = attributes
+		case fakeIntFields[field]:
+			n, _ := strconv.Atoi(values[0])
+			resource[field] = n
+		case values[0] == "true" || values[0] == "false":
+			resource[field] = values[0] == "true"
+		default:
+			resource[field] = values[0]
+		}
+	}
+	return resource
+}
+
+func matchesFilters(resource, filters map[string]interface{}) bool {
+	for key, value := range filters {
+		if fmt.Sprint(resource[key]) != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesSearch applies the number search criteria the fake understands,
+// AreaCode and Contains; the others are ignored.
+func matchesSearch(resource, filters map[string]interface{}) bool {
+	number := strings.TrimPrefix(fmt.Sprint(resource["phone_number"]), "+1")
+	if areaCode, ok := filters["area_code"]; ok && fmt.Sprint(areaCode) != "0" && !strings.HasPrefix(number, fmt.Sprint(areaCode)) {
+		return false
+	}
+	if contains, ok := filters["contains"]; ok && !strings.Contains(number, fmt.Sprint(contains)) {
+		return false
+	}
+	return true
+}
+
+func setResourceStatus(resource map[string]interface{}, status string) {
+	if _, ok := resource["campaign_status"]; ok {
+		resource["campaign_status"] = status
+		return
+	}
+	resource["status"] = status
+}
+
+func copyResource(resource map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(resource))
+	for key, value := range resource {
+		out[key] = value
+	}
+	return out
+}
+
+// snakeCase turns "A2PProfileBundleSid" into "a2p_profile_bundle_sid".
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeFakeError(w http.ResponseWriter, err client.TwilioRestError) {
+	if err.MoreInfo == "" {
+		err.MoreInfo = fmt.Sprintf("https://www.twilio.com/docs/errors/%d", err.Code)
+	}
+	writeFakeJSON(w, err.Status, err)
+}
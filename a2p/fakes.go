@@ -0,0 +1,946 @@
+package a2p
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+	messaging "github.com/twilio/twilio-go/rest/messaging/v1"
+	trusthub "github.com/twilio/twilio-go/rest/trusthub/v1"
+)
+
+// The fakes below keep every resource in memory so A2PService can be
+// exercised without Twilio credentials or network access. Each create call
+// stores the resource under a new SID with the same prefix Twilio uses.
+// FailOn makes a method return the given error instead of touching state, and
+// a cancelled context fails every call with ctx.Err().
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+type fakeBase struct {
+	mu     sync.Mutex
+	seq    int
+	errors map[string]error
+}
+
+func (f *fakeBase) nextSid(prefix string) string {
+	f.seq++
+	return fmt.Sprintf("%s%032x", prefix, f.seq)
+}
+
+// FailOn makes every later call to method (for example "CreateEndUser")
+// return err. Passing a nil err clears the failure.
+func (f *fakeBase) FailOn(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.errors == nil {
+		f.errors = map[string]error{}
+	}
+	if err == nil {
+		delete(f.errors, method)
+		return
+	}
+	f.errors[method] = err
+}
+
+func (f *fakeBase) fail(ctx context.Context, method string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.errors[method]
+}
+
+type fakeAssignment struct {
+	bundleSid string
+	objectSid string
+}
+
+// FakeTrustHub is an in-memory TrustHubClient.
+type FakeTrustHub struct {
+	fakeBase
+	CustomerProfiles    map[string]*trusthub.TrusthubV1CustomerProfile
+	EndUsers            map[string]*trusthub.TrusthubV1EndUser
+	SupportingDocuments map[string]*trusthub.TrusthubV1SupportingDocument
+	TrustProducts       map[string]*trusthub.TrusthubV1TrustProduct
+	// Assignments maps a bundle SID (customer profile or trust product) to
+	// the SIDs of the objects attached to it.
+	Assignments map[string][]string
+	// assignmentSids maps an assignment SID to the bundle and object it
+	// attached.
+	assignmentSids map[string]fakeAssignment
+	// EvaluationStatus is returned by every evaluation; defaults to "compliant".
+	EvaluationStatus string
+	// EvaluationResults are the results returned with EvaluationStatus.
+	EvaluationResults []interface{}
+}
+
+func NewFakeTrustHub() *FakeTrustHub {
+	return &FakeTrustHub{
+		CustomerProfiles:    map[string]*trusthub.TrusthubV1CustomerProfile{},
+		EndUsers:            map[string]*trusthub.TrusthubV1EndUser{},
+		SupportingDocuments: map[string]*trusthub.TrusthubV1SupportingDocument{},
+		TrustProducts:       map[string]*trusthub.TrusthubV1TrustProduct{},
+		Assignments:         map[string][]string{},
+		assignmentSids:      map[string]fakeAssignment{},
+		EvaluationStatus:    "compliant",
+	}
+}
+
+// SetBundleStatus moves an existing customer profile or trust product to
+// status, for example "twilio-approved", with the rejection errors given.
+func (f *FakeTrustHub) SetBundleStatus(sid, status string, errs ...interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rejections *[]interface{}
+	if len(errs) > 0 {
+		rejections = &errs
+	}
+	if profile, ok := f.CustomerProfiles[sid]; ok {
+		profile.Status, profile.Errors = ptr(status), rejections
+		return nil
+	}
+	if product, ok := f.TrustProducts[sid]; ok {
+		product.Status, product.Errors = ptr(status), rejections
+		return nil
+	}
+	return fmt.Errorf("bundle %s not found", sid)
+}
+
+func (f *FakeTrustHub) CreateCustomerProfile(ctx context.Context, params *trusthub.CreateCustomerProfileParams) (*trusthub.TrusthubV1CustomerProfile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "CreateCustomerProfile"); err != nil {
+		return nil, err
+	}
+	profile := &trusthub.TrusthubV1CustomerProfile{
+		Sid:            ptr(f.nextSid("BU")),
+		PolicySid:      params.PolicySid,
+		FriendlyName:   params.FriendlyName,
+		Email:          params.Email,
+		StatusCallback: params.StatusCallback,
+		Status:         ptr("draft"),
+	}
+	f.CustomerProfiles[*profile.Sid] = profile
+	return profile, nil
+}
+
+func (f *FakeTrustHub) ListCustomerProfile(ctx context.Context, params *trusthub.ListCustomerProfileParams) ([]trusthub.TrusthubV1CustomerProfile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "ListCustomerProfile"); err != nil {
+		return nil, err
+	}
+	var profiles []trusthub.TrusthubV1CustomerProfile
+	for _, profile := range f.CustomerProfiles {
+		if params.FriendlyName == nil || deref(profile.FriendlyName) == *params.FriendlyName {
+			profiles = append(profiles, *profile)
+		}
+	}
+	return profiles, nil
+}
+
+func (f *FakeTrustHub) FetchCustomerProfile(ctx context.Context, sid string) (*trusthub.TrusthubV1CustomerProfile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "FetchCustomerProfile"); err != nil {
+		return nil, err
+	}
+	resource, ok := f.CustomerProfiles[sid]
+	if !ok {
+		return nil, fmt.Errorf("customer profile %s not found", sid)
+	}
+	copied := *resource
+	return &copied, nil
+}
+
+func (f *FakeTrustHub) UpdateCustomerProfile(ctx context.Context, sid string, params *trusthub.UpdateCustomerProfileParams) (*trusthub.TrusthubV1CustomerProfile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "UpdateCustomerProfile"); err != nil {
+		return nil, err
+	}
+	profile, ok := f.CustomerProfiles[sid]
+	if !ok {
+		return nil, fmt.Errorf("customer profile %s not found", sid)
+	}
+	if params.Status != nil {
+		profile.Status = params.Status
+	}
+	return profile, nil
+}
+
+func (f *FakeTrustHub) DeleteCustomerProfile(ctx context.Context, sid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "DeleteCustomerProfile"); err != nil {
+		return err
+	}
+	if _, ok := f.CustomerProfiles[sid]; !ok {
+		return fmt.Errorf("customer profile %s not found", sid)
+	}
+	delete(f.CustomerProfiles, sid)
+	f.dropAssignments(sid)
+	return nil
+}
+
+func (f *FakeTrustHub) CreateCustomerProfileEntityAssignment(ctx context.Context, customerProfileSid string, params *trusthub.CreateCustomerProfileEntityAssignmentParams) (*trusthub.TrusthubV1CustomerProfileEntityAssignment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "CreateCustomerProfileEntityAssignment"); err != nil {
+		return nil, err
+	}
+	if _, ok := f.CustomerProfiles[customerProfileSid]; !ok {
+		return nil, fmt.Errorf("customer profile %s not found", customerProfileSid)
+	}
+	f.Assignments[customerProfileSid] = append(f.Assignments[customerProfileSid], deref(params.ObjectSid))
+	sid := f.nextSid("BV")
+	f.assignmentSids[sid] = fakeAssignment{bundleSid: customerProfileSid, objectSid: deref(params.ObjectSid)}
+	return &trusthub.TrusthubV1CustomerProfileEntityAssignment{
+		Sid:                ptr(sid),
+		CustomerProfileSid: ptr(customerProfileSid),
+		ObjectSid:          params.ObjectSid,
+	}, nil
+}
+
+func (f *FakeTrustHub) ListCustomerProfileEntityAssignment(ctx context.Context, customerProfileSid string, params *trusthub.ListCustomerProfileEntityAssignmentParams) ([]trusthub.TrusthubV1CustomerProfileEntityAssignment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "ListCustomerProfileEntityAssignment"); err != nil {
+		return nil, err
+	}
+	var assignments []trusthub.TrusthubV1CustomerProfileEntityAssignment
+	for _, objectSid := range f.Assignments[customerProfileSid] {
+		assignments = append(assignments, trusthub.TrusthubV1CustomerProfileEntityAssignment{
+			Sid:                ptr(f.assignmentSid(customerProfileSid, objectSid)),
+			CustomerProfileSid: ptr(customerProfileSid),
+			ObjectSid:          ptr(objectSid),
+		})
+	}
+	return assignments, nil
+}
+
+func (f *FakeTrustHub) DeleteCustomerProfileEntityAssignment(ctx context.Context, customerProfileSid string, sid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "DeleteCustomerProfileEntityAssignment"); err != nil {
+		return err
+	}
+	return f.detach(customerProfileSid, sid)
+}
+
+func (f *FakeTrustHub) CreateCustomerProfileEvaluation(ctx context.Context, customerProfileSid string, params *trusthub.CreateCustomerProfileEvaluationParams) (*trusthub.TrusthubV1CustomerProfileEvaluation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "CreateCustomerProfileEvaluation"); err != nil {
+		return nil, err
+	}
+	return &trusthub.TrusthubV1CustomerProfileEvaluation{
+		Sid:                ptr(f.nextSid("EL")),
+		PolicySid:          params.PolicySid,
+		CustomerProfileSid: ptr(customerProfileSid),
+		Status:             ptr(f.EvaluationStatus),
+		Results:            ptr(f.EvaluationResults),
+	}, nil
+}
+
+func (f *FakeTrustHub) CreateEndUser(ctx context.Context, params *trusthub.CreateEndUserParams) (*trusthub.TrusthubV1EndUser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "CreateEndUser"); err != nil {
+		return nil, err
+	}
+	var attributes interface{} = deref(params.Attributes)
+	endUser := &trusthub.TrusthubV1EndUser{
+		Sid:          ptr(f.nextSid("IT")),
+		FriendlyName: params.FriendlyName,
+		Type:         params.Type,
+		Attributes:   &attributes,
+	}
+	f.EndUsers[*endUser.Sid] = endUser
+	return endUser, nil
+}
+
+func (f *FakeTrustHub) ListEndUser(ctx context.Context, params *trusthub.ListEndUserParams) ([]trusthub.TrusthubV1EndUser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "ListEndUser"); err != nil {
+		return nil, err
+	}
+	endUsers := make([]trusthub.TrusthubV1EndUser, 0, len(f.EndUsers))
+	for _, endUser := range f.EndUsers {
+		endUsers = append(endUsers, *endUser)
+	}
+	return endUsers, nil
+}
+
+func (f *FakeTrustHub) DeleteEndUser(ctx context.Context, sid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "DeleteEndUser"); err != nil {
+		return err
+	}
+	if _, ok := f.EndUsers[sid]; !ok {
+		return fmt.Errorf("end user %s not found", sid)
+	}
+	delete(f.EndUsers, sid)
+	return nil
+}
+
+func (f *FakeTrustHub) CreateSupportingDocument(ctx context.Context, params *trusthub.CreateSupportingDocumentParams) (*trusthub.TrusthubV1SupportingDocument, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "CreateSupportingDocument"); err != nil {
+		return nil, err
+	}
+	var attributes interface{} = deref(params.Attributes)
+	document := &trusthub.TrusthubV1SupportingDocument{
+		Sid:          ptr(f.nextSid("RD")),
+		FriendlyName: params.FriendlyName,
+		Type:         params.Type,
+		Attributes:   &attributes,
+		Status:       ptr("draft"),
+	}
+	f.SupportingDocuments[*document.Sid] = document
+	return document, nil
+}
+
+func (f *FakeTrustHub) ListSupportingDocument(ctx context.Context, params *trusthub.ListSupportingDocumentParams) ([]trusthub.TrusthubV1SupportingDocument, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "ListSupportingDocument"); err != nil {
+		return nil, err
+	}
+	documents := make([]trusthub.TrusthubV1SupportingDocument, 0, len(f.SupportingDocuments))
+	for _, document := range f.SupportingDocuments {
+		documents = append(documents, *document)
+	}
+	return documents, nil
+}
+
+func (f *FakeTrustHub) DeleteSupportingDocument(ctx context.Context, sid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "DeleteSupportingDocument"); err != nil {
+		return err
+	}
+	if _, ok := f.SupportingDocuments[sid]; !ok {
+		return fmt.Errorf("supporting document %s not found", sid)
+	}
+	delete(f.SupportingDocuments, sid)
+	return nil
+}
+
+func (f *FakeTrustHub) CreateTrustProduct(ctx context.Context, params *trusthub.CreateTrustProductParams) (*trusthub.TrusthubV1TrustProduct, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "CreateTrustProduct"); err != nil {
+		return nil, err
+	}
+	product := &trusthub.TrusthubV1TrustProduct{
+		Sid:            ptr(f.nextSid("BU")),
+		PolicySid:      params.PolicySid,
+		FriendlyName:   params.FriendlyName,
+		Email:          params.Email,
+		StatusCallback: params.StatusCallback,
+		Status:         ptr("draft"),
+	}
+	f.TrustProducts[*product.Sid] = product
+	return product, nil
+}
+
+func (f *FakeTrustHub) ListTrustProduct(ctx context.Context, params *trusthub.ListTrustProductParams) ([]trusthub.TrusthubV1TrustProduct, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "ListTrustProduct"); err != nil {
+		return nil, err
+	}
+	var products []trusthub.TrusthubV1TrustProduct
+	for _, product := range f.TrustProducts {
+		if params.FriendlyName == nil || deref(product.FriendlyName) == *params.FriendlyName {
+			products = append(products, *product)
+		}
+	}
+	return products, nil
+}
+
+func (f *FakeTrustHub) FetchTrustProduct(ctx context.Context, sid string) (*trusthub.TrusthubV1TrustProduct, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "FetchTrustProduct"); err != nil {
+		return nil, err
+	}
+	resource, ok := f.TrustProducts[sid]
+	if !ok {
+		return nil, fmt.Errorf("trust product %s not found", sid)
+	}
+	copied := *resource
+	return &copied, nil
+}
+
+func (f *FakeTrustHub) UpdateTrustProduct(ctx context.Context, sid string, params *trusthub.UpdateTrustProductParams) (*trusthub.TrusthubV1TrustProduct, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "UpdateTrustProduct"); err != nil {
+		return nil, err
+	}
+	product, ok := f.TrustProducts[sid]
+	if !ok {
+		return nil, fmt.Errorf("trust product %s not found", sid)
+	}
+	if params.Status != nil {
+		product.Status = params.Status
+	}
+	return product, nil
+}
+
+func (f *FakeTrustHub) DeleteTrustProduct(ctx context.Context, sid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "DeleteTrustProduct"); err != nil {
+		return err
+	}
+	if _, ok := f.TrustProducts[sid]; !ok {
+		return fmt.Errorf("trust product %s not found", sid)
+	}
+	delete(f.TrustProducts, sid)
+	f.dropAssignments(sid)
+	return nil
+}
+
+func (f *FakeTrustHub) CreateTrustProductEntityAssignment(ctx context.Context, trustProductSid string, params *trusthub.CreateTrustProductEntityAssignmentParams) (*trusthub.TrusthubV1TrustProductEntityAssignment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "CreateTrustProductEntityAssignment"); err != nil {
+		return nil, err
+	}
+	if _, ok := f.TrustProducts[trustProductSid]; !ok {
+		return nil, fmt.Errorf("trust product %s not found", trustProductSid)
+	}
+	f.Assignments[trustProductSid] = append(f.Assignments[trustProductSid], deref(params.ObjectSid))
+	sid := f.nextSid("BV")
+	f.assignmentSids[sid] = fakeAssignment{bundleSid: trustProductSid, objectSid: deref(params.ObjectSid)}
+	return &trusthub.TrusthubV1TrustProductEntityAssignment{
+		Sid:             ptr(sid),
+		TrustProductSid: ptr(trustProductSid),
+		ObjectSid:       params.ObjectSid,
+	}, nil
+}
+
+func (f *FakeTrustHub) ListTrustProductEntityAssignment(ctx context.Context, trustProductSid string, params *trusthub.ListTrustProductEntityAssignmentParams) ([]trusthub.TrusthubV1TrustProductEntityAssignment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "ListTrustProductEntityAssignment"); err != nil {
+		return nil, err
+	}
+	var assignments []trusthub.TrusthubV1TrustProductEntityAssignment
+	for _, objectSid := range f.Assignments[trustProductSid] {
+		assignments = append(assignments, trusthub.TrusthubV1TrustProductEntityAssignment{
+			Sid:             ptr(f.assignmentSid(trustProductSid, objectSid)),
+			TrustProductSid: ptr(trustProductSid),
+			ObjectSid:       ptr(objectSid),
+		})
+	}
+	return assignments, nil
+}
+
+func (f *FakeTrustHub) DeleteTrustProductEntityAssignment(ctx context.Context, trustProductSid string, sid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "DeleteTrustProductEntityAssignment"); err != nil {
+		return err
+	}
+	return f.detach(trustProductSid, sid)
+}
+
+// detach removes assignment sid from bundle. Callers hold f.mu.
+func (f *FakeTrustHub) detach(bundleSid, sid string) error {
+	assignment, ok := f.assignmentSids[sid]
+	if !ok || assignment.bundleSid != bundleSid {
+		return fmt.Errorf("entity assignment %s not found on %s", sid, bundleSid)
+	}
+	delete(f.assignmentSids, sid)
+	for i, attached := range f.Assignments[bundleSid] {
+		if attached == assignment.objectSid {
+			f.Assignments[bundleSid] = append(f.Assignments[bundleSid][:i:i], f.Assignments[bundleSid][i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// dropAssignments forgets everything attached to a deleted bundle. Callers
+// hold f.mu.
+func (f *FakeTrustHub) dropAssignments(bundleSid string) {
+	delete(f.Assignments, bundleSid)
+	for sid, assignment := range f.assignmentSids {
+		if assignment.bundleSid == bundleSid {
+			delete(f.assignmentSids, sid)
+		}
+	}
+}
+
+// assignmentSid returns the SID of the assignment that attached objectSid to
+// bundleSid. Callers hold f.mu.
+func (f *FakeTrustHub) assignmentSid(bundleSid, objectSid string) string {
+	for sid, assignment := range f.assignmentSids {
+		if assignment == (fakeAssignment{bundleSid: bundleSid, objectSid: objectSid}) {
+			return sid
+		}
+	}
+	return ""
+}
+
+func (f *FakeTrustHub) CreateTrustProductEvaluation(ctx context.Context, trustProductSid string, params *trusthub.CreateTrustProductEvaluationParams) (*trusthub.TrusthubV1TrustProductEvaluation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "CreateTrustProductEvaluation"); err != nil {
+		return nil, err
+	}
+	return &trusthub.TrusthubV1TrustProductEvaluation{
+		Sid:             ptr(f.nextSid("EL")),
+		PolicySid:       params.PolicySid,
+		TrustProductSid: ptr(trustProductSid),
+		Status:          ptr(f.EvaluationStatus),
+		Results:         ptr(f.EvaluationResults),
+	}, nil
+}
+
+func (f *FakeTrustHub) FetchPolicies(ctx context.Context, sid string) (*trusthub.TrusthubV1Policies, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "FetchPolicies"); err != nil {
+		return nil, err
+	}
+	return &trusthub.TrusthubV1Policies{
+		Sid:          ptr(sid),
+		FriendlyName: ptr("Secondary Customer Profile of type Business"),
+	}, nil
+}
+
+// FakeMessaging is an in-memory MessagingClient. New brand registrations
+// start in BrandStatus; use SetBrandStatus to script later transitions.
+type FakeMessaging struct {
+	fakeBase
+	BrandRegistrations map[string]*messaging.MessagingV1BrandRegistrations
+	Services           map[string]*messaging.MessagingV1Service
+	// PhoneNumbers maps a messaging service SID to its attached numbers.
+	PhoneNumbers map[string][]*messaging.MessagingV1PhoneNumber
+	Campaigns    map[string]*messaging.MessagingV1UsAppToPerson
+	// BrandStatus is the status of newly created brands; defaults to "PENDING".
+	BrandStatus string
+	// CampaignStatus is the status of newly created campaigns; defaults to "IN_PROGRESS".
+	CampaignStatus string
+}
+
+func NewFakeMessaging() *FakeMessaging {
+	return &FakeMessaging{
+		BrandRegistrations: map[string]*messaging.MessagingV1BrandRegistrations{},
+		Services:           map[string]*messaging.MessagingV1Service{},
+		PhoneNumbers:       map[string][]*messaging.MessagingV1PhoneNumber{},
+		Campaigns:          map[string]*messaging.MessagingV1UsAppToPerson{},
+		BrandStatus:        "PENDING",
+		CampaignStatus:     "IN_PROGRESS",
+	}
+}
+
+// SetBrandStatus moves an existing brand registration to status.
+func (f *FakeMessaging) SetBrandStatus(sid, status string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	brand, ok := f.BrandRegistrations[sid]
+	if !ok {
+		return fmt.Errorf("brand registration %s not found", sid)
+	}
+	brand.Status = ptr(status)
+	return nil
+}
+
+// SetCampaignStatus moves an existing campaign to status.
+func (f *FakeMessaging) SetCampaignStatus(sid, status string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	campaign, ok := f.Campaigns[sid]
+	if !ok {
+		return fmt.Errorf("campaign %s not found", sid)
+	}
+	campaign.CampaignStatus = ptr(status)
+	return nil
+}
+
+func (f *FakeMessaging) CreateBrandRegistrations(ctx context.Context, params *messaging.CreateBrandRegistrationsParams) (*messaging.MessagingV1BrandRegistrations, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "CreateBrandRegistrations"); err != nil {
+		return nil, err
+	}
+	brand := &messaging.MessagingV1BrandRegistrations{
+		Sid:                      ptr(f.nextSid("BN")),
+		CustomerProfileBundleSid: params.CustomerProfileBundleSid,
+		A2pProfileBundleSid:      params.A2PProfileBundleSid,
+		SkipAutomaticSecVet:      params.SkipAutomaticSecVet,
+		BrandType:                ptr("STANDARD"),
+		Status:                   ptr(f.BrandStatus),
+	}
+	f.BrandRegistrations[*brand.Sid] = brand
+	return brand, nil
+}
+
+func (f *FakeMessaging) FetchBrandRegistrations(ctx context.Context, sid string) (*messaging.MessagingV1BrandRegistrations, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "FetchBrandRegistrations"); err != nil {
+		return nil, err
+	}
+	brand, ok := f.BrandRegistrations[sid]
+	if !ok {
+		return nil, fmt.Errorf("brand registration %s not found", sid)
+	}
+	// A copy, so SetBrandStatus does not race with the caller.
+	copied := *brand
+	return &copied, nil
+}
+
+func (f *FakeMessaging) ListBrandRegistrations(ctx context.Context, params *messaging.ListBrandRegistrationsParams) ([]messaging.MessagingV1BrandRegistrations, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "ListBrandRegistrations"); err != nil {
+		return nil, err
+	}
+	brands := make([]messaging.MessagingV1BrandRegistrations, 0, len(f.BrandRegistrations))
+	for _, brand := range f.BrandRegistrations {
+		brands = append(brands, *brand)
+	}
+	return brands, nil
+}
+
+func (f *FakeMessaging) CreateService(ctx context.Context, params *messaging.CreateServiceParams) (*messaging.MessagingV1Service, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "CreateService"); err != nil {
+		return nil, err
+	}
+	service := &messaging.MessagingV1Service{
+		Sid:               ptr(f.nextSid("MG")),
+		FriendlyName:      params.FriendlyName,
+		InboundRequestUrl: params.InboundRequestUrl,
+		FallbackUrl:       params.FallbackUrl,
+		StatusCallback:    params.StatusCallback,
+		Usecase:           params.Usecase,
+	}
+	f.Services[*service.Sid] = service
+	return service, nil
+}
+
+func (f *FakeMessaging) ListService(ctx context.Context, params *messaging.ListServiceParams) ([]messaging.MessagingV1Service, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "ListService"); err != nil {
+		return nil, err
+	}
+	services := make([]messaging.MessagingV1Service, 0, len(f.Services))
+	for _, service := range f.Services {
+		services = append(services, *service)
+	}
+	return services, nil
+}
+
+func (f *FakeMessaging) UpdateService(ctx context.Context, sid string, params *messaging.UpdateServiceParams) (*messaging.MessagingV1Service, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "UpdateService"); err != nil {
+		return nil, err
+	}
+	service, ok := f.Services[sid]
+	if !ok {
+		return nil, fmt.Errorf("messaging service %s not found", sid)
+	}
+	if params.StatusCallback != nil {
+		service.StatusCallback = params.StatusCallback
+	}
+	return service, nil
+}
+
+func (f *FakeMessaging) DeleteService(ctx context.Context, sid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "DeleteService"); err != nil {
+		return err
+	}
+	if _, ok := f.Services[sid]; !ok {
+		return fmt.Errorf("messaging service %s not found", sid)
+	}
+	delete(f.Services, sid)
+	return nil
+}
+
+func (f *FakeMessaging) CreatePhoneNumber(ctx context.Context, serviceSid string, params *messaging.CreatePhoneNumberParams) (*messaging.MessagingV1PhoneNumber, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "CreatePhoneNumber"); err != nil {
+		return nil, err
+	}
+	if _, ok := f.Services[serviceSid]; !ok {
+		return nil, fmt.Errorf("messaging service %s not found", serviceSid)
+	}
+	number := &messaging.MessagingV1PhoneNumber{
+		Sid:        params.PhoneNumberSid,
+		ServiceSid: ptr(serviceSid),
+	}
+	f.PhoneNumbers[serviceSid] = append(f.PhoneNumbers[serviceSid], number)
+	return number, nil
+}
+
+func (f *FakeMessaging) ListPhoneNumber(ctx context.Context, serviceSid string, params *messaging.ListPhoneNumberParams) ([]messaging.MessagingV1PhoneNumber, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "ListPhoneNumber"); err != nil {
+		return nil, err
+	}
+	numbers := make([]messaging.MessagingV1PhoneNumber, 0, len(f.PhoneNumbers[serviceSid]))
+	for _, number := range f.PhoneNumbers[serviceSid] {
+		numbers = append(numbers, *number)
+	}
+	return numbers, nil
+}
+
+func (f *FakeMessaging) FetchUsAppToPersonUsecase(ctx context.Context, messagingServiceSid string, params *messaging.FetchUsAppToPersonUsecaseParams) (*messaging.MessagingV1UsAppToPersonUsecase, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "FetchUsAppToPersonUsecase"); err != nil {
+		return nil, err
+	}
+	return &messaging.MessagingV1UsAppToPersonUsecase{
+		UsAppToPersonUsecases: &[]interface{}{},
+	}, nil
+}
+
+func (f *FakeMessaging) CreateUsAppToPerson(ctx context.Context, messagingServiceSid string, params *messaging.CreateUsAppToPersonParams) (*messaging.MessagingV1UsAppToPerson, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "CreateUsAppToPerson"); err != nil {
+		return nil, err
+	}
+	if _, ok := f.Services[messagingServiceSid]; !ok {
+		return nil, fmt.Errorf("messaging service %s not found", messagingServiceSid)
+	}
+	campaign := &messaging.MessagingV1UsAppToPerson{
+		Sid:                  ptr(f.nextSid("QE")),
+		BrandRegistrationSid: params.BrandRegistrationSid,
+		MessagingServiceSid:  ptr(messagingServiceSid),
+		Description:          params.Description,
+		MessageSamples:       params.MessageSamples,
+		UsAppToPersonUsecase: params.UsAppToPersonUsecase,
+		HasEmbeddedLinks:     params.HasEmbeddedLinks,
+		HasEmbeddedPhone:     params.HasEmbeddedPhone,
+		MessageFlow:          params.MessageFlow,
+		OptInMessage:         params.OptInMessage,
+		OptOutMessage:        params.OptOutMessage,
+		HelpMessage:          params.HelpMessage,
+		OptInKeywords:        params.OptInKeywords,
+		OptOutKeywords:       params.OptOutKeywords,
+		HelpKeywords:         params.HelpKeywords,
+		CampaignStatus:       ptr(f.CampaignStatus),
+	}
+	f.Campaigns[*campaign.Sid] = campaign
+	return campaign, nil
+}
+
+func (f *FakeMessaging) FetchUsAppToPerson(ctx context.Context, messagingServiceSid string, sid string) (*messaging.MessagingV1UsAppToPerson, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "FetchUsAppToPerson"); err != nil {
+		return nil, err
+	}
+	campaign, ok := f.Campaigns[sid]
+	if !ok || deref(campaign.MessagingServiceSid) != messagingServiceSid {
+		return nil, fmt.Errorf("campaign %s not found", sid)
+	}
+	copied := *campaign
+	return &copied, nil
+}
+
+func (f *FakeMessaging) ListUsAppToPerson(ctx context.Context, messagingServiceSid string, params *messaging.ListUsAppToPersonParams) ([]messaging.MessagingV1UsAppToPerson, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "ListUsAppToPerson"); err != nil {
+		return nil, err
+	}
+	var campaigns []messaging.MessagingV1UsAppToPerson
+	for _, campaign := range f.Campaigns {
+		if deref(campaign.MessagingServiceSid) == messagingServiceSid {
+			campaigns = append(campaigns, *campaign)
+		}
+	}
+	return campaigns, nil
+}
+
+// FakeAccounts is an in-memory AccountsClient. Seed IncomingPhoneNumbers and
+// AvailablePhoneNumbers via AddIncomingPhoneNumber or by setting the field.
+type FakeAccounts struct {
+	fakeBase
+	Accounts              map[string]*api.ApiV2010Account
+	Addresses             map[string]*api.ApiV2010Address
+	IncomingPhoneNumbers  []api.ApiV2010IncomingPhoneNumber
+	AvailablePhoneNumbers []api.ApiV2010AvailablePhoneNumberLocal
+}
+
+func NewFakeAccounts() *FakeAccounts {
+	return &FakeAccounts{
+		Accounts:  map[string]*api.ApiV2010Account{},
+		Addresses: map[string]*api.ApiV2010Address{},
+	}
+}
+
+// AddIncomingPhoneNumber registers a purchased number and returns its SID.
+func (f *FakeAccounts) AddIncomingPhoneNumber(phoneNumber string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sid := f.nextSid("PN")
+	f.IncomingPhoneNumbers = append(f.IncomingPhoneNumbers, api.ApiV2010IncomingPhoneNumber{
+		Sid:         ptr(sid),
+		PhoneNumber: ptr(phoneNumber),
+	})
+	return sid
+}
+
+func (f *FakeAccounts) CreateAccount(ctx context.Context, params *api.CreateAccountParams) (*api.ApiV2010Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "CreateAccount"); err != nil {
+		return nil, err
+	}
+	account := &api.ApiV2010Account{
+		Sid:          ptr(f.nextSid("AC")),
+		AuthToken:    ptr(fmt.Sprintf("%032x", f.seq)),
+		FriendlyName: params.FriendlyName,
+		Status:       ptr("active"),
+		Type:         ptr("Full"),
+	}
+	f.Accounts[*account.Sid] = account
+	return account, nil
+}
+
+func (f *FakeAccounts) ListAccount(ctx context.Context, params *api.ListAccountParams) ([]api.ApiV2010Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "ListAccount"); err != nil {
+		return nil, err
+	}
+	var accounts []api.ApiV2010Account
+	for _, account := range f.Accounts {
+		if params.FriendlyName == nil || deref(account.FriendlyName) == *params.FriendlyName {
+			accounts = append(accounts, *account)
+		}
+	}
+	return accounts, nil
+}
+
+func (f *FakeAccounts) UpdateAccount(ctx context.Context, sid string, params *api.UpdateAccountParams) (*api.ApiV2010Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "UpdateAccount"); err != nil {
+		return nil, err
+	}
+	account, ok := f.Accounts[sid]
+	if !ok {
+		return nil, fmt.Errorf("account %s not found", sid)
+	}
+	if params.Status != nil {
+		account.Status = params.Status
+	}
+	return account, nil
+}
+
+func (f *FakeAccounts) CreateAddress(ctx context.Context, params *api.CreateAddressParams) (*api.ApiV2010Address, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "CreateAddress"); err != nil {
+		return nil, err
+	}
+	address := &api.ApiV2010Address{
+		Sid:             ptr(f.nextSid("AD")),
+		CustomerName:    params.CustomerName,
+		Street:          params.Street,
+		StreetSecondary: params.StreetSecondary,
+		City:            params.City,
+		Region:          params.Region,
+		PostalCode:      params.PostalCode,
+		IsoCountry:      params.IsoCountry,
+		FriendlyName:    params.FriendlyName,
+	}
+	f.Addresses[*address.Sid] = address
+	return address, nil
+}
+
+func (f *FakeAccounts) ListAddress(ctx context.Context, params *api.ListAddressParams) ([]api.ApiV2010Address, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "ListAddress"); err != nil {
+		return nil, err
+	}
+	var addresses []api.ApiV2010Address
+	for _, address := range f.Addresses {
+		if params.CustomerName != nil && deref(address.CustomerName) != *params.CustomerName {
+			continue
+		}
+		if params.FriendlyName != nil && deref(address.FriendlyName) != *params.FriendlyName {
+			continue
+		}
+		addresses = append(addresses, *address)
+	}
+	return addresses, nil
+}
+
+func (f *FakeAccounts) DeleteAddress(ctx context.Context, sid string, params *api.DeleteAddressParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "DeleteAddress"); err != nil {
+		return err
+	}
+	if _, ok := f.Addresses[sid]; !ok {
+		return fmt.Errorf("address %s not found", sid)
+	}
+	delete(f.Addresses, sid)
+	return nil
+}
+
+func (f *FakeAccounts) ListIncomingPhoneNumber(ctx context.Context, params *api.ListIncomingPhoneNumberParams) ([]api.ApiV2010IncomingPhoneNumber, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "ListIncomingPhoneNumber"); err != nil {
+		return nil, err
+	}
+	var numbers []api.ApiV2010IncomingPhoneNumber
+	for _, number := range f.IncomingPhoneNumbers {
+		if params.PhoneNumber == nil || deref(number.PhoneNumber) == *params.PhoneNumber {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers, nil
+}
+
+func (f *FakeAccounts) ListAvailablePhoneNumberLocal(ctx context.Context, countryCode string, params *api.ListAvailablePhoneNumberLocalParams) ([]api.ApiV2010AvailablePhoneNumberLocal, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx, "ListAvailablePhoneNumberLocal"); err != nil {
+		return nil, err
+	}
+	return append([]api.ApiV2010AvailablePhoneNumberLocal(nil), f.AvailablePhoneNumbers...), nil
+}
+
+var (
+	_ TrustHubClient  = (*FakeTrustHub)(nil)
+	_ MessagingClient = (*FakeMessaging)(nil)
+	_ AccountsClient  = (*FakeAccounts)(nil)
+)
@@ -0,0 +1,160 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+	messaging "github.com/twilio/twilio-go/rest/messaging/v1"
+	trusthub "github.com/twilio/twilio-go/rest/trusthub/v1"
+)
+
+func TestFakesCreateSIDs(t *testing.T) {
+	ctx := context.Background()
+	trustHub, messagingFake, accounts := NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts()
+
+	tests := []struct {
+		name   string
+		prefix string
+		create func() (*string, error)
+	}{
+		{"customer profile", "BU", func() (*string, error) {
+			resp, err := trustHub.CreateCustomerProfile(ctx, &trusthub.CreateCustomerProfileParams{FriendlyName: ptr("Acme")})
+			return resp.Sid, err
+		}},
+		{"end user", "IT", func() (*string, error) {
+			resp, err := trustHub.CreateEndUser(ctx, &trusthub.CreateEndUserParams{FriendlyName: ptr("Acme")})
+			return resp.Sid, err
+		}},
+		{"supporting document", "RD", func() (*string, error) {
+			resp, err := trustHub.CreateSupportingDocument(ctx, &trusthub.CreateSupportingDocumentParams{FriendlyName: ptr("Acme")})
+			return resp.Sid, err
+		}},
+		{"trust product", "BU", func() (*string, error) {
+			resp, err := trustHub.CreateTrustProduct(ctx, &trusthub.CreateTrustProductParams{FriendlyName: ptr("Acme")})
+			return resp.Sid, err
+		}},
+		{"brand registration", "BN", func() (*string, error) {
+			resp, err := messagingFake.CreateBrandRegistrations(ctx, &messaging.CreateBrandRegistrationsParams{})
+			return resp.Sid, err
+		}},
+		{"messaging service", "MG", func() (*string, error) {
+			resp, err := messagingFake.CreateService(ctx, &messaging.CreateServiceParams{FriendlyName: ptr("Acme")})
+			return resp.Sid, err
+		}},
+		{"account", "AC", func() (*string, error) {
+			resp, err := accounts.CreateAccount(ctx, &api.CreateAccountParams{FriendlyName: ptr("Acme")})
+			return resp.Sid, err
+		}},
+		{"address", "AD", func() (*string, error) {
+			resp, err := accounts.CreateAddress(ctx, &api.CreateAddressParams{CustomerName: ptr("Acme")})
+			return resp.Sid, err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sid, err := tt.create()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(deref(sid), tt.prefix) || len(deref(sid)) != 34 {
+				t.Errorf("SID = %q, want %s followed by 32 hex digits", deref(sid), tt.prefix)
+			}
+		})
+	}
+}
+
+func TestFakeFailOn(t *testing.T) {
+	errInjected := errors.New("injected")
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		failOn  error
+		wantErr error
+	}{
+		{name: "no failure", ctx: context.Background()},
+		{name: "injected", ctx: context.Background(), failOn: errInjected, wantErr: errInjected},
+		{name: "cancelled context", ctx: cancelled, wantErr: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFakeTrustHub()
+			f.FailOn("CreateEndUser", tt.failOn)
+			_, err := f.CreateEndUser(tt.ctx, &trusthub.CreateEndUserParams{FriendlyName: ptr("Acme")})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CreateEndUser error = %v, want %v", err, tt.wantErr)
+			}
+			wantStored := 1
+			if tt.wantErr != nil {
+				wantStored = 0
+			}
+			if len(f.EndUsers) != wantStored {
+				t.Errorf("%d end users stored, want %d", len(f.EndUsers), wantStored)
+			}
+
+			// A nil error clears the failure.
+			f.FailOn("CreateEndUser", nil)
+			if _, err := f.CreateEndUser(context.Background(), &trusthub.CreateEndUserParams{}); err != nil {
+				t.Errorf("CreateEndUser after clearing the failure: %v", err)
+			}
+		})
+	}
+}
+
+func TestFakeFetchReturnsCopy(t *testing.T) {
+	ctx := context.Background()
+	f := NewFakeTrustHub()
+	created, err := f.CreateCustomerProfile(ctx, &trusthub.CreateCustomerProfileParams{FriendlyName: ptr("Acme")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched, err := f.FetchCustomerProfile(ctx, *created.Sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched.Status = ptr("twilio-approved")
+
+	again, err := f.FetchCustomerProfile(ctx, *created.Sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := deref(again.Status); got != "draft" {
+		t.Errorf("status after changing a fetched copy = %q, want draft", got)
+	}
+	if _, err := f.FetchCustomerProfile(ctx, "BU404"); err == nil {
+		t.Error("FetchCustomerProfile of an unknown SID succeeded")
+	}
+}
+
+func TestOnboardCustomerWithFakes(t *testing.T) {
+	ctx := context.Background()
+	trustHub, messagingFake, accounts := NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts()
+	accounts.AddIncomingPhoneNumber(testParams().TwilioPurchasedPhoneNumber)
+	s := NewA2PService(trustHub, messagingFake, accounts, WithLogger(discardLogger()))
+
+	resp, err := s.OnboardCustomer(ctx, testParams())
+	if err != nil {
+		t.Fatalf("OnboardCustomer: %v", err)
+	}
+	if resp.Data.State != StateBrandPending {
+		t.Errorf("state = %s, want %s", resp.Data.State, StateBrandPending)
+	}
+	if len(trustHub.CustomerProfiles) != 1 || len(trustHub.TrustProducts) != 1 {
+		t.Errorf("%d customer profiles and %d trust products, want one each", len(trustHub.CustomerProfiles), len(trustHub.TrustProducts))
+	}
+	brand, ok := messagingFake.BrandRegistrations[resp.Data.BrandRegistrationSID]
+	if !ok {
+		t.Fatalf("brand registration %s not stored", resp.Data.BrandRegistrationSID)
+	}
+	if _, ok := trustHub.CustomerProfiles[deref(brand.CustomerProfileBundleSid)]; !ok {
+		t.Errorf("brand customer profile %s is not the one created", deref(brand.CustomerProfileBundleSid))
+	}
+	if _, ok := trustHub.TrustProducts[deref(brand.A2pProfileBundleSid)]; !ok {
+		t.Errorf("brand trust product %s is not the one created", deref(brand.A2pProfileBundleSid))
+	}
+}
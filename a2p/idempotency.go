@@ -0,0 +1,300 @@
+package a2p
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+	messaging "github.com/twilio/twilio-go/rest/messaging/v1"
+	trusthub "github.com/twilio/twilio-go/rest/trusthub/v1"
+)
+
+// The find functions below locate the resource a create call would produce,
+// matched on the fields onboarding sets: the name together with the submitted
+// attributes, address or URLs for named resources, the attached SID for
+// assignments and phone numbers, and the bundle or brand SID for brands and
+// campaigns. Bundles rejected by Twilio and failed or deleted registrations
+// never match, so a corrected rerun creates fresh ones. They back both the
+// create-or-reuse lookups of A2PService and the retry decorators, which pass
+// the start of the failed attempt as since; the zero time matches resources
+// of any age.
+
+// reuseKey carries a *reuseRecorder through the context of a pipeline step,
+// so the run learns whether the step created its resource or reused one.
+type reuseKey struct{}
+
+type reuseRecorder struct {
+	mu     sync.Mutex
+	reused bool
+}
+
+func (r *reuseRecorder) get() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reused
+}
+
+// reuseOrCreate returns the resource find locates, and only calls create when
+// there is none, so running a step twice does not duplicate its resource. A
+// reuse is reported to the step's reuseRecorder: the run did not create the
+// resource, so a rollback must leave it alone.
+func reuseOrCreate[T any](ctx context.Context, s *A2PService, kind string, find, create func(context.Context) (*T, error)) (*T, error) {
+	existing, err := find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up existing %s: %w", kind, err)
+	}
+	if existing != nil {
+		s.logger.Info("reusing existing resource", "resource", kind)
+		if recorder, ok := ctx.Value(reuseKey{}).(*reuseRecorder); ok {
+			recorder.mu.Lock()
+			recorder.reused = true
+			recorder.mu.Unlock()
+		}
+		return existing, nil
+	}
+	return create(ctx)
+}
+
+// correlatedName appends key to name, so resources of different customers
+// sharing an account never match each other's lookups.
+func correlatedName(name, key string) string {
+	if key == "" {
+		return name
+	}
+	return fmt.Sprintf("%s [%s]", name, key)
+}
+
+func findCustomerProfile(ctx context.Context, c TrustHubClient, params *trusthub.CreateCustomerProfileParams, since time.Time) (*trusthub.TrusthubV1CustomerProfile, error) {
+	profiles, err := c.ListCustomerProfile(ctx, &trusthub.ListCustomerProfileParams{FriendlyName: params.FriendlyName})
+	if err != nil {
+		return nil, err
+	}
+	for i := range profiles {
+		if deref(profiles[i].Email) == deref(params.Email) && deref(profiles[i].PolicySid) == deref(params.PolicySid) &&
+			deref(profiles[i].Status) != bundleRejected && createdSince(profiles[i].DateCreated, since) {
+			return &profiles[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func findCustomerProfileEntityAssignment(ctx context.Context, c TrustHubClient, customerProfileSid string, params *trusthub.CreateCustomerProfileEntityAssignmentParams) (*trusthub.TrusthubV1CustomerProfileEntityAssignment, error) {
+	assignments, err := c.ListCustomerProfileEntityAssignment(ctx, customerProfileSid, &trusthub.ListCustomerProfileEntityAssignmentParams{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range assignments {
+		if deref(assignments[i].ObjectSid) == deref(params.ObjectSid) {
+			return &assignments[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func findEndUser(ctx context.Context, c TrustHubClient, params *trusthub.CreateEndUserParams, since time.Time) (*trusthub.TrusthubV1EndUser, error) {
+	endUsers, err := c.ListEndUser(ctx, &trusthub.ListEndUserParams{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range endUsers {
+		if deref(endUsers[i].FriendlyName) == deref(params.FriendlyName) && deref(endUsers[i].Type) == deref(params.Type) &&
+			sameAttributes(endUsers[i].Attributes, params.Attributes) && createdSince(endUsers[i].DateCreated, since) {
+			return &endUsers[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func findSupportingDocument(ctx context.Context, c TrustHubClient, params *trusthub.CreateSupportingDocumentParams, since time.Time) (*trusthub.TrusthubV1SupportingDocument, error) {
+	documents, err := c.ListSupportingDocument(ctx, &trusthub.ListSupportingDocumentParams{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range documents {
+		if deref(documents[i].FriendlyName) == deref(params.FriendlyName) && deref(documents[i].Type) == deref(params.Type) &&
+			sameAttributes(documents[i].Attributes, params.Attributes) && createdSince(documents[i].DateCreated, since) {
+			return &documents[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func findTrustProduct(ctx context.Context, c TrustHubClient, params *trusthub.CreateTrustProductParams, since time.Time) (*trusthub.TrusthubV1TrustProduct, error) {
+	products, err := c.ListTrustProduct(ctx, &trusthub.ListTrustProductParams{FriendlyName: params.FriendlyName})
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		if deref(products[i].Email) == deref(params.Email) && deref(products[i].PolicySid) == deref(params.PolicySid) &&
+			deref(products[i].Status) != bundleRejected && createdSince(products[i].DateCreated, since) {
+			return &products[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func findTrustProductEntityAssignment(ctx context.Context, c TrustHubClient, trustProductSid string, params *trusthub.CreateTrustProductEntityAssignmentParams) (*trusthub.TrusthubV1TrustProductEntityAssignment, error) {
+	assignments, err := c.ListTrustProductEntityAssignment(ctx, trustProductSid, &trusthub.ListTrustProductEntityAssignmentParams{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range assignments {
+		if deref(assignments[i].ObjectSid) == deref(params.ObjectSid) {
+			return &assignments[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// findBrandRegistration matches on the two bundles. A brand registration is
+// billed when created, so one that exists for the bundles is reused whatever
+// its age, unless it failed or was deleted.
+func findBrandRegistration(ctx context.Context, c MessagingClient, params *messaging.CreateBrandRegistrationsParams) (*messaging.MessagingV1BrandRegistrations, error) {
+	brands, err := c.ListBrandRegistrations(ctx, &messaging.ListBrandRegistrationsParams{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range brands {
+		if deref(brands[i].CustomerProfileBundleSid) == deref(params.CustomerProfileBundleSid) && deref(brands[i].A2pProfileBundleSid) == deref(params.A2PProfileBundleSid) &&
+			!registrationEnded(deref(brands[i].Status)) {
+			return &brands[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func findService(ctx context.Context, c MessagingClient, params *messaging.CreateServiceParams, since time.Time) (*messaging.MessagingV1Service, error) {
+	services, err := c.ListService(ctx, &messaging.ListServiceParams{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range services {
+		if deref(services[i].FriendlyName) == deref(params.FriendlyName) && deref(services[i].InboundRequestUrl) == deref(params.InboundRequestUrl) &&
+			deref(services[i].FallbackUrl) == deref(params.FallbackUrl) && createdSince(services[i].DateCreated, since) {
+			return &services[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func findPhoneNumber(ctx context.Context, c MessagingClient, serviceSid string, params *messaging.CreatePhoneNumberParams) (*messaging.MessagingV1PhoneNumber, error) {
+	numbers, err := c.ListPhoneNumber(ctx, serviceSid, &messaging.ListPhoneNumberParams{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range numbers {
+		if deref(numbers[i].Sid) == deref(params.PhoneNumberSid) {
+			return &numbers[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func findUsAppToPerson(ctx context.Context, c MessagingClient, messagingServiceSid string, params *messaging.CreateUsAppToPersonParams) (*messaging.MessagingV1UsAppToPerson, error) {
+	campaigns, err := c.ListUsAppToPerson(ctx, messagingServiceSid, &messaging.ListUsAppToPersonParams{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range campaigns {
+		if deref(campaigns[i].BrandRegistrationSid) == deref(params.BrandRegistrationSid) && !registrationEnded(deref(campaigns[i].CampaignStatus)) {
+			return &campaigns[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func findAccount(ctx context.Context, c AccountsClient, params *api.CreateAccountParams, since time.Time) (*api.ApiV2010Account, error) {
+	accounts, err := c.ListAccount(ctx, &api.ListAccountParams{FriendlyName: params.FriendlyName})
+	if err != nil {
+		return nil, err
+	}
+	for i := range accounts {
+		if createdSince2010(accounts[i].DateCreated, since) {
+			return &accounts[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func findAddress(ctx context.Context, c AccountsClient, params *api.CreateAddressParams, since time.Time) (*api.ApiV2010Address, error) {
+	addresses, err := c.ListAddress(ctx, &api.ListAddressParams{CustomerName: params.CustomerName, FriendlyName: params.FriendlyName})
+	if err != nil {
+		return nil, err
+	}
+	for i := range addresses {
+		address := &addresses[i]
+		if deref(address.Street) == deref(params.Street) && deref(address.City) == deref(params.City) &&
+			deref(address.Region) == deref(params.Region) && deref(address.PostalCode) == deref(params.PostalCode) &&
+			deref(address.IsoCountry) == deref(params.IsoCountry) && createdSince2010(address.DateCreated, since) {
+			return &addresses[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// bundleRejected is the status of a customer profile or trust product that
+// failed review; it cannot be submitted again.
+const bundleRejected = "twilio-rejected"
+
+// registrationEnded reports a brand or campaign status that will not become
+// approved.
+func registrationEnded(status string) bool {
+	switch strings.ToUpper(status) {
+	case "FAILED", "DELETED":
+		return true
+	}
+	return false
+}
+
+// sameAttributes compares the attributes of an existing TrustHub resource
+// with the submitted ones through their JSON form, since Twilio returns them
+// decoded as generic maps and lists.
+func sameAttributes(existing *interface{}, submitted *interface{}) bool {
+	normalize := func(attributes *interface{}) interface{} {
+		if attributes == nil {
+			return nil
+		}
+		value := *attributes
+		if raw, ok := value.(string); ok {
+			var decoded interface{}
+			if json.Unmarshal([]byte(raw), &decoded) == nil {
+				return decoded
+			}
+			return raw
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return value
+		}
+		var decoded interface{}
+		if json.Unmarshal(data, &decoded) != nil {
+			return value
+		}
+		return decoded
+	}
+	return reflect.DeepEqual(normalize(existing), normalize(submitted))
+}
+
+// createdSince reports whether a resource may have been created at or after
+// since. Resources without a creation date (the in-memory fakes) match.
+func createdSince(created *time.Time, since time.Time) bool {
+	return created == nil || !created.Before(since)
+}
+
+// createdSince2010 is createdSince for API 2010 dates, which are RFC 1123
+// strings.
+func createdSince2010(created *string, since time.Time) bool {
+	if created == nil {
+		return true
+	}
+	t, err := time.Parse(time.RFC1123Z, *created)
+	if err != nil {
+		return true
+	}
+	return !t.Before(since)
+}
@@ -0,0 +1,200 @@
+package a2p
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// InboundMessage is a message sent to one of the customer's numbers.
+type InboundMessage struct {
+	MessageSid          string
+	AccountSid          string
+	MessagingServiceSID string
+	From                string
+	To                  string
+	Body                string
+	// OptedOut is set when the sender has opted out of messages.
+	OptedOut bool
+	// Params holds every parameter of the webhook request.
+	Params url.Values
+}
+
+// InboundMessageFunc handles an inbound message that is not a campaign
+// keyword. A non-empty reply is sent back to the sender, unless the sender
+// has opted out.
+type InboundMessageFunc func(ctx context.Context, msg InboundMessage) (reply string, err error)
+
+type keywordKind int
+
+const (
+	keywordNone keywordKind = iota
+	keywordOptOut
+	keywordOptIn
+	keywordHelp
+)
+
+// Carriers expect these keywords to work whatever the campaign registered.
+var (
+	standardOptOutKeywords = []string{"STOP", "STOPALL", "UNSUBSCRIBE", "CANCEL", "END", "QUIT"}
+	standardOptInKeywords  = []string{"START", "UNSTOP"}
+	standardHelpKeywords   = []string{"HELP", "INFO"}
+)
+
+// InboundMessageHandler returns an http.Handler for the messaging service's
+// inbound webhook, see CallbackURLs.InboundRequestURL. Messages consisting of
+// one of the campaign's opt-out, opt-in or help keywords (see
+// WithCampaignKeywords), or of STOP, START or HELP, are answered with the
+// campaign's message through TwiML; opt-outs and opt-ins are recorded in
+// consents. Any other message is passed to onMessage, which may be nil; senders
+// who opted out get no reply.
+//
+// A nil consents uses the service's Store when it is a ConsentStore, such as
+// FileStore. Twilio's own Advanced Opt-Out, when enabled on the messaging
+// service, answers the standard keywords as well.
+func (s *A2PService) InboundMessageHandler(consents ConsentStore, onMessage InboundMessageFunc) (http.Handler, error) {
+	if consents == nil {
+		consents, _ = s.store.(ConsentStore)
+	}
+	if consents == nil {
+		return nil, ErrConsentStoreRequired
+	}
+	return &inboundMessageHandler{service: s, consents: consents, onMessage: onMessage}, nil
+}
+
+type inboundMessageHandler struct {
+	service   *A2PService
+	consents  ConsentStore
+	onMessage InboundMessageFunc
+}
+
+func (h *inboundMessageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	msg := InboundMessage{
+		MessageSid:          r.PostForm.Get("MessageSid"),
+		AccountSid:          r.PostForm.Get("AccountSid"),
+		MessagingServiceSID: r.PostForm.Get("MessagingServiceSid"),
+		From:                r.PostForm.Get("From"),
+		To:                  r.PostForm.Get("To"),
+		Body:                r.PostForm.Get("Body"),
+		Params:              r.PostForm,
+	}
+	if msg.From == "" {
+		http.Error(w, "From is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	log := h.service.logger.With("message_sid", msg.MessageSid, "from", msg.From, "to", msg.To)
+	scope := msg.MessagingServiceSID
+	if scope == "" {
+		scope = msg.To
+	}
+
+	var reply string
+	switch kind := h.service.keywords.match(msg.Body); kind {
+	case keywordOptOut, keywordOptIn:
+		record := &ConsentRecord{
+			Scope:      scope,
+			Phone:      msg.From,
+			AccountSid: msg.AccountSid,
+			OptedOut:   kind == keywordOptOut,
+			Keyword:    strings.TrimSpace(msg.Body),
+			UpdatedAt:  time.Now().UTC(),
+		}
+		if err := h.consents.SaveConsent(ctx, record); err != nil {
+			log.Error("failed to record consent", "keyword", record.Keyword, "error", err)
+			http.Error(w, "failed to record consent", http.StatusInternalServerError)
+			return
+		}
+		log.Info("consent recorded", "keyword", record.Keyword, "opted_out", record.OptedOut)
+		reply = h.service.keywords.OptInMessage
+		if record.OptedOut {
+			reply = h.service.keywords.OptOutMessage
+		}
+	case keywordHelp:
+		reply = h.service.keywords.HelpMessage
+	default:
+		consent, err := h.consents.LoadConsent(ctx, scope, msg.From)
+		if err != nil && !errors.Is(err, ErrConsentNotFound) {
+			log.Error("failed to load consent", "error", err)
+			http.Error(w, "failed to load consent", http.StatusInternalServerError)
+			return
+		}
+		msg.OptedOut = consent != nil && consent.OptedOut
+		if h.onMessage != nil {
+			reply, err = h.onMessage(ctx, msg)
+			if err != nil {
+				log.Error("inbound message not handled", "error", err)
+				http.Error(w, "failed to handle message", http.StatusInternalServerError)
+				return
+			}
+		}
+		if msg.OptedOut && reply != "" {
+			log.Info("reply to opted-out sender suppressed")
+			reply = ""
+		}
+	}
+
+	writeTwiML(w, reply)
+}
+
+// match returns the kind of keyword body is. Opt-out wins when a keyword is
+// registered more than once.
+func (k CampaignKeywords) match(body string) keywordKind {
+	word := strings.TrimSpace(body)
+	switch {
+	case word == "":
+		return keywordNone
+	case containsFold(k.OptOutKeywords, word) || containsFold(standardOptOutKeywords, word):
+		return keywordOptOut
+	case containsFold(k.OptInKeywords, word) || containsFold(standardOptInKeywords, word):
+		return keywordOptIn
+	case containsFold(k.HelpKeywords, word) || containsFold(standardHelpKeywords, word):
+		return keywordHelp
+	}
+	return keywordNone
+}
+
+func containsFold(keywords []string, word string) bool {
+	for _, keyword := range keywords {
+		if strings.EqualFold(keyword, word) {
+			return true
+		}
+	}
+	return false
+}
+
+type twimlResponse struct {
+	XMLName  xml.Name `xml:"Response"`
+	Messages []string `xml:"Message"`
+}
+
+// writeTwiML answers the webhook with reply as a message, or with an empty
+// response when reply is empty.
+func writeTwiML(w http.ResponseWriter, reply string) {
+	response := twimlResponse{}
+	if reply != "" {
+		response.Messages = []string{reply}
+	}
+	data, err := xml.Marshal(response)
+	if err != nil {
+		http.Error(w, "failed to encode TwiML", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
@@ -0,0 +1,295 @@
+package a2p
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCampaignKeywordsMatch(t *testing.T) {
+	custom := CampaignKeywords{
+		OptInKeywords:  []string{"JOIN"},
+		OptOutKeywords: []string{"LEAVE", "JOIN"},
+		HelpKeywords:   []string{"SUPPORT"},
+	}
+
+	tests := []struct {
+		name     string
+		keywords CampaignKeywords
+		body     string
+		want     keywordKind
+	}{
+		{"stop", DefaultCampaignKeywords, "STOP", keywordOptOut},
+		{"lower case with spaces", DefaultCampaignKeywords, "  stop \n", keywordOptOut},
+		{"unsubscribe", DefaultCampaignKeywords, "Unsubscribe", keywordOptOut},
+		{"start", DefaultCampaignKeywords, "start", keywordOptIn},
+		{"yes is an appointment confirmation", DefaultCampaignKeywords, "YES", keywordNone},
+		{"help", DefaultCampaignKeywords, "help", keywordHelp},
+		{"info", DefaultCampaignKeywords, "INFO", keywordHelp},
+		{"no is not a keyword", DefaultCampaignKeywords, "NO", keywordNone},
+		{"confirm is not a keyword", DefaultCampaignKeywords, "confirm", keywordNone},
+		{"keyword inside a sentence", DefaultCampaignKeywords, "please stop", keywordNone},
+		{"empty", DefaultCampaignKeywords, "   ", keywordNone},
+		{"opt-out wins over opt-in", custom, "join", keywordOptOut},
+		{"custom help", custom, "Support", keywordHelp},
+		{"standard keywords always work", custom, "STOP", keywordOptOut},
+		{"standard opt-in always works", custom, "UNSTOP", keywordOptIn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.keywords.match(tt.body); got != tt.want {
+				t.Errorf("match(%q) = %d, want %d", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInboundMessageHandler(t *testing.T) {
+	const scope = "MG00000000000000000000000000000001"
+	errHandler := errors.New("handler failed")
+
+	type message struct {
+		body string
+		// wantReply is the TwiML message expected, "" for none.
+		wantReply  string
+		wantStatus int
+	}
+	tests := []struct {
+		name string
+		// consent is stored before the messages are sent.
+		consent     *ConsentRecord
+		onMessage   InboundMessageFunc
+		messages    []message
+		wantOptOut  bool
+		wantKeyword string
+	}{
+		{
+			name:        "opt-out",
+			messages:    []message{{body: "stop", wantReply: DefaultCampaignKeywords.OptOutMessage}},
+			wantOptOut:  true,
+			wantKeyword: "stop",
+		},
+		{
+			name: "opt-out then opt-in",
+			messages: []message{
+				{body: "STOP", wantReply: DefaultCampaignKeywords.OptOutMessage},
+				{body: "START", wantReply: DefaultCampaignKeywords.OptInMessage},
+			},
+			wantKeyword: "START",
+		},
+		{
+			name:     "help does not change consent",
+			messages: []message{{body: "HELP", wantReply: DefaultCampaignKeywords.HelpMessage}},
+		},
+		{
+			name:     "other messages go to onMessage",
+			messages: []message{{body: "What time do you open?", wantReply: "echo: What time do you open?"}},
+		},
+		{
+			name:     "appointment confirmations go to onMessage",
+			messages: []message{{body: "YES", wantReply: "echo: YES"}},
+		},
+		{
+			name:        "opted-out senders get no reply",
+			consent:     &ConsentRecord{Scope: scope, Phone: "+15125550100", OptedOut: true, Keyword: "STOP"},
+			messages:    []message{{body: "What time do you open?"}},
+			wantOptOut:  true,
+			wantKeyword: "STOP",
+		},
+		{
+			name:        "opted-out senders still get help",
+			consent:     &ConsentRecord{Scope: scope, Phone: "+15125550100", OptedOut: true, Keyword: "STOP"},
+			messages:    []message{{body: "help", wantReply: DefaultCampaignKeywords.HelpMessage}},
+			wantOptOut:  true,
+			wantKeyword: "STOP",
+		},
+		{
+			name: "onMessage failure",
+			onMessage: func(ctx context.Context, msg InboundMessage) (string, error) {
+				return "", errHandler
+			},
+			messages: []message{{body: "hello", wantStatus: http.StatusInternalServerError}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			consents := NewMemoryConsentStore()
+			if tt.consent != nil {
+				if err := consents.SaveConsent(ctx, tt.consent); err != nil {
+					t.Fatal(err)
+				}
+			}
+			onMessage := tt.onMessage
+			if onMessage == nil {
+				onMessage = func(ctx context.Context, msg InboundMessage) (string, error) {
+					return "echo: " + msg.Body, nil
+				}
+			}
+			s := NewA2PService(NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts(), WithLogger(discardLogger()))
+			handler, err := s.InboundMessageHandler(consents, onMessage)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, m := range tt.messages {
+				form := url.Values{"MessageSid": {"SM1"}, "MessagingServiceSid": {scope}, "From": {"+15125550100"}, "To": {"+15125550199"}, "Body": {m.body}}
+				r := httptest.NewRequest(http.MethodPost, "/inbound", strings.NewReader(form.Encode()))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+
+				wantStatus := m.wantStatus
+				if wantStatus == 0 {
+					wantStatus = http.StatusOK
+				}
+				if w.Code != wantStatus {
+					t.Fatalf("%q: status = %d, want %d", m.body, w.Code, wantStatus)
+				}
+				if wantStatus != http.StatusOK {
+					continue
+				}
+				var response twimlResponse
+				if err := xml.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("%q: invalid TwiML %q: %v", m.body, w.Body.String(), err)
+				}
+				var reply string
+				if len(response.Messages) > 0 {
+					reply = response.Messages[0]
+				}
+				if reply != m.wantReply || len(response.Messages) > 1 {
+					t.Errorf("%q: replies %q, want %q", m.body, response.Messages, m.wantReply)
+				}
+			}
+
+			consent, err := consents.LoadConsent(ctx, scope, "+15125550100")
+			if tt.wantKeyword == "" {
+				if !errors.Is(err, ErrConsentNotFound) {
+					t.Errorf("consent recorded: %+v, %v", consent, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if consent.OptedOut != tt.wantOptOut || consent.Keyword != tt.wantKeyword {
+				t.Errorf("consent opted out %t by %q, want %t by %q", consent.OptedOut, consent.Keyword, tt.wantOptOut, tt.wantKeyword)
+			}
+		})
+	}
+}
+
+func TestFileStoreConsent(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.LoadConsent(ctx, "+15125550199", "+15125550100"); !errors.Is(err, ErrConsentNotFound) {
+		t.Fatalf("LoadConsent before any consent: %v, want ErrConsentNotFound", err)
+	}
+	record := &ConsentRecord{Scope: "+15125550199", Phone: "+15125550100", OptedOut: true, Keyword: "STOP"}
+	if err := store.SaveConsent(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.LoadConsent(ctx, record.Scope, record.Phone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *loaded != *record {
+		t.Errorf("LoadConsent = %+v, want %+v", loaded, record)
+	}
+	// Consents are not onboarding records.
+	records, err := store.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Errorf("List returned %d records, want none", len(records))
+	}
+
+	// Without a consent store, the service's FileStore is used.
+	s := NewA2PService(NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts(), WithStore(store))
+	if _, err := s.InboundMessageHandler(nil, nil); err != nil {
+		t.Errorf("InboundMessageHandler with a FileStore: %v", err)
+	}
+	s = NewA2PService(NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts())
+	if _, err := s.InboundMessageHandler(nil, nil); !errors.Is(err, ErrConsentStoreRequired) {
+		t.Errorf("InboundMessageHandler without a store: %v, want ErrConsentStoreRequired", err)
+	}
+}
+
+func TestFileStoreConsentStaysInConsentsDir(t *testing.T) {
+	tests := []struct {
+		name, scope, phone string
+		wantErr            error
+		// wantStatus is the handler's answer to a STOP from phone, 0 for 200.
+		wantStatus int
+	}{
+		{name: "parent scope", scope: "..", phone: "location-1"},
+		{name: "current scope", scope: ".", phone: "location-1"},
+		{name: "separators", scope: "../../etc", phone: "../location-1"},
+		{name: "parent phone", scope: "..", phone: ".."},
+		{name: "empty scope", scope: "", phone: "+15125550100", wantErr: ErrConsentKey, wantStatus: http.StatusInternalServerError},
+		{name: "empty phone", scope: "MG1", phone: "", wantErr: ErrConsentKey, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			store, err := NewFileStore(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save(ctx, &OnboardingRecord{LocationID: "location-1", SubaccountID: testSubaccountSID}); err != nil {
+				t.Fatal(err)
+			}
+			s := NewA2PService(NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts(), WithLogger(discardLogger()), WithStore(store))
+			handler, err := s.InboundMessageHandler(nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			form := url.Values{"MessageSid": {"SM1"}, "MessagingServiceSid": {tt.scope}, "To": {""}, "From": {tt.phone}, "Body": {"STOP"}}
+			r := httptest.NewRequest(http.MethodPost, "/inbound", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			wantStatus := tt.wantStatus
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+			if w.Code != wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, wantStatus)
+			}
+			if err := store.SaveConsent(ctx, &ConsentRecord{Scope: tt.scope, Phone: tt.phone}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("SaveConsent error = %v, want %v", err, tt.wantErr)
+			}
+
+			record, err := store.Load(ctx, "location-1")
+			if err != nil || record.SubaccountID != testSubaccountSID {
+				t.Errorf("onboarding record overwritten: %+v, %v", record, err)
+			}
+			consents := filepath.Join(dir, "consents") + string(filepath.Separator)
+			err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+				if err != nil || entry.IsDir() || path == filepath.Join(dir, "location-1.json") {
+					return err
+				}
+				if !strings.HasPrefix(path, consents) {
+					t.Errorf("consent written outside consents/: %s", path)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
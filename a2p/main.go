@@ -1,26 +1,97 @@
 package a2p
 
 import (
-	// "crm/internal/fmt"Printlnrors"
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
-	"github.com/twilio/twilio-go"
 	messaging "github.com/twilio/twilio-go/rest/messaging/v1"
 )
 
 type A2PService struct {
-	client *twilio.RestClient
+	trustHub  TrustHubClient
+	messaging MessagingClient
+	accounts  AccountsClient
+	logger    *slog.Logger
+	retrier   *retrier
+	callbacks CallbackURLs
+	rollback  bool
+	pipeline  *Pipeline
+	// concurrency limits the steps run at once, see WithConcurrency.
+	concurrency int
+	observers   observers
+	store       Store
+	keywords    CampaignKeywords
+	// forAccount builds the same service authenticated as another
+	// (sub)account; it backs ForSubaccount.
+	forAccount func(sid, token string) *A2PService
 }
 
-func NewA2PServiceInstance(sid, token string) *A2PService {
+// NewA2PService builds an A2PService on top of the given API clients, for
+// example the in-memory fakes (NewFakeTrustHub, NewFakeMessaging,
+// NewFakeAccounts) in tests. NewA2PServiceInstance wires the real Twilio API.
+// Transport options (WithHTTPClient, WithEdge, WithRegion, WithBaseURL) have
+// no effect here.
+func NewA2PService(trustHub TrustHubClient, messaging MessagingClient, accounts AccountsClient, opts ...Option) *A2PService {
+	o := defaultServiceOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := newA2PService(trustHub, messaging, accounts, o)
+	// The given clients have no notion of accounts, so every subaccount
+	// shares them.
+	s.forAccount = func(sid, token string) *A2PService { return s }
+	return s
+}
+
+func newA2PService(trustHub TrustHubClient, messaging MessagingClient, accounts AccountsClient, o serviceOptions) *A2PService {
+	r := newRetrier(o.retry, o.logger)
 	return &A2PService{
-		client: twilio.NewRestClientWithParams(twilio.ClientParams{
-			Username: sid,
-			Password: token,
-		}),
+		trustHub:    retryTrustHub{next: trustHub, r: r},
+		messaging:   retryMessaging{next: messaging, r: r},
+		accounts:    retryAccounts{next: accounts, r: r},
+		logger:      o.logger,
+		retrier:     r,
+		callbacks:   o.callbacks,
+		rollback:    o.rollback,
+		pipeline:    o.pipeline,
+		concurrency: o.concurrency,
+		observers:   o.observers,
+		store:       o.store,
+		keywords:    o.keywords,
+	}
+}
+
+// NewA2PServiceInstance builds an A2PService that talks to Twilio with the
+// given account SID and auth token. When either is empty the credentials are
+// read from the environment, see EnvCredentials.
+func NewA2PServiceInstance(sid, token string, opts ...Option) *A2PService {
+	if sid == "" || token == "" {
+		return NewA2PServiceWithCredentials(EnvCredentials{}, opts...)
+	}
+	return NewA2PServiceWithCredentials(StaticCredentials(AuthTokenCredentials(sid, token)), opts...)
+}
+
+// NewA2PServiceWithCredentials builds an A2PService that asks creds for the
+// credentials of every request, so rotated credentials take effect without
+// rebuilding the service.
+func NewA2PServiceWithCredentials(creds CredentialsProvider, opts ...Option) *A2PService {
+	o := defaultServiceOptions()
+	for _, opt := range opts {
+		opt(&o)
 	}
+	return newRESTService(creds, o)
+}
+
+func newRESTService(creds CredentialsProvider, o serviceOptions) *A2PService {
+	backend := newRESTBackend(newTwilioRestClient(Credentials{}, o), creds)
+	s := newA2PService(restTrustHub{backend}, restMessaging{backend}, restAccounts{backend}, o)
+	s.forAccount = func(sid, token string) *A2PService {
+		return newRESTService(StaticCredentials(AuthTokenCredentials(sid, token)), o)
+	}
+	return s
 }
 
 var (
@@ -30,235 +101,94 @@ var (
 	ErrGetTwilioUsername              = errors.New("get the Twilio root username first before proceeding")
 	ErrGetTwilioPassword              = errors.New("get the Twilio root password first before proceeding")
 	ErrBrandRegistrationCheckTimedOut = errors.New("checking brand registration timed out after 48 hours")
+	ErrSubaccountCredentials          = errors.New("a subaccount SID (AC...) and auth token are required")
 )
 
-func (s *A2PService) OnboardCustomer(params *FullA2POnboardingParams) (FullA2POnboardingResponse, error) {
-
-	if params.SubaccountID == "" {
-		return FullA2POnboardingResponse{}, ErrCreateSubaccount
-	}
-
-	if params.TwilioPurchasedPhoneNumber == "" {
-		return FullA2POnboardingResponse{}, ErrPurchasePhoneNumber
-	}
-
-	if params.TwilioPurchasedPhoneNumberSID == "" {
-		return FullA2POnboardingResponse{}, ErrGetPhoneNumberSID
-	}
-
-	if params.TwilioUsername == "" {
-		return FullA2POnboardingResponse{}, ErrGetTwilioUsername
-	}
-
-	if params.TwilioPassword == "" {
-		return FullA2POnboardingResponse{}, ErrGetTwilioPassword
-	}
-
-	fmt.Println("Starting onboarding process for", params.FriendlyName)
-
-	// Stage 2.1: Create a secondary customer profile
-	customerProfileSid, err := s.CreateSecondaryCustomerProfile(CustomerProfileData{
-		FriendlyName:   params.FriendlyName,
-		Email:          params.Email,
-		PolicySid:      "RNdfbf3fae0e1107f8aded0e7cead80bf5",
-		StatusCallback: "www.demo.com/callback/status",
-	})
-
-	if err != nil {
-		fmt.Println("CreateSecondaryCustomerProfile", "error at stage 2.1", err)
-		return FullA2POnboardingResponse{}, err
-	}
-
-	// Stage 2.2: Create an EndUser Business Information resource
-	endUserBusinessInfoSID, err := s.CreateEndUserBusinessInfo(BusinessInfoData{
-		BusinessName:               params.BusinessName,
-		SocialMediaProfileUrls:     params.SocialMediaProfileURLs,
-		WebsiteUrl:                 params.WebsiteURL,
-		BusinessRegionsOfOperation: params.RegionOfOperation,
-		BusinessType:               params.BusinessType,
-		BusinessRegistrationId:     params.BusinessRegistrationId,
-		BusinessIdentity:           params.BusinessIdentity,
-		BusinessIndustry:           params.BusinessIndustry,
-		BusinessRegistrationNumber: params.BusinessRegistrationNumber,
-	})
-	if err != nil {
-		fmt.Println("CreateEndUserBusinessInfo", "error at stage 2.2", err)
-		return FullA2POnboardingResponse{}, err
-	}
-
-	// Stage 2.3: Attach EndUser to the Secondary Customer Profile
-	// attachEndUserToProfileSID
-	_, err = s.AttachEndUserToProfile(EndUserAssignmentData{
-		CustomerProfileSid: customerProfileSid,
-		EndUserSid:         endUserBusinessInfoSID,
-	})
-	if err != nil {
-		return FullA2POnboardingResponse{}, fmt.Errorf("error at stage 2.3: %w", err)
-	}
-
-	// Stage 2.4. Create an EndUser resource of type: authorized_representative_1
-	endUserAuthorizedRep1SID, err := s.CreateEndUserAuthorizedRep1(EndUserAuthorizedRep1BusinessInfoData{
-		Type:          "authorized_representative_1",
-		FirstName:     params.EndUserRepOneFirstName,
-		LastName:      params.EndUserRepOneLastName,
-		Email:         params.EndUserRepOneEmail,
-		PhoneNumber:   params.EndUserRepOneEmail,
-		Position:      params.EndUserRepOnePosition,
-		BusinessTitle: params.EndUserRepOneBusinessTitle,
-		FriendlyName:  fmt.Sprintf("%s - Authorized Representative 1", params.CustomerName),
-	})
-	if err != nil {
-		fmt.Println("CreateEndUserAuthorizedRep1", "error at stage 2.4", err)
-		return FullA2POnboardingResponse{}, err
-	}
-
-	// Stage 2.5: Attach EndUser to the Secondary Customer Profile
-	// attachEndUserToProfileSID
-	_, err = s.AttachEndUserAuthorizedRep1ToProfile(EndUserAssignmentData{
-		CustomerProfileSid: customerProfileSid,
-		EndUserSid:         endUserAuthorizedRep1SID,
-	})
-	if err != nil {
-		fmt.Println("AttachEndUserAuthorizedRep1ToProfile", "error at stage 2.5", err)
-		return FullA2POnboardingResponse{}, err
-	}
-
-	// Stage 2.6 Create An Address Resource and returns address sid
-	addressSID, err := s.CreateAddressResource(AddressData{
-		PathAccountSid: params.TwilioUsername,
-		CustomerName:   params.CustomerName,
-		Street:         params.Street,
-		City:           params.City,
-		Region:         params.Region,
-		PostalCode:     params.PostalCode,
-		IsoCountry:     params.IsoCountry,
-		FriendlyName:   fmt.Sprintf("%s - Address Resource", params.CustomerName),
-	})
-	if err != nil {
-		fmt.Println("CreateAddressResource", "error at stage 2.6", err)
-		return FullA2POnboardingResponse{}, err
-	}
-
-	// Stage 2.7 Create a supporting document resource and returns supporting_document_sid
-	supportingDocumentSID, err := s.CreateSupportingDocumentResource(SupportingDocumentData{
-		FriendlyName: fmt.Sprintf("%s - Business License Document", params.CustomerName),
-		AddressSid:   addressSID,
-	})
-	if err != nil {
-		fmt.Println("CreateSupportingDocument", "error at stage 2.7", err)
-		return FullA2POnboardingResponse{}, err
-	}
-
-	// Stage 2.8 Attach the supporting document to the Secondary Customer Profile
-	//attachSupportingDocumentToProfileSID
-	_, err = s.AttachSupportingDocumentToProfile(customerProfileSid, &supportingDocumentSID)
-	if err != nil {
-		fmt.Println("AttachSupportingDocumentToProfile", "error at stage 2.8", err)
-		return FullA2POnboardingResponse{}, err
-	}
+// OnboardCustomer runs the onboarding pipeline, by default stages 2.1 to 5.1
+// (see DefaultPipeline and WithPipeline), and submits the brand registration.
+// Every resource is created inside the subaccount given by TwilioUsername and
+// TwilioPassword. Stage failures are returned as *StageError; its Checkpoint
+// can be passed to ResumeOnboarding.
+func (s *A2PService) OnboardCustomer(ctx context.Context, params *FullA2POnboardingParams) (FullA2POnboardingResponse, error) {
+	return s.ResumeOnboarding(ctx, params, nil)
+}
 
-	// Stage 2.9. Evaluate the Secondary Customer Profile
-	//evaluateSecondaryCustomerProfileSID
-	_, err = s.EvaluateSecondaryCustomerProfile(customerProfileSid)
-	if err != nil {
-		fmt.Println("EvaluateSecondaryCustomerProfile", "error at stage 2.9", err)
+// ResumeOnboarding continues an onboarding from checkpoint, skipping the
+// stages it records as completed and reusing their SIDs. A nil checkpoint
+// starts from the one saved in the store (see WithStore), or from stage 2.1,
+// like OnboardCustomer.
+func (s *A2PService) ResumeOnboarding(ctx context.Context, params *FullA2POnboardingParams, checkpoint *OnboardingCheckpoint) (FullA2POnboardingResponse, error) {
+	if err := validateOnboardingParams(params); err != nil {
 		return FullA2POnboardingResponse{}, err
 	}
 
-	// Stage 2.10. Submit the Secondary Customer Profile for review  - status must be set to pending-review
-	// submitSecondaryCustomerProfileForReviewSID
-	_, err = s.SubmitSecondaryCustomerProfileForReview(customerProfileSid)
-	if err != nil {
-		fmt.Println("SubmitSecondaryCustomerProfileForReview", "error at stage 2.10", err)
-		return FullA2POnboardingResponse{}, err
+	if checkpoint == nil {
+		stored, err := s.storedCheckpoint(ctx, params)
+		if err != nil {
+			return FullA2POnboardingResponse{}, fmt.Errorf("failed to load onboarding record: %w", err)
+		}
+		checkpoint = stored
 	}
-
-	// Stage 3.1: Create a TrustProduct Resource
-	trustProductSID, err := s.CreateTrustProduct(TrustProductData{
-		FriendlyName:   params.FriendlyName,
-		PolicySid:      "RNdfbf3fae0e1107f8aded0e7cead80bf5",
-		Email:          params.Email,
-		StatusCallback: "www.demo.com/callback/status",
-	})
-	if err != nil {
-		fmt.Println("CreateTrustProduct", "error at stage 3.1", err)
-		return FullA2POnboardingResponse{}, err
+	if checkpoint == nil {
+		checkpoint = NewOnboardingCheckpoint(params)
+	} else if checkpoint.LocationID != params.LocationID || checkpoint.SubaccountID != params.SubaccountID {
+		return FullA2POnboardingResponse{}, ErrCheckpointMismatch
 	}
 
-	// Stage 3.2: Create an EndUser Resource of Type us_a2p_messaging_profile_information
-	endUserMessagingProfileSID, err := s.CreateEndUserMessagingProfile(EndUserMessagingProfileData{
-		CompanyType:   params.BusinessType,
-		StockExchange: "",
-		StockTicker:   "",
-	})
+	sub, err := s.ForSubaccount(params.TwilioUsername, params.TwilioPassword)
 	if err != nil {
-		fmt.Println("CreateEndUserMessagingProfile", "error at stage 3.2", err)
 		return FullA2POnboardingResponse{}, err
 	}
+	return sub.onboardCustomer(ctx, params, checkpoint.clone())
+}
 
-	// Stage 3.3: Attach the EndUser to the TrustProduct
-	//attachEndUserToTrustProductSID
-	_, err = s.AttachEndUserToTrustProduct(trustProductSID, endUserMessagingProfileSID)
-	if err != nil {
-		fmt.Println("AttachEndUserToTrustProduct", "error at stage 3.3", err)
-		return FullA2POnboardingResponse{}, err
+func validateOnboardingParams(params *FullA2POnboardingParams) error {
+	if params.SubaccountID == "" {
+		return ErrCreateSubaccount
 	}
 
-	// Stage 3.4: Attach the Secondary Customer Profile to the TrustProduct
-	//attachSecondaryCustomerProfileToTrustProductSID
-	_, err = s.AttachSecondaryCustomerProfileToTrustProduct(trustProductSID, customerProfileSid)
-	if err != nil {
-		fmt.Println("AttachSecondaryCustomerProfileToTrustProduct", "error at stage 3.4", err)
-		return FullA2POnboardingResponse{}, err
+	if params.TwilioPurchasedPhoneNumber == "" {
+		return ErrPurchasePhoneNumber
 	}
 
-	// Stage 3.5: Evaluate the TrustProduct
-	// evaluateTrustProductSID
-	_, err = s.EvaluateTrustProduct(trustProductSID, "RNdfbf3fae0e1107f8aded0e7cead80bf5")
-	if err != nil {
-		fmt.Println("EvaluateTrustProduct", "error at stage 3.5", err)
-		return FullA2POnboardingResponse{}, err
+	if params.TwilioPurchasedPhoneNumberSID == "" {
+		return ErrGetPhoneNumberSID
 	}
 
-	// Stage 3.6: Submit the TrustProduct for Review  - status must be set to pending-review
-	// submitTrustProductForReviewSID
-	_, err = s.SubmitTrustProductForReview(trustProductSID)
-	if err != nil {
-		fmt.Println("SubmitTrustProductForReview", "error at stage 3.6", err)
-		return FullA2POnboardingResponse{}, err
+	if params.TwilioUsername == "" {
+		return ErrGetTwilioUsername
 	}
 
-	// Stage 4.1: Create a BrandRegistration
-	brandRegistrationSID, brandRegistrationStatus, err := s.CreateBrandRegistration(BrandRegistrationData{
-		CustomerProfileBundleSid: customerProfileSid,
-		A2PProfileBundleSid:      trustProductSID,
-	})
-
-	if err != nil {
-		fmt.Println("CreateBrandRegistration", "error at stage 4.1", err)
-		return FullA2POnboardingResponse{}, err
+	if params.TwilioPassword == "" {
+		return ErrGetTwilioPassword
 	}
+	return nil
+}
 
-	// Stage 5.1: Create a MessagingService Resource - This will return MessageServiceSID
-	messagingServiceSID, err := s.CreateMessagingService(MessagingServiceData{
-		FriendlyName:      params.FriendlyName,
-		InboundRequestUrl: "https://www.example.com/inbound-messages-webhook",
-		FallbackUrl:       "https://www.example.com/fallback",
-	})
-
-	if err != nil {
-		fmt.Println("CreateMessagingService", "error at stage 5.1", err)
+func (s *A2PService) onboardCustomer(ctx context.Context, params *FullA2POnboardingParams, checkpoint *OnboardingCheckpoint) (FullA2POnboardingResponse, error) {
+	log := s.onboardingLogger(params)
+	log.Info("starting onboarding", "friendly_name", params.FriendlyName, "completed_stages", len(checkpoint.Stages))
+	state := &PipelineState{Service: s, Params: params, checkpoint: checkpoint, onboarding: StateDraft}
+	run := newOnboardingRun(log, state)
+	if err := run.execute(ctx, s.pipeline.Steps(), s.concurrency); err != nil {
 		return FullA2POnboardingResponse{}, err
 	}
-
-	fmt.Println("|---------------*********************************************************----------------|")
-	fmt.Println("Brand Registration SID:", brandRegistrationSID)
-	fmt.Println("Brand Registration Current Status:", brandRegistrationStatus)
-	fmt.Println("|----------------********************************************************----------------|")
+	state.mu.Lock()
+	onboardingState := state.onboarding
+	state.mu.Unlock()
+	brandRegistrationSID := state.SID("4.1")
+	brandRegistrationStatus := checkpoint.BrandRegistrationStatus
+	messagingServiceSID := state.SID("5.1")
+
+	log.Info("onboarding submitted",
+		"brand_registration_sid", brandRegistrationSID,
+		"brand_registration_status", brandRegistrationStatus,
+		"messaging_service_sid", messagingServiceSID,
+	)
 
 	params.MessagingServiceSID = messagingServiceSID
 
-	return FullA2POnboardingResponse{
+	response := FullA2POnboardingResponse{
 		Message: "Brand Registration Created Successfully",
 		Data: &A2POnboardingResponse{
 			LocationID:                   params.LocationID,
@@ -274,66 +204,146 @@ func (s *A2PService) OnboardCustomer(params *FullA2POnboardingParams) (FullA2POn
 			AppliedForBrandRegistration:  true,
 			AppliedForMessagingService:   false,
 			AppliedForA2pMessageCampaign: false,
+			State:                        onboardingState,
 		},
-	}, nil
+	}
+	s.updateRecord(ctx, log, params, func(record *OnboardingRecord) {
+		stored := record.response(params)
+		// A resumed run of a finished onboarding must not move it back.
+		if stored.State.brandDecided() {
+			return
+		}
+		data := *response.Data
+		data.State = stored.State
+		data.Created_At = stored.Created_At
+		data.advanceTo(log, onboardingState)
+		record.Response = &data
+	})
+	return response, nil
+}
 
+// CompleteOnboarding runs stages 6.0 to 7.1 once the brand is approved, in
+// the subaccount given by params. Stage failures are returned as *StageError.
+// With WithStore, an empty brandRegistrationSID or params.MessagingServiceSID
+// is taken from the customer's record.
+func (s *A2PService) CompleteOnboarding(ctx context.Context, params *FullA2POnboardingParams, brandRegistrationSID string) (FullA2POnboardingResponse, error) {
+	sub, err := s.subaccountFor(params)
+	if err != nil {
+		return FullA2POnboardingResponse{}, err
+	}
+	if s.store != nil && (brandRegistrationSID == "" || params.MessagingServiceSID == "") {
+		record, err := s.store.Load(ctx, params.LocationID)
+		if err != nil {
+			return FullA2POnboardingResponse{}, fmt.Errorf("failed to load onboarding record: %w", err)
+		}
+		if brandRegistrationSID == "" {
+			brandRegistrationSID = record.sid("4.1", func(r *A2POnboardingResponse) string { return r.BrandRegistrationSID })
+		}
+		if params.MessagingServiceSID == "" {
+			params.MessagingServiceSID = record.sid("5.1", func(r *A2POnboardingResponse) string { return r.MessagingServiceSID })
+		}
+	}
+	return sub.completeOnboarding(ctx, params, brandRegistrationSID)
 }
 
-func (s *A2PService) CompleteOnboarding(params *FullA2POnboardingParams, brandRegistrationSID string) (FullA2POnboardingResponse, error) {
+func (s *A2PService) completeOnboarding(ctx context.Context, params *FullA2POnboardingParams, brandRegistrationSID string) (FullA2POnboardingResponse, error) {
+	log := s.onboardingLogger(params).With("brand_registration_sid", brandRegistrationSID)
+	created := map[string]string{
+		"brand_registration_sid": brandRegistrationSID,
+		"messaging_service_sid":  params.MessagingServiceSID,
+	}
 
-	twilioPhoneSID, err := s.GetPhoneNumberSID(params.TwilioPurchasedPhoneNumber)
+	twilioPhoneSID, err := s.GetPhoneNumberSID(ctx, params.TwilioPurchasedPhoneNumber)
 	if err != nil {
-		fmt.Println("GetPhoneNumberSID", "error at stage 6.0", err)
-		return FullA2POnboardingResponse{}, err
+		return FullA2POnboardingResponse{}, stageFailed(log, "6.0", "GetPhoneNumberSID", err, created)
 	}
+	logStage(log, "6.0", "GetPhoneNumberSID", nil, "phone_number_sid", twilioPhoneSID)
 
 	// Stage 6.1: Add a Phone Number to the Messaging Service , once you have a phone number, you can associate it with the messaging service
-	_, err = s.AddPhoneNumberToMessagingService(params.MessagingServiceSID, &messaging.CreatePhoneNumberParams{
+	_, err = s.AddPhoneNumberToMessagingService(ctx, params.MessagingServiceSID, &messaging.CreatePhoneNumberParams{
 		PhoneNumberSid: &twilioPhoneSID,
 	})
 
 	if err != nil {
-		fmt.Println("AddPhoneNumberToMessagingService", "error at stage 7.1", err)
-		return FullA2POnboardingResponse{}, err
+		return FullA2POnboardingResponse{}, stageFailed(log, "6.1", "AddPhoneNumberToMessagingService", err, created)
 	}
+	logStage(log, "6.1", "AddPhoneNumberToMessagingService", nil, "messaging_service_sid", params.MessagingServiceSID, "phone_number_sid", twilioPhoneSID)
+	created["phone_number_sid"] = twilioPhoneSID
+	s.updateRecord(ctx, log, params, func(record *OnboardingRecord) {
+		response := record.response(params)
+		response.BrandRegistrationSID = brandRegistrationSID
+		response.BrandRegistrationStatus = "Approved"
+		response.advanceThrough(log, statesTo(StateNumberAttached)...)
+	})
 
 	// Stage 7.1: Create the A2P Campaign
-	campaignSID, err := s.CreateA2PCampaign(params.MessagingServiceSID, CampaignData{
+	campaignSID, err := s.CreateA2PCampaign(ctx, params.MessagingServiceSID, CampaignData{
 		BrandRegistrationSid: brandRegistrationSID,
 	})
 
 	if err != nil {
-		fmt.Println("CreateA2PCampaign", "error at stage 6.1", err)
-		return FullA2POnboardingResponse{}, err
+		return FullA2POnboardingResponse{}, stageFailed(log, "7.1", "CreateA2PCampaign", err, created)
 	}
+	logStage(log, "7.1", "CreateA2PCampaign", nil, "campaign_sid", campaignSID)
+	s.updateRecord(ctx, log, params, func(record *OnboardingRecord) {
+		response := record.response(params)
+		response.A2pMessageCampaignSID = campaignSID
+		response.AppliedForA2pMessageCampaign = true
+		response.advanceThrough(log, statesTo(StateCampaignPending)...)
+	})
 
+	data := &A2POnboardingResponse{
+		LocationID:              params.LocationID,
+		SubaccountID:            params.SubaccountID,
+		TwilioUsername:          params.TwilioUsername,
+		TwilioPassword:          params.TwilioPassword,
+		BrandRegistrationSID:    brandRegistrationSID,
+		MessagingServiceSID:     params.MessagingServiceSID,
+		A2pMessageCampaignSID:   campaignSID,
+		BrandRegistrationStatus: "Approved",
+	}
+	data.advanceThrough(log, statesTo(StateCampaignPending)...)
 	return FullA2POnboardingResponse{
 		Message: "Success !! Proceed To Register A2P Campaign Once Brand Registration is Approved",
-		Data: &A2POnboardingResponse{
-			LocationID:              params.LocationID,
-			SubaccountID:            params.SubaccountID,
-			TwilioUsername:          params.TwilioUsername,
-			TwilioPassword:          params.TwilioPassword,
-			BrandRegistrationSID:    brandRegistrationSID,
-			MessagingServiceSID:     params.MessagingServiceSID,
-			A2pMessageCampaignSID:   campaignSID,
-			BrandRegistrationStatus: "Approved",
-		},
+		Data:    data,
 	}, nil
 }
 
-func (s *A2PService) processRegistrationStatus(status string, params *FullA2POnboardingParams, sid string) (FullA2POnboardingResponse, error) {
-	switch status {
-	case "APPROVED":
-		return s.CompleteOnboarding(params, sid)
-	case "FAILED", "IN_REVIEW", "PENDING", "DELETED":
-		return FullA2POnboardingResponse{Message: fmt.Sprintf("Brand registration is %s", status)}, nil
-	default:
-		return FullA2POnboardingResponse{}, fmt.Errorf("unknown status: %s", status)
+func (s *A2PService) processRegistrationStatus(ctx context.Context, status string, params *FullA2POnboardingParams, sid string) (FullA2POnboardingResponse, error) {
+	state, err := brandRegistrationState(status)
+	if err != nil {
+		return FullA2POnboardingResponse{}, err
+	}
+	if state == StateBrandApproved {
+		return s.CompleteOnboarding(ctx, params, sid)
+	}
+	data := &A2POnboardingResponse{
+		LocationID:              params.LocationID,
+		SubaccountID:            params.SubaccountID,
+		BrandRegistrationSID:    sid,
+		MessagingServiceSID:     params.MessagingServiceSID,
+		BrandRegistrationStatus: status,
 	}
+	data.advanceThrough(s.logger, statesTo(state)...)
+	return FullA2POnboardingResponse{
+		Message: fmt.Sprintf("Brand registration is %s", status),
+		Data:    data,
+	}, nil
 }
 
-func (s *A2PService) MonitorBrandRegistration(brandRegistrationSID string, params *FullA2POnboardingParams) (FullA2POnboardingResponse, error) {
+// MonitorBrandRegistration polls the brand registration every hour for up to
+// 48 hours and completes the onboarding once it is approved. It blocks the
+// whole time and keeps no state.
+//
+// Deprecated: Use StatusScheduler, which tracks many brands from the Store,
+// adapts its polling and survives restarts.
+func (s *A2PService) MonitorBrandRegistration(ctx context.Context, brandRegistrationSID string, params *FullA2POnboardingParams) (FullA2POnboardingResponse, error) {
+	sub, err := s.subaccountFor(params)
+	if err != nil {
+		return FullA2POnboardingResponse{}, err
+	}
+	log := s.onboardingLogger(params).With("brand_registration_sid", brandRegistrationSID)
+
 	ticker := time.NewTicker(1 * time.Hour)
 	defer ticker.Stop()
 
@@ -341,22 +351,46 @@ func (s *A2PService) MonitorBrandRegistration(brandRegistrationSID string, param
 
 	for {
 		select {
+		case <-ctx.Done():
+			return FullA2POnboardingResponse{}, ctx.Err()
 		case <-timeout:
+			log.Warn("brand registration check timed out")
 			return FullA2POnboardingResponse{Message: "Brand registration checking timed out"}, ErrBrandRegistrationCheckTimedOut
 		case <-ticker.C:
-			status, err := s.FetchBrandRegistration(brandRegistrationSID)
+			status, err := sub.FetchBrandRegistration(ctx, brandRegistrationSID)
 			if err != nil {
-				fmt.Println("CheckBrandRegistrationStatus", "error", err)
+				log.Error("brand registration check failed", "operation", "FetchBrandRegistration", "error", err)
 				continue
 			}
+			log.Info("brand registration checked", "operation", "FetchBrandRegistration", "brand_registration_status", status)
 
 			// Process based on registration status
-			response, err := s.processRegistrationStatus(status, params, brandRegistrationSID)
+			response, err := sub.processRegistrationStatus(ctx, status, params, brandRegistrationSID)
 			if err != nil {
-				fmt.Println("ProcessRegistrationStatus", "error", err)
+				log.Error("brand registration status not handled", "operation", "processRegistrationStatus", "brand_registration_status", status, "error", err)
 				continue
 			}
 			return response, nil
 		}
 	}
 }
+
+// onboardingLogger returns the service logger tagged with the customer the
+// onboarding run belongs to. Credentials are never attached.
+func (s *A2PService) onboardingLogger(params *FullA2POnboardingParams) *slog.Logger {
+	return s.logger.With(
+		slog.String("subaccount_sid", params.SubaccountID),
+		slog.String("location_id", params.LocationID),
+	)
+}
+
+// logStage records the outcome of one onboarding stage. attrs carry the SIDs
+// the stage produced or worked on.
+func logStage(log *slog.Logger, stage, operation string, err error, attrs ...any) {
+	attrs = append([]any{slog.String("stage", stage), slog.String("operation", operation)}, attrs...)
+	if err != nil {
+		log.Error("onboarding stage failed", append(attrs, slog.Any("error", err))...)
+		return
+	}
+	log.Info("onboarding stage completed", attrs...)
+}
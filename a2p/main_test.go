@@ -0,0 +1,216 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+const (
+	testSubaccountSID = "AC00000000000000000000000000000001"
+	// testPhoneNumberSID is the SID the fake server gives the first number
+	// added to it.
+	testPhoneNumberSID = "PN00000000000000000000000000000001"
+)
+
+// testParams returns onboarding params that pass validation.
+func testParams() *FullA2POnboardingParams {
+	return &FullA2POnboardingParams{
+		LocationID:                    "location-1",
+		SubaccountID:                  testSubaccountSID,
+		TwilioUsername:                testSubaccountSID,
+		TwilioPassword:                "subaccounttoken",
+		CustomerName:                  "Acme Dental",
+		Email:                         "office@acme.test",
+		PhoneNumber:                   "+15125550100",
+		Street:                        "1 Main St",
+		City:                          "Austin",
+		Region:                        "TX",
+		PostalCode:                    "78701",
+		IsoCountry:                    "US",
+		SocialMediaProfileURLs:        "https://social.example/acme",
+		WebsiteURL:                    "https://acme.test",
+		FriendlyName:                  "Acme Dental",
+		BusinessName:                  "Acme Dental LLC",
+		BusinessIndustry:              "HEALTHCARE",
+		BusinessType:                  "Limited Liability Corporation",
+		BusinessRegistrationId:        "EIN",
+		BusinessIdentity:              "direct_customer",
+		BusinessRegistrationNumber:    "12-3456789",
+		RegionOfOperation:             "USA_AND_CANADA",
+		TwilioPurchasedPhoneNumber:    "+15125550199",
+		TwilioPurchasedPhoneNumberSID: testPhoneNumberSID,
+		AuthorizedRepresentativeName:  "Jo Doe",
+		AuthorizedRepresentativeTitle: "CEO",
+		AuthorizedRepresentativeEmail: "jo@acme.test",
+		AuthorizedRepresentativePhone: "+15125550101",
+		EndUserRepOnePosition:         "CEO",
+		EndUserRepOneFirstName:        "Jo",
+		EndUserRepOneLastName:         "Doe",
+		EndUserRepOneEmail:            "jo@acme.test",
+		EndUserRepOnePhoneNumber:      "+15125550101",
+		EndUserRepOneBusinessTitle:    "CEO",
+		UseCase:                       "MIXED",
+		AreaCode:                      "512",
+	}
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// newTestServer returns a fake Twilio server with the purchased number of
+// testParams, and a service pointed at it.
+func newTestServer(t *testing.T, opts ...Option) (*FakeTwilioServer, *A2PService) {
+	t.Helper()
+	server := NewFakeTwilioServer()
+	t.Cleanup(server.Close)
+	if sid := server.AddIncomingPhoneNumber(testSubaccountSID, testParams().TwilioPurchasedPhoneNumber); sid != testPhoneNumberSID {
+		t.Fatalf("purchased number SID = %s, want %s", sid, testPhoneNumberSID)
+	}
+	opts = append([]Option{WithLogger(discardLogger())}, opts...)
+	return server, server.Service(testSubaccountSID, "subaccounttoken", opts...)
+}
+
+func TestOnboardingEndToEnd(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, s := newTestServer(t, WithStore(store))
+
+	submitted, err := s.OnboardCustomer(ctx, testParams())
+	if err != nil {
+		t.Fatalf("OnboardCustomer: %v", err)
+	}
+	if submitted.Data.State != StateBrandPending {
+		t.Errorf("state after OnboardCustomer = %s, want %s", submitted.Data.State, StateBrandPending)
+	}
+	brandSID := submitted.Data.BrandRegistrationSID
+	if status := server.Resource(brandSID)["status"]; status != "PENDING" {
+		t.Errorf("brand registration status = %v, want PENDING", status)
+	}
+
+	if err := server.SetStatus(brandSID, "APPROVED"); err != nil {
+		t.Fatal(err)
+	}
+	// The brand and messaging service SIDs are taken from the store.
+	completed, err := s.CompleteOnboarding(ctx, testParams(), "")
+	if err != nil {
+		t.Fatalf("CompleteOnboarding: %v", err)
+	}
+	if completed.Data.State != StateCampaignPending {
+		t.Errorf("state after CompleteOnboarding = %s, want %s", completed.Data.State, StateCampaignPending)
+	}
+	if completed.Data.BrandRegistrationSID != brandSID {
+		t.Errorf("CompleteOnboarding brand = %s, want %s", completed.Data.BrandRegistrationSID, brandSID)
+	}
+	campaign := server.Resource(completed.Data.A2pMessageCampaignSID)
+	if campaign == nil {
+		t.Fatalf("campaign %s not created", completed.Data.A2pMessageCampaignSID)
+	}
+	if got := campaign["brand_registration_sid"]; got != brandSID {
+		t.Errorf("campaign brand_registration_sid = %v, want %s", got, brandSID)
+	}
+	if got := server.Count("v1/Services/" + submitted.Data.MessagingServiceSID + "/PhoneNumbers"); got != 1 {
+		t.Errorf("phone numbers on the messaging service = %d, want 1", got)
+	}
+
+	record, err := store.Load(ctx, "location-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Response.State != StateCampaignPending {
+		t.Errorf("stored state = %s, want %s", record.Response.State, StateCampaignPending)
+	}
+	if record.Response.TwilioPassword != "" {
+		t.Error("stored record keeps the subaccount password")
+	}
+}
+
+func TestOnboardCustomerRerunReusesResources(t *testing.T) {
+	ctx := context.Background()
+	collections := []string{"v1/CustomerProfiles", "v1/EndUsers", "v1/SupportingDocuments", "v1/TrustProducts", "v1/a2p/BrandRegistrations", "v1/Services"}
+
+	tests := []struct {
+		name string
+		// store keeps the checkpoint of the first run, so the rerun skips
+		// the completed stages instead of looking the resources up.
+		store bool
+	}{
+		{name: "lookup"},
+		{name: "checkpoint", store: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.store {
+				store, err := NewFileStore(t.TempDir())
+				if err != nil {
+					t.Fatal(err)
+				}
+				opts = append(opts, WithStore(store))
+			}
+			server, s := newTestServer(t, opts...)
+
+			first, err := s.OnboardCustomer(ctx, testParams())
+			if err != nil {
+				t.Fatalf("first OnboardCustomer: %v", err)
+			}
+			counts := map[string]int{}
+			for _, collection := range collections {
+				counts[collection] = server.Count(collection)
+			}
+
+			second, err := s.OnboardCustomer(ctx, testParams())
+			if err != nil {
+				t.Fatalf("second OnboardCustomer: %v", err)
+			}
+			for _, collection := range collections {
+				if got := server.Count(collection); got != counts[collection] {
+					t.Errorf("%s: %d resources after the rerun, want %d", collection, got, counts[collection])
+				}
+			}
+			if second.Data.BrandRegistrationSID != first.Data.BrandRegistrationSID {
+				t.Errorf("rerun brand = %s, want %s", second.Data.BrandRegistrationSID, first.Data.BrandRegistrationSID)
+			}
+			if second.Data.MessagingServiceSID != first.Data.MessagingServiceSID {
+				t.Errorf("rerun messaging service = %s, want %s", second.Data.MessagingServiceSID, first.Data.MessagingServiceSID)
+			}
+		})
+	}
+}
+
+func TestWithBaseURL(t *testing.T) {
+	tests := []struct {
+		rawURL  string
+		wantErr bool
+	}{
+		{rawURL: "http://127.0.0.1:8080"},
+		{rawURL: "https://twilio.example.com/proxy/"},
+		{rawURL: "localhost:8080", wantErr: true},
+		{rawURL: "example.com", wantErr: true},
+		{rawURL: "ftp://example.com", wantErr: true},
+		{rawURL: "http://", wantErr: true},
+		{rawURL: "http://[::1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rawURL, func(t *testing.T) {
+			var o serviceOptions
+			WithBaseURL(tt.rawURL)(&o)
+			if (o.baseURLErr != nil) != tt.wantErr {
+				t.Fatalf("WithBaseURL(%q) error = %v, want error %t", tt.rawURL, o.baseURLErr, tt.wantErr)
+			}
+			if !tt.wantErr {
+				return
+			}
+			s := NewA2PServiceInstance(testSubaccountSID, "subaccounttoken", WithBaseURL(tt.rawURL), WithLogger(discardLogger()))
+			if _, err := s.ListA2PCampaigns(context.Background(), "MG1"); !errors.Is(err, ErrInvalidBaseURL) {
+				t.Errorf("request error = %v, want ErrInvalidBaseURL", err)
+			}
+		})
+	}
+}
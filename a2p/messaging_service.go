@@ -2,20 +2,22 @@
 package a2p
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	_ "github.com/twilio/twilio-go"
 	messaging "github.com/twilio/twilio-go/rest/messaging/v1"
 )
 
 // Step 5.1: Create a MessagingService Resource - This will return MessageServiceSID
-func (s *A2PService) CreateMessagingService(data MessagingServiceData) (string, error) {
+func (s *A2PService) CreateMessagingService(ctx context.Context, data MessagingServiceData) (string, error) {
 	params := &messaging.CreateServiceParams{}
-	params.SetFriendlyName(data.FriendlyName)
+	params.SetFriendlyName(correlatedName(data.FriendlyName, data.CorrelationKey))
 	params.SetInboundRequestUrl(data.InboundRequestUrl)
 	params.SetFallbackUrl(data.FallbackUrl)
 
-	resp, err := s.client.MessagingV1.CreateService(params)
+	resp, err := s.reuseOrCreateService(ctx, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to create MessagingService: %w", err)
 	}
@@ -23,9 +25,9 @@ func (s *A2PService) CreateMessagingService(data MessagingServiceData) (string,
 }
 
 // Step 5.2: Additional Configuration (Optional)
-func (s *A2PService) CreateMessagingServiceWithConfig(data MessagingServiceAdditional) (string, error) {
+func (s *A2PService) CreateMessagingServiceWithConfig(ctx context.Context, data MessagingServiceAdditional) (string, error) {
 	params := &messaging.CreateServiceParams{}
-	params.SetFriendlyName(data.FriendlyName)
+	params.SetFriendlyName(correlatedName(data.FriendlyName, data.CorrelationKey))
 	params.SetInboundRequestUrl(data.InboundRequestUrl)
 	params.SetFallbackUrl(data.FallbackUrl)
 	if data.StatusCallback != "" {
@@ -41,10 +43,20 @@ func (s *A2PService) CreateMessagingServiceWithConfig(data MessagingServiceAddit
 	params.SetSynchronousValidation(data.SynchronousValidation)
 	params.SetUsecase(data.Usecase)
 
-	resp, err := s.client.MessagingV1.CreateService(params)
+	resp, err := s.reuseOrCreateService(ctx, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to create MessagingService with config: %w", err)
 	}
 
 	return *resp.Sid, nil
 }
+
+// reuseOrCreateService creates a messaging service unless one with the same
+// FriendlyName exists. An existing service keeps its configuration.
+func (s *A2PService) reuseOrCreateService(ctx context.Context, params *messaging.CreateServiceParams) (*messaging.MessagingV1Service, error) {
+	return reuseOrCreate(ctx, s, "messaging service", func(ctx context.Context) (*messaging.MessagingV1Service, error) {
+		return findService(ctx, s.messaging, params, time.Time{})
+	}, func(ctx context.Context) (*messaging.MessagingV1Service, error) {
+		return s.messaging.CreateService(ctx, params)
+	})
+}
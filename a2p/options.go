@@ -0,0 +1,265 @@
+package a2p
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrInvalidBaseURL is returned by every request of a service given a
+// WithBaseURL that is not an absolute http or https URL.
+var ErrInvalidBaseURL = errors.New("base URL must be an absolute http or https URL")
+
+// Option configures an A2PService at construction time.
+type Option func(*serviceOptions)
+
+type serviceOptions struct {
+	httpClient  *http.Client
+	edge        string
+	region      string
+	baseURL     *url.URL
+	baseURLErr  error
+	logger      *slog.Logger
+	retry       RetryPolicy
+	callbacks   CallbackURLs
+	rollback    bool
+	pipeline    *Pipeline
+	concurrency int
+	observers   observers
+	store       Store
+	keywords    CampaignKeywords
+}
+
+// RetryPolicy controls how transient Twilio failures are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries per call, including the
+	// first. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry; it doubles on every
+	// further attempt up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy is used when WithRetryPolicy is not given.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+}
+
+// CallbackURLs are the webhook URLs given to the resources created during
+// onboarding.
+type CallbackURLs struct {
+	// StatusCallback receives TrustHub status changes for the customer
+	// profile and the trust product; see StatusCallbackHandler.
+	StatusCallback string
+	// InboundRequestURL and FallbackURL are set on the messaging service;
+	// see InboundMessageHandler.
+	InboundRequestURL string
+	FallbackURL       string
+}
+
+// DefaultCallbackURLs are placeholders; production deployments should pass
+// their own with WithCallbackURLs.
+var DefaultCallbackURLs = CallbackURLs{
+	StatusCallback:    "www.demo.com/callback/status",
+	InboundRequestURL: "https://www.example.com/inbound-messages-webhook",
+	FallbackURL:       "https://www.example.com/fallback",
+}
+
+// CampaignKeywords are the keywords and replies registered with the A2P
+// campaign, and answered by InboundMessageHandler.
+type CampaignKeywords struct {
+	OptInKeywords  []string
+	OptInMessage   string
+	OptOutKeywords []string
+	OptOutMessage  string
+	HelpKeywords   []string
+	HelpMessage    string
+}
+
+// DefaultCampaignKeywords are used when WithCampaignKeywords is not given.
+// CreateA2PCampaign registers them with the campaign, so changing them changes
+// the content submitted for review. The messages name the business with the
+// placeholder [business_name]; production deployments should pass their own
+// with WithCampaignKeywords. YES is not an opt-in keyword: the campaign's
+// message samples ask recipients to reply YES to confirm an appointment, and
+// those replies go to the InboundMessageFunc.
+var DefaultCampaignKeywords = CampaignKeywords{
+	OptInKeywords:  []string{"START", "UNSTOP"},
+	OptInMessage:   "[business_name]: You are now subscribed to our messages. Msg frequency varies. Msg & data rates may apply. Reply HELP for help, STOP to opt out.",
+	OptOutKeywords: []string{"STOP", "STOPALL", "UNSUBSCRIBE", "CANCEL", "END", "QUIT"},
+	OptOutMessage:  "[business_name]: You have been unsubscribed and will not receive any more messages. Reply START to subscribe again.",
+	HelpKeywords:   []string{"HELP", "INFO"},
+	HelpMessage:    "[business_name]: For help, reply to this message or contact us at [phone_number]. Msg & data rates may apply. Reply STOP to opt out.",
+}
+
+// DefaultConcurrency is the number of independent onboarding steps run at
+// once when WithConcurrency is not given.
+const DefaultConcurrency = 4
+
+func defaultServiceOptions() serviceOptions {
+	return serviceOptions{
+		logger:      slog.Default(),
+		retry:       DefaultRetryPolicy,
+		callbacks:   DefaultCallbackURLs,
+		pipeline:    DefaultPipeline(),
+		concurrency: DefaultConcurrency,
+		keywords:    DefaultCampaignKeywords,
+	}
+}
+
+// WithHTTPClient sends Twilio requests through c, for example to set
+// timeouts or a proxy. It only applies to NewA2PServiceInstance and
+// NewA2PServiceWithCredentials.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *serviceOptions) {
+		o.httpClient = c
+	}
+}
+
+// WithEdge routes requests through a Twilio edge location such as "ashburn".
+func WithEdge(edge string) Option {
+	return func(o *serviceOptions) {
+		o.edge = edge
+	}
+}
+
+// WithRegion targets a Twilio region such as "us1" or "ie1".
+func WithRegion(region string) Option {
+	return func(o *serviceOptions) {
+		o.region = region
+	}
+}
+
+// WithBaseURL sends every request to rawURL instead of *.twilio.com, keeping
+// the request path. It is meant for tests against FakeTwilioServer.
+func WithBaseURL(rawURL string) Option {
+	return func(o *serviceOptions) {
+		o.baseURL, o.baseURLErr = parseBaseURL(rawURL)
+	}
+}
+
+// parseBaseURL parses rawURL, rejecting URLs without an http or https scheme
+// or a host, such as "localhost:8080" or "example.com".
+func parseBaseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBaseURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidBaseURL, rawURL)
+	}
+	return u, nil
+}
+
+// WithLogger sets the logger used for onboarding progress and errors.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *serviceOptions) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+// WithRetryPolicy overrides DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *serviceOptions) {
+		o.retry = policy
+	}
+}
+
+// WithCallbackURLs overrides DefaultCallbackURLs. Empty fields keep their
+// defaults.
+func WithCallbackURLs(callbacks CallbackURLs) Option {
+	return func(o *serviceOptions) {
+		if callbacks.StatusCallback != "" {
+			o.callbacks.StatusCallback = callbacks.StatusCallback
+		}
+		if callbacks.InboundRequestURL != "" {
+			o.callbacks.InboundRequestURL = callbacks.InboundRequestURL
+		}
+		if callbacks.FallbackURL != "" {
+			o.callbacks.FallbackURL = callbacks.FallbackURL
+		}
+	}
+}
+
+// WithRollback makes OnboardCustomer and ResumeOnboarding undo a failed run:
+// the steps it completed are compensated in reverse order, detaching and
+// deleting their resources, and the outcome is reported in
+// StageError.Rollback.
+func WithRollback() Option {
+	return func(o *serviceOptions) {
+		o.rollback = true
+	}
+}
+
+// WithPipeline replaces DefaultPipeline as the steps OnboardCustomer runs.
+// The pipeline must not be modified while the service is in use.
+func WithPipeline(p *Pipeline) Option {
+	return func(o *serviceOptions) {
+		if p != nil {
+			o.pipeline = p
+		}
+	}
+}
+
+// WithConcurrency limits how many independent pipeline steps OnboardCustomer
+// runs at once. 1 runs the steps one after another in pipeline order.
+func WithConcurrency(n int) Option {
+	return func(o *serviceOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithProgressObserver registers an observer of the stages OnboardCustomer
+// and ResumeOnboarding run. It may be given more than once.
+func WithProgressObserver(observer ProgressObserver) Option {
+	return func(o *serviceOptions) {
+		if observer != nil {
+			o.observers = append(o.observers, observer)
+		}
+	}
+}
+
+// WithStore writes every onboarding stage through to store. OnboardCustomer
+// then continues from the stored checkpoint of the customer, and
+// CompleteOnboarding fills in the brand registration and messaging service
+// SIDs it is not given.
+func WithStore(store Store) Option {
+	return func(o *serviceOptions) {
+		o.store = store
+	}
+}
+
+// WithCampaignKeywords overrides DefaultCampaignKeywords. Empty fields keep
+// their defaults.
+func WithCampaignKeywords(keywords CampaignKeywords) Option {
+	return func(o *serviceOptions) {
+		if len(keywords.OptInKeywords) > 0 {
+			o.keywords.OptInKeywords = keywords.OptInKeywords
+		}
+		if keywords.OptInMessage != "" {
+			o.keywords.OptInMessage = keywords.OptInMessage
+		}
+		if len(keywords.OptOutKeywords) > 0 {
+			o.keywords.OptOutKeywords = keywords.OptOutKeywords
+		}
+		if keywords.OptOutMessage != "" {
+			o.keywords.OptOutMessage = keywords.OptOutMessage
+		}
+		if len(keywords.HelpKeywords) > 0 {
+			o.keywords.HelpKeywords = keywords.HelpKeywords
+		}
+		if keywords.HelpMessage != "" {
+			o.keywords.HelpMessage = keywords.HelpMessage
+		}
+	}
+}
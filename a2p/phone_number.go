@@ -1,9 +1,9 @@
 package a2p
 
 import (
+	"context"
 	"fmt"
 
-	"github.com/twilio/twilio-go"
 	api "github.com/twilio/twilio-go/rest/api/v2010"
 	messaging "github.com/twilio/twilio-go/rest/messaging/v1"
 )
@@ -22,9 +22,7 @@ type ListAvailablePhoneNumberLocalParams struct {
 
 // GetAvailablePhoneNumbers retrieves a list of available local phone numbers
 // for a specified area code and prints their friendly names.
-func (s *A2PService) GetAvailablePhoneNumbers(param ListAvailablePhoneNumberLocalParams) ([]string, error) {
-	client := twilio.NewRestClient()
-
+func (s *A2PService) GetAvailablePhoneNumbers(ctx context.Context, param ListAvailablePhoneNumberLocalParams) ([]string, error) {
 	// Set parameters for the API request
 	params := &api.ListAvailablePhoneNumberLocalParams{
 		AreaCode:     &param.AreaCode,
@@ -35,7 +33,7 @@ func (s *A2PService) GetAvailablePhoneNumbers(param ListAvailablePhoneNumberLoca
 	}
 
 	// Make the API request to fetch available phone numbers
-	resp, err := client.Api.ListAvailablePhoneNumberLocal("US", params)
+	resp, err := s.accounts.ListAvailablePhoneNumberLocal(ctx, "US", params)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching phone numbers: %v", err)
 	}
@@ -53,26 +51,37 @@ func (s *A2PService) GetAvailablePhoneNumbers(param ListAvailablePhoneNumberLoca
 }
 
 // AddPhoneNumberToMessagingService associates a phone number with the messaging service.
-func (s *A2PService) AddPhoneNumberToMessagingService(serviceSid string, params *messaging.CreatePhoneNumberParams) (*messaging.MessagingV1PhoneNumber, error) {
+func (s *A2PService) AddPhoneNumberToMessagingService(ctx context.Context, serviceSid string, params *messaging.CreatePhoneNumberParams) (*messaging.MessagingV1PhoneNumber, error) {
 	// Add the phone number to the Messaging Service
-	resp, err := s.client.MessagingV1.CreatePhoneNumber(serviceSid, params)
+	resp, err := reuseOrCreate(ctx, s, "messaging service phone number", func(ctx context.Context) (*messaging.MessagingV1PhoneNumber, error) {
+		return findPhoneNumber(ctx, s.messaging, serviceSid, params)
+	}, func(ctx context.Context) (*messaging.MessagingV1PhoneNumber, error) {
+		return s.messaging.CreatePhoneNumber(ctx, serviceSid, params)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error adding phone number to messaging service: %v", err)
 	}
 	if resp.Sid != nil {
-		fmt.Println("Phone number added successfully with SID:", *resp.Sid)
+		s.logger.Info("phone number added to messaging service",
+			"operation", "AddPhoneNumberToMessagingService",
+			"messaging_service_sid", serviceSid,
+			"phone_number_sid", *resp.Sid,
+		)
 	} else {
-		fmt.Println("Failed to retrieve SID from response.")
+		s.logger.Warn("phone number added to messaging service without a SID in the response",
+			"operation", "AddPhoneNumberToMessagingService",
+			"messaging_service_sid", serviceSid,
+		)
 	}
 	return resp, nil
 }
 
-func (s *A2PService) GetPhoneNumberSID(phoneNumber string) (string, error) {
+func (s *A2PService) GetPhoneNumberSID(ctx context.Context, phoneNumber string) (string, error) {
 
 	params := &api.ListIncomingPhoneNumberParams{}
 	params.SetPhoneNumber(phoneNumber)
 
-	resp, err := s.client.Api.ListIncomingPhoneNumber(params)
+	resp, err := s.accounts.ListIncomingPhoneNumber(ctx, params)
 	if err != nil {
 		return "", err
 	}
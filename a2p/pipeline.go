@@ -0,0 +1,244 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrStepNotFound is returned when a Pipeline has no step of that name.
+	ErrStepNotFound = errors.New("pipeline step not found")
+	// ErrDuplicateStep is returned when a step name is already in a Pipeline.
+	ErrDuplicateStep = errors.New("pipeline step already exists")
+	// ErrDependencyCycle is returned when the DependsOn of pipeline steps
+	// form a cycle, so none of them can start.
+	ErrDependencyCycle = errors.New("pipeline steps depend on each other")
+)
+
+// Step is one stage of an onboarding pipeline.
+type Step interface {
+	// Name identifies the step in checkpoints, logs and StageError.Stage. The
+	// built-in steps use their stage IDs, "2.1" to "5.1".
+	Name() string
+	// Run performs the step and returns the SID of the resource it created
+	// or acted on. Later steps read it with PipelineState.SID.
+	Run(ctx context.Context, state *PipelineState) (string, error)
+	// Compensate undoes Run when WithRollback is set. sid is the value Run
+	// returned.
+	Compensate(ctx context.Context, state *PipelineState, sid string) error
+}
+
+// PipelineState is shared by the steps of one onboarding run, which may run
+// concurrently.
+type PipelineState struct {
+	// Service is scoped to the customer's subaccount.
+	Service *A2PService
+	Params  *FullA2POnboardingParams
+
+	// mu guards checkpoint and onboarding.
+	mu         sync.Mutex
+	checkpoint *OnboardingCheckpoint
+	// onboarding is the state reached by the steps completed so far, in
+	// this run or an earlier one; see FuncStep.Advances.
+	onboarding OnboardingState
+}
+
+// SID returns what the named step returned, in this run or an earlier one
+// being resumed, or "" if it has not run.
+func (st *PipelineState) SID(step string) string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.checkpoint.Stages[step]
+}
+
+// update changes the checkpoint under the state lock.
+func (st *PipelineState) update(fn func(checkpoint *OnboardingCheckpoint)) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	fn(st.checkpoint)
+}
+
+// FuncStep builds a Step from functions; the built-in steps are FuncSteps.
+type FuncStep struct {
+	ID string
+	// Operation is logged and reported in StageError.Operation; it defaults
+	// to ID.
+	Operation string
+	// Key names the SID in StageError.CreatedSIDs and in the logs, for
+	// example "customer_profile_sid". Leave it empty for steps that only act
+	// on existing resources.
+	Key string
+	// Resource is the kind of resource reported by a rollback, for example
+	// "end_user".
+	Resource string
+	// DependsOn names the steps that must finish before this one starts;
+	// steps without a path between them run concurrently. Names not in the
+	// pipeline are ignored. Leave it nil to run the step after every step
+	// in front of it and before every step behind it, and set it to an
+	// empty slice for a step that needs nothing.
+	DependsOn []string
+	// Advances is the onboarding state reached once the step has
+	// succeeded, such as StateProfileSubmitted for the submission of the
+	// customer profile. Most steps leave it empty.
+	Advances OnboardingState
+	RunFunc  func(ctx context.Context, state *PipelineState) (string, error)
+	// CompensateFunc is nil for steps with nothing to undo, such as
+	// evaluations, which go away with their bundle.
+	CompensateFunc func(ctx context.Context, state *PipelineState, sid string) error
+
+	// builtin marks the steps of DefaultPipeline, which only call Twilio
+	// through state.Service and so are safe to run in PlanOnboarding.
+	builtin bool
+}
+
+func (f *FuncStep) Name() string {
+	return f.ID
+}
+
+func (f *FuncStep) Run(ctx context.Context, state *PipelineState) (string, error) {
+	return f.RunFunc(ctx, state)
+}
+
+func (f *FuncStep) Compensate(ctx context.Context, state *PipelineState, sid string) error {
+	if f.CompensateFunc == nil {
+		return nil
+	}
+	return f.CompensateFunc(ctx, state, sid)
+}
+
+// stepInfo returns how a step is logged and reported. Steps other than
+// FuncStep use their name throughout; compensates reports whether the step
+// has anything to undo.
+func stepInfo(step Step) (operation, key, resource string, compensates bool) {
+	f, ok := step.(*FuncStep)
+	if !ok {
+		return step.Name(), step.Name(), step.Name(), true
+	}
+	operation = f.Operation
+	if operation == "" {
+		operation = f.ID
+	}
+	resource = f.Resource
+	if resource == "" {
+		resource = f.ID
+	}
+	return operation, f.Key, resource, f.CompensateFunc != nil
+}
+
+// builtinStep reports whether step is one of the steps of DefaultPipeline.
+func builtinStep(step Step) bool {
+	f, ok := step.(*FuncStep)
+	return ok && f.builtin
+}
+
+// stepAdvances returns the FuncStep.Advances of step.
+func stepAdvances(step Step) OnboardingState {
+	if f, ok := step.(*FuncStep); ok {
+		return f.Advances
+	}
+	return ""
+}
+
+// Pipeline is the ordered list of steps OnboardCustomer runs, concurrently
+// where their DependsOn allow. Start from DefaultPipeline and insert, replace
+// or skip steps, then pass it with WithPipeline:
+//
+//	p := a2p.DefaultPipeline()
+//	err := p.InsertAfter("2.5", secondRepStep, attachSecondRepStep)
+type Pipeline struct {
+	steps []Step
+}
+
+// NewPipeline returns a pipeline of steps, which must have distinct names.
+func NewPipeline(steps ...Step) (*Pipeline, error) {
+	p := &Pipeline{}
+	if err := p.insert(0, steps); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DefaultPipeline returns the built-in stages 2.1 to 5.1.
+func DefaultPipeline() *Pipeline {
+	return &Pipeline{steps: defaultSteps()}
+}
+
+// Steps returns the steps in order.
+func (p *Pipeline) Steps() []Step {
+	return append([]Step(nil), p.steps...)
+}
+
+// InsertBefore adds steps in front of the named step.
+func (p *Pipeline) InsertBefore(name string, steps ...Step) error {
+	i, err := p.index(name)
+	if err != nil {
+		return err
+	}
+	return p.insert(i, steps)
+}
+
+// InsertAfter adds steps behind the named step.
+func (p *Pipeline) InsertAfter(name string, steps ...Step) error {
+	i, err := p.index(name)
+	if err != nil {
+		return err
+	}
+	return p.insert(i+1, steps)
+}
+
+// Append adds steps at the end.
+func (p *Pipeline) Append(steps ...Step) error {
+	return p.insert(len(p.steps), steps)
+}
+
+// Replace swaps the named step for step, which may keep the name so later
+// steps reading its SID keep working.
+func (p *Pipeline) Replace(name string, step Step) error {
+	i, err := p.index(name)
+	if err != nil {
+		return err
+	}
+	if step.Name() != name {
+		if _, err := p.index(step.Name()); err == nil {
+			return fmt.Errorf("%w: %s", ErrDuplicateStep, step.Name())
+		}
+	}
+	p.steps[i] = step
+	return nil
+}
+
+// Skip removes the named steps. Steps that read the SID of a skipped step
+// get "" unless a resumed checkpoint provides it.
+func (p *Pipeline) Skip(names ...string) error {
+	for _, name := range names {
+		i, err := p.index(name)
+		if err != nil {
+			return err
+		}
+		p.steps = append(p.steps[:i:i], p.steps[i+1:]...)
+	}
+	return nil
+}
+
+func (p *Pipeline) index(name string) (int, error) {
+	for i, step := range p.steps {
+		if step.Name() == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrStepNotFound, name)
+}
+
+func (p *Pipeline) insert(i int, steps []Step) error {
+	seen := map[string]bool{}
+	for _, step := range steps {
+		if _, err := p.index(step.Name()); err == nil || seen[step.Name()] {
+			return fmt.Errorf("%w: %s", ErrDuplicateStep, step.Name())
+		}
+		seen[step.Name()] = true
+	}
+	p.steps = append(p.steps[:i:i], append(append([]Step(nil), steps...), p.steps[i:]...)...)
+	return nil
+}
@@ -0,0 +1,368 @@
+package a2p
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+	messaging "github.com/twilio/twilio-go/rest/messaging/v1"
+	trusthub "github.com/twilio/twilio-go/rest/trusthub/v1"
+)
+
+// OnboardingPlan is what OnboardCustomer would send for a customer, produced
+// by PlanOnboarding without calling Twilio.
+type OnboardingPlan struct {
+	LocationID   string `json:"location_id"`
+	SubaccountID string `json:"subaccount_id"`
+	// AccountSid and AuthToken are the subaccount credentials the requests
+	// would be sent with; the token is masked.
+	AccountSid string         `json:"account_sid"`
+	AuthToken  string         `json:"auth_token"`
+	Stages     []PlannedStage `json:"stages"`
+	// Problems lists what would make the onboarding fail or be rejected.
+	Problems []PlanProblem `json:"problems,omitempty"`
+}
+
+// PlannedStage is one pipeline step and the requests it would make.
+type PlannedStage struct {
+	Stage     string `json:"stage"`
+	Operation string `json:"operation"`
+	// NotSimulated is set for steps added to the pipeline: a dry run does
+	// not execute their code, so their requests are unknown.
+	NotSimulated bool             `json:"not_simulated,omitempty"`
+	Requests     []PlannedRequest `json:"requests"`
+}
+
+// PlannedRequest is one Twilio request. SIDs of resources created earlier in
+// the plan appear as placeholders such as "<customer_profile_sid>".
+type PlannedRequest struct {
+	Method   string         `json:"method"`
+	Endpoint string         `json:"endpoint"`
+	Params   map[string]any `json:"params,omitempty"`
+}
+
+// PlanProblem is a validation failure. Field is the JSON name of the
+// FullA2POnboardingParams field, Stage the step that would fail.
+type PlanProblem struct {
+	Field   string `json:"field,omitempty"`
+	Stage   string `json:"stage,omitempty"`
+	Message string `json:"message"`
+}
+
+// Valid reports whether the plan found no problems.
+func (p *OnboardingPlan) Valid() bool {
+	return len(p.Problems) == 0
+}
+
+// JSON returns the plan as indented JSON, leaving the <...> placeholders
+// unescaped.
+func (p *OnboardingPlan) JSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// PlanOnboarding runs the onboarding pipeline against in-memory fakes and
+// returns every request OnboardCustomer would send, with secrets masked and
+// validation problems listed. It assumes none of the resources exist yet;
+// the real run reuses those that do. Only the built-in steps are simulated;
+// steps added to the pipeline are listed as NotSimulated without running
+// them, since their code may act outside the service's Twilio clients.
+func (s *A2PService) PlanOnboarding(ctx context.Context, params *FullA2POnboardingParams) (*OnboardingPlan, error) {
+	plan := &OnboardingPlan{
+		LocationID:   params.LocationID,
+		SubaccountID: params.SubaccountID,
+		AccountSid:   params.TwilioUsername,
+		AuthToken:    maskSecret(params.TwilioPassword),
+		Stages:       []PlannedStage{},
+	}
+	plan.Problems = validationProblems(params)
+
+	rec := &planRecorder{}
+	o := serviceOptions{
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		retry:     RetryPolicy{MaxAttempts: 1},
+		callbacks: s.callbacks,
+		pipeline:  s.pipeline,
+	}
+	planner := newA2PService(
+		planTrustHub{TrustHubClient: NewFakeTrustHub(), rec: rec},
+		planMessaging{MessagingClient: NewFakeMessaging(), rec: rec},
+		planAccounts{AccountsClient: NewFakeAccounts(), rec: rec, accountSid: params.TwilioUsername},
+		o,
+	)
+	state := &PipelineState{Service: planner, Params: params, checkpoint: NewOnboardingCheckpoint(params)}
+
+	var placeholders []string
+	for _, step := range s.pipeline.Steps() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		operation, key, _, _ := stepInfo(step)
+		if !builtinStep(step) {
+			plan.Stages = append(plan.Stages, PlannedStage{Stage: step.Name(), Operation: operation, NotSimulated: true, Requests: []PlannedRequest{}})
+			continue
+		}
+		rec.requests = nil
+		sid, err := step.Run(ctx, state)
+		plan.Stages = append(plan.Stages, PlannedStage{Stage: step.Name(), Operation: operation, Requests: rec.requests})
+		if err != nil {
+			plan.Problems = append(plan.Problems, PlanProblem{Stage: step.Name(), Message: err.Error()})
+			break
+		}
+		state.checkpoint.Stages[step.Name()] = sid
+		if sid != "" {
+			if key == "" {
+				key = step.Name() + "_sid"
+			}
+			placeholders = append(placeholders, sid, "<"+key+">")
+		}
+	}
+
+	// The first step returning a SID names it; a submission returning its
+	// bundle SID keeps the bundle's placeholder.
+	replacer := strings.NewReplacer(firstPlaceholders(placeholders)...)
+	for i := range plan.Stages {
+		for j := range plan.Stages[i].Requests {
+			req := &plan.Stages[i].Requests[j]
+			req.Endpoint = replacer.Replace(req.Endpoint)
+			req.Params, _ = substituteSIDs(req.Params, replacer).(map[string]any)
+		}
+	}
+	return plan, nil
+}
+
+// validationProblems runs the checks OnboardCustomer makes before calling
+// Twilio, plus FullA2POnboardingParams.Validate.
+func validationProblems(params *FullA2POnboardingParams) []PlanProblem {
+	var problems []PlanProblem
+	reported := map[string]bool{}
+	if err := validateOnboardingParams(params); err != nil {
+		reported[requiredParamFields[err]] = true
+		problems = append(problems, PlanProblem{Field: requiredParamFields[err], Message: err.Error()})
+	}
+	if params.TwilioUsername != "" && !strings.HasPrefix(params.TwilioUsername, "AC") {
+		problems = append(problems, PlanProblem{Field: "subaccount_username", Message: ErrSubaccountCredentials.Error()})
+	}
+
+	var fieldErrs validation.Errors
+	if err := params.Validate(); errors.As(err, &fieldErrs) {
+		fields := make([]string, 0, len(fieldErrs))
+		for field := range fieldErrs {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			if reported[field] {
+				continue
+			}
+			problems = append(problems, PlanProblem{Field: field, Message: fieldErrs[field].Error()})
+		}
+	} else if err != nil {
+		problems = append(problems, PlanProblem{Message: err.Error()})
+	}
+	return problems
+}
+
+// requiredParamFields maps the errors of validateOnboardingParams to the
+// fields they are about.
+var requiredParamFields = map[error]string{
+	ErrCreateSubaccount:    "subaccount_id",
+	ErrPurchasePhoneNumber: "twilio_purchased_phone_number",
+	ErrGetPhoneNumberSID:   "twilio_purchased_phone_number_sid",
+	ErrGetTwilioUsername:   "subaccount_username",
+	ErrGetTwilioPassword:   "subaccount_password",
+}
+
+// secretParam matches request parameters whose values are masked in a plan.
+var secretParam = regexp.MustCompile(`(?i)token|password|secret`)
+
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
+
+func firstPlaceholders(pairs []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if !seen[pairs[i]] {
+			seen[pairs[i]] = true
+			out = append(out, pairs[i], pairs[i+1])
+		}
+	}
+	return out
+}
+
+func substituteSIDs(v any, replacer *strings.Replacer) any {
+	switch v := v.(type) {
+	case string:
+		return replacer.Replace(v)
+	case map[string]any:
+		for key, value := range v {
+			v[key] = substituteSIDs(value, replacer)
+		}
+		return v
+	case []any:
+		for i, value := range v {
+			v[i] = substituteSIDs(value, replacer)
+		}
+		return v
+	}
+	return v
+}
+
+// planRecorder collects the requests of the step being planned.
+type planRecorder struct {
+	requests []PlannedRequest
+}
+
+func (r *planRecorder) record(method, endpoint string, params any) {
+	req := PlannedRequest{Method: method, Endpoint: endpoint}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err == nil {
+			_ = json.Unmarshal(data, &req.Params)
+		}
+		for key, value := range req.Params {
+			if secretParam.MatchString(key) {
+				req.Params[key] = maskSecret(fmt.Sprint(value))
+			}
+		}
+	}
+	r.requests = append(r.requests, req)
+}
+
+const (
+	trustHubBaseURL  = "https://trusthub.twilio.com/v1"
+	messagingBaseURL = "https://messaging.twilio.com/v1"
+	api2010BaseURL   = "https://api.twilio.com/2010-04-01"
+)
+
+// planTrustHub, planMessaging and planAccounts record the writes onboarding
+// makes and pass every call on to the in-memory fakes, so later steps see
+// the resources earlier ones created.
+type planTrustHub struct {
+	TrustHubClient
+	rec *planRecorder
+}
+
+func (c planTrustHub) CreateCustomerProfile(ctx context.Context, params *trusthub.CreateCustomerProfileParams) (*trusthub.TrusthubV1CustomerProfile, error) {
+	c.rec.record("POST", trustHubBaseURL+"/CustomerProfiles", params)
+	return c.TrustHubClient.CreateCustomerProfile(ctx, params)
+}
+
+func (c planTrustHub) UpdateCustomerProfile(ctx context.Context, sid string, params *trusthub.UpdateCustomerProfileParams) (*trusthub.TrusthubV1CustomerProfile, error) {
+	c.rec.record("POST", trustHubBaseURL+"/CustomerProfiles/"+sid, params)
+	return c.TrustHubClient.UpdateCustomerProfile(ctx, sid, params)
+}
+
+func (c planTrustHub) CreateCustomerProfileEntityAssignment(ctx context.Context, customerProfileSid string, params *trusthub.CreateCustomerProfileEntityAssignmentParams) (*trusthub.TrusthubV1CustomerProfileEntityAssignment, error) {
+	c.rec.record("POST", trustHubBaseURL+"/CustomerProfiles/"+customerProfileSid+"/EntityAssignments", params)
+	return c.TrustHubClient.CreateCustomerProfileEntityAssignment(ctx, customerProfileSid, params)
+}
+
+func (c planTrustHub) CreateCustomerProfileEvaluation(ctx context.Context, customerProfileSid string, params *trusthub.CreateCustomerProfileEvaluationParams) (*trusthub.TrusthubV1CustomerProfileEvaluation, error) {
+	c.rec.record("POST", trustHubBaseURL+"/CustomerProfiles/"+customerProfileSid+"/Evaluations", params)
+	return c.TrustHubClient.CreateCustomerProfileEvaluation(ctx, customerProfileSid, params)
+}
+
+func (c planTrustHub) CreateEndUser(ctx context.Context, params *trusthub.CreateEndUserParams) (*trusthub.TrusthubV1EndUser, error) {
+	c.rec.record("POST", trustHubBaseURL+"/EndUsers", params)
+	return c.TrustHubClient.CreateEndUser(ctx, params)
+}
+
+func (c planTrustHub) CreateSupportingDocument(ctx context.Context, params *trusthub.CreateSupportingDocumentParams) (*trusthub.TrusthubV1SupportingDocument, error) {
+	c.rec.record("POST", trustHubBaseURL+"/SupportingDocuments", params)
+	return c.TrustHubClient.CreateSupportingDocument(ctx, params)
+}
+
+func (c planTrustHub) CreateTrustProduct(ctx context.Context, params *trusthub.CreateTrustProductParams) (*trusthub.TrusthubV1TrustProduct, error) {
+	c.rec.record("POST", trustHubBaseURL+"/TrustProducts", params)
+	return c.TrustHubClient.CreateTrustProduct(ctx, params)
+}
+
+func (c planTrustHub) UpdateTrustProduct(ctx context.Context, sid string, params *trusthub.UpdateTrustProductParams) (*trusthub.TrusthubV1TrustProduct, error) {
+	c.rec.record("POST", trustHubBaseURL+"/TrustProducts/"+sid, params)
+	return c.TrustHubClient.UpdateTrustProduct(ctx, sid, params)
+}
+
+func (c planTrustHub) CreateTrustProductEntityAssignment(ctx context.Context, trustProductSid string, params *trusthub.CreateTrustProductEntityAssignmentParams) (*trusthub.TrusthubV1TrustProductEntityAssignment, error) {
+	c.rec.record("POST", trustHubBaseURL+"/TrustProducts/"+trustProductSid+"/EntityAssignments", params)
+	return c.TrustHubClient.CreateTrustProductEntityAssignment(ctx, trustProductSid, params)
+}
+
+func (c planTrustHub) CreateTrustProductEvaluation(ctx context.Context, trustProductSid string, params *trusthub.CreateTrustProductEvaluationParams) (*trusthub.TrusthubV1TrustProductEvaluation, error) {
+	c.rec.record("POST", trustHubBaseURL+"/TrustProducts/"+trustProductSid+"/Evaluations", params)
+	return c.TrustHubClient.CreateTrustProductEvaluation(ctx, trustProductSid, params)
+}
+
+type planMessaging struct {
+	MessagingClient
+	rec *planRecorder
+}
+
+func (c planMessaging) CreateBrandRegistrations(ctx context.Context, params *messaging.CreateBrandRegistrationsParams) (*messaging.MessagingV1BrandRegistrations, error) {
+	c.rec.record("POST", messagingBaseURL+"/a2p/BrandRegistrations", params)
+	return c.MessagingClient.CreateBrandRegistrations(ctx, params)
+}
+
+func (c planMessaging) CreateService(ctx context.Context, params *messaging.CreateServiceParams) (*messaging.MessagingV1Service, error) {
+	c.rec.record("POST", messagingBaseURL+"/Services", params)
+	return c.MessagingClient.CreateService(ctx, params)
+}
+
+func (c planMessaging) UpdateService(ctx context.Context, sid string, params *messaging.UpdateServiceParams) (*messaging.MessagingV1Service, error) {
+	c.rec.record("POST", messagingBaseURL+"/Services/"+sid, params)
+	return c.MessagingClient.UpdateService(ctx, sid, params)
+}
+
+func (c planMessaging) CreatePhoneNumber(ctx context.Context, serviceSid string, params *messaging.CreatePhoneNumberParams) (*messaging.MessagingV1PhoneNumber, error) {
+	c.rec.record("POST", messagingBaseURL+"/Services/"+serviceSid+"/PhoneNumbers", params)
+	return c.MessagingClient.CreatePhoneNumber(ctx, serviceSid, params)
+}
+
+func (c planMessaging) CreateUsAppToPerson(ctx context.Context, messagingServiceSid string, params *messaging.CreateUsAppToPersonParams) (*messaging.MessagingV1UsAppToPerson, error) {
+	c.rec.record("POST", messagingBaseURL+"/Services/"+messagingServiceSid+"/Compliance/Usa2p", params)
+	return c.MessagingClient.CreateUsAppToPerson(ctx, messagingServiceSid, params)
+}
+
+type planAccounts struct {
+	AccountsClient
+	rec        *planRecorder
+	accountSid string
+}
+
+func (c planAccounts) CreateAccount(ctx context.Context, params *api.CreateAccountParams) (*api.ApiV2010Account, error) {
+	c.rec.record("POST", api2010BaseURL+"/Accounts.json", params)
+	return c.AccountsClient.CreateAccount(ctx, params)
+}
+
+func (c planAccounts) UpdateAccount(ctx context.Context, sid string, params *api.UpdateAccountParams) (*api.ApiV2010Account, error) {
+	c.rec.record("POST", api2010BaseURL+"/Accounts/"+sid+".json", params)
+	return c.AccountsClient.UpdateAccount(ctx, sid, params)
+}
+
+func (c planAccounts) CreateAddress(ctx context.Context, params *api.CreateAddressParams) (*api.ApiV2010Address, error) {
+	c.rec.record("POST", api2010BaseURL+"/Accounts/"+c.accountSid+"/Addresses.json", params)
+	return c.AccountsClient.CreateAddress(ctx, params)
+}
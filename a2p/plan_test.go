@@ -0,0 +1,113 @@
+package a2p
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPlanOnboarding(t *testing.T) {
+	tests := []struct {
+		name   string
+		params func(params *FullA2POnboardingParams)
+		// wantProblems are the fields reported as problems.
+		wantProblems []string
+	}{
+		{name: "valid"},
+		{
+			name:         "missing fields",
+			params:       func(params *FullA2POnboardingParams) { params.Email, params.TwilioPurchasedPhoneNumberSID = "", "" },
+			wantProblems: []string{"twilio_purchased_phone_number_sid", "customer_email"},
+		},
+		{
+			name:         "not a subaccount",
+			params:       func(params *FullA2POnboardingParams) { params.TwilioUsername = "SK123" },
+			wantProblems: []string{"subaccount_username"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trustHub, messagingFake, accounts := NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts()
+			s := NewA2PService(trustHub, messagingFake, accounts, WithLogger(discardLogger()))
+			params := testParams()
+			if tt.params != nil {
+				tt.params(params)
+			}
+
+			plan, err := s.PlanOnboarding(context.Background(), params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(trustHub.CustomerProfiles) + len(trustHub.EndUsers) + len(messagingFake.Services); got != 0 {
+				t.Errorf("the plan created %d resources in the service's clients", got)
+			}
+			if len(plan.Stages) != len(defaultSteps()) {
+				t.Errorf("%d planned stages, want %d", len(plan.Stages), len(defaultSteps()))
+			}
+			var problems []string
+			for _, problem := range plan.Problems {
+				problems = append(problems, problem.Field)
+			}
+			if strings.Join(problems, ",") != strings.Join(tt.wantProblems, ",") {
+				t.Errorf("problems %v, want %v", plan.Problems, tt.wantProblems)
+			}
+			if plan.Valid() != (len(tt.wantProblems) == 0) {
+				t.Errorf("Valid() = %t with problems %v", plan.Valid(), plan.Problems)
+			}
+
+			data, err := plan.JSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(data), params.TwilioPassword) {
+				t.Error("plan contains the subaccount password")
+			}
+			if !strings.Contains(string(data), "/CustomerProfiles/<customer_profile_sid>/EntityAssignments") {
+				t.Errorf("plan does not attach to the planned customer profile:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestPlanOnboardingSkipsCustomSteps(t *testing.T) {
+	ran := 0
+	custom := &FuncStep{
+		ID:        "notify",
+		Operation: "NotifyCRM",
+		DependsOn: []string{"2.1"},
+		RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+			ran++
+			return "", nil
+		},
+	}
+	pipeline := DefaultPipeline()
+	if err := pipeline.InsertAfter("2.1", custom); err != nil {
+		t.Fatal(err)
+	}
+	// A built-in stage replaced with other code is custom too.
+	replaced := &FuncStep{ID: "5.1", RunFunc: custom.RunFunc}
+	if err := pipeline.Replace("5.1", replaced); err != nil {
+		t.Fatal(err)
+	}
+	s := NewA2PService(NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts(), WithLogger(discardLogger()), WithPipeline(pipeline))
+
+	plan, err := s.PlanOnboarding(context.Background(), testParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ran != 0 {
+		t.Errorf("custom steps ran %d times during the plan", ran)
+	}
+	for _, stage := range plan.Stages {
+		custom := stage.Stage == "notify" || stage.Stage == "5.1"
+		if stage.NotSimulated != custom {
+			t.Errorf("stage %s: NotSimulated = %t, want %t", stage.Stage, stage.NotSimulated, custom)
+		}
+		if custom && len(stage.Requests) != 0 {
+			t.Errorf("stage %s: %d requests planned for a custom step", stage.Stage, len(stage.Requests))
+		}
+	}
+	if !plan.Valid() {
+		t.Errorf("problems %v", plan.Problems)
+	}
+}
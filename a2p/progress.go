@@ -0,0 +1,151 @@
+package a2p
+
+import (
+	"sync"
+	"time"
+)
+
+// StageEvent describes an onboarding stage to a ProgressObserver.
+type StageEvent struct {
+	LocationID string `json:"location_id"`
+	Stage      string `json:"stage"`
+	Operation  string `json:"operation"`
+	// Index is the position of the stage in the pipeline, from 1, and Total
+	// the number of stages, for showing "stage 7 of 18". Stages run
+	// concurrently may finish out of order.
+	Index int `json:"index"`
+	Total int `json:"total"`
+	// SID is the resource the stage created or acted on; it is set when the
+	// stage succeeded.
+	SID string `json:"sid,omitempty"`
+	// Duration is how long the stage ran; it is zero for OnStageStarted and
+	// for stages skipped because a resumed checkpoint has them.
+	Duration time.Duration `json:"duration"`
+	Skipped  bool          `json:"skipped,omitempty"`
+	// Err is the *StageError of a failed stage.
+	Err error `json:"-"`
+}
+
+// ProgressObserver is told about every stage OnboardCustomer and
+// ResumeOnboarding run. Stages run concurrently, so the methods must be safe
+// for concurrent use; they run on the onboarding goroutines and should return
+// quickly.
+type ProgressObserver interface {
+	OnStageStarted(event StageEvent)
+	// OnStageSucceeded is also called, with Skipped set and no preceding
+	// OnStageStarted, for stages completed in an earlier run.
+	OnStageSucceeded(event StageEvent)
+	OnStageFailed(event StageEvent)
+}
+
+// ProgressKind tells which ProgressObserver method a ProgressEvent stands for.
+type ProgressKind string
+
+const (
+	StageStarted   ProgressKind = "started"
+	StageSucceeded ProgressKind = "succeeded"
+	StageFailed    ProgressKind = "failed"
+)
+
+// ProgressEvent is a StageEvent sent by a ChannelObserver.
+type ProgressEvent struct {
+	Kind ProgressKind `json:"kind"`
+	StageEvent
+}
+
+// ChannelObserver is a ProgressObserver that sends the events to a channel,
+// for example to stream them to a client:
+//
+//	progress := a2p.NewChannelObserver(32)
+//	s := a2p.NewA2PServiceInstance(sid, token, a2p.WithProgressObserver(progress))
+//	go func() {
+//		defer progress.Close()
+//		s.OnboardCustomer(ctx, params)
+//	}()
+//	for event := range progress.Events() {
+//		...
+//	}
+//
+// Onboarding waits while the channel is full, so the events must be read
+// until Close.
+type ChannelObserver struct {
+	events chan ProgressEvent
+	done   chan struct{}
+	once   sync.Once
+	// mu keeps Close from closing events during a send.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewChannelObserver returns a ChannelObserver whose channel buffers buffer
+// events.
+func NewChannelObserver(buffer int) *ChannelObserver {
+	return &ChannelObserver{
+		events: make(chan ProgressEvent, buffer),
+		done:   make(chan struct{}),
+	}
+}
+
+// Events returns the channel the events are sent to. It is closed by Close.
+func (c *ChannelObserver) Events() <-chan ProgressEvent {
+	return c.events
+}
+
+// Close closes the events channel. Events observed afterwards, and sends
+// blocked on a full channel, are dropped.
+func (c *ChannelObserver) Close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.closed = true
+		close(c.events)
+	})
+}
+
+func (c *ChannelObserver) OnStageStarted(event StageEvent) {
+	c.send(ProgressEvent{Kind: StageStarted, StageEvent: event})
+}
+
+func (c *ChannelObserver) OnStageSucceeded(event StageEvent) {
+	c.send(ProgressEvent{Kind: StageSucceeded, StageEvent: event})
+}
+
+func (c *ChannelObserver) OnStageFailed(event StageEvent) {
+	c.send(ProgressEvent{Kind: StageFailed, StageEvent: event})
+}
+
+func (c *ChannelObserver) send(event ProgressEvent) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.events <- event:
+	case <-c.done:
+	}
+}
+
+// observers fans events out to every registered ProgressObserver.
+type observers []ProgressObserver
+
+func (o observers) started(event StageEvent) {
+	for _, observer := range o {
+		observer.OnStageStarted(event)
+	}
+}
+
+func (o observers) succeeded(event StageEvent) {
+	for _, observer := range o {
+		observer.OnStageSucceeded(event)
+	}
+}
+
+func (o observers) failed(event StageEvent) {
+	for _, observer := range o {
+		observer.OnStageFailed(event)
+	}
+}
+
+var _ ProgressObserver = (*ChannelObserver)(nil)
@@ -0,0 +1,60 @@
+package a2p
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces requests evenly at a fixed rate.
+type rateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the caller may send a request.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rateLimiterKey carries the *rateLimiter of an account through the context
+// of everything done for it, so every attempt made by retryDo waits on it.
+type rateLimiterKey struct{}
+
+func withRateLimiter(ctx context.Context, l *rateLimiter) context.Context {
+	return context.WithValue(ctx, rateLimiterKey{}, l)
+}
+
+func waitRateLimit(ctx context.Context) error {
+	l, ok := ctx.Value(rateLimiterKey{}).(*rateLimiter)
+	if !ok {
+		return nil
+	}
+	return l.wait(ctx)
+}
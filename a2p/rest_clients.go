@@ -0,0 +1,525 @@
+package a2p
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/twilio/twilio-go"
+	"github.com/twilio/twilio-go/client"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+	messaging "github.com/twilio/twilio-go/rest/messaging/v1"
+	trusthub "github.com/twilio/twilio-go/rest/trusthub/v1"
+)
+
+// restBackend adapts a twilio-go client to the context-aware client
+// interfaces. twilio-go builds its HTTP requests without a context, so every
+// call gets a copy of the client whose transport attaches ctx to the request
+// and whose credentials come fresh from the provider.
+type restBackend struct {
+	rest  *twilio.RestClient
+	creds CredentialsProvider
+}
+
+func newRESTBackend(rest *twilio.RestClient, creds CredentialsProvider) *restBackend {
+	return &restBackend{rest: rest, creds: creds}
+}
+
+// newTwilioRestClient builds a twilio-go client from the transport options.
+func newTwilioRestClient(creds Credentials, o serviceOptions) *twilio.RestClient {
+	httpClient := defaultTwilioHTTPClient()
+	if o.httpClient != nil {
+		copied := *o.httpClient
+		httpClient = &copied
+	}
+	if o.baseURL != nil || o.baseURLErr != nil {
+		httpClient.Transport = &baseURLTransport{base: o.baseURL, err: o.baseURLErr, next: httpClient.Transport}
+	}
+
+	c := &client.Client{
+		Credentials: client.NewCredentials(creds.Username, creds.Password),
+		HTTPClient:  httpClient,
+	}
+	c.SetAccountSid(creds.AccountSid)
+
+	rest := twilio.NewRestClientWithParams(twilio.ClientParams{Client: c})
+	if o.edge != "" {
+		rest.SetEdge(o.edge)
+	}
+	if o.region != "" {
+		rest.SetRegion(o.region)
+	}
+	return rest
+}
+
+func (b *restBackend) handler(ctx context.Context) (*client.RequestHandler, error) {
+	base, ok := b.rest.RequestHandler.Client.(*client.Client)
+	if !ok {
+		// A custom BaseClient has no HTTP client or credentials we can bind.
+		return b.rest.RequestHandler, nil
+	}
+
+	creds, err := b.creds.Credentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Twilio credentials: %w", err)
+	}
+
+	httpClient := defaultTwilioHTTPClient()
+	if base.HTTPClient != nil {
+		copied := *base.HTTPClient
+		httpClient = &copied
+	}
+	httpClient.Transport = &contextTransport{ctx: ctx, next: httpClient.Transport}
+
+	bound := *base
+	bound.HTTPClient = httpClient
+	bound.Credentials = client.NewCredentials(creds.Username, creds.Password)
+	bound.SetAccountSid(creds.AccountSid)
+	return &client.RequestHandler{
+		Client: &bound,
+		Edge:   b.rest.RequestHandler.Edge,
+		Region: b.rest.RequestHandler.Region,
+	}, nil
+}
+
+// defaultTwilioHTTPClient mirrors the client twilio-go uses when none is set.
+func defaultTwilioHTTPClient() *http.Client {
+	return &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: 10 * time.Second,
+	}
+}
+
+// baseURLTransport sends every request to base, keeping the original path
+// and query. It backs WithBaseURL.
+type baseURLTransport struct {
+	base *url.URL
+	err  error
+	next http.RoundTripper
+}
+
+func (t *baseURLTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	out := req.Clone(req.Context())
+	out.URL.Scheme = t.base.Scheme
+	out.URL.Host = t.base.Host
+	out.URL.Path = strings.TrimSuffix(t.base.Path, "/") + req.URL.Path
+	out.Host = t.base.Host
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(out)
+}
+
+// contextTransport attaches ctx to every outgoing request and reports any
+// Retry-After header to the retrier.
+type contextTransport struct {
+	ctx  context.Context
+	next http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req.WithContext(t.ctx))
+	if err == nil {
+		recordRetryAfter(t.ctx, resp)
+	}
+	return resp, err
+}
+
+type restTrustHub struct {
+	backend *restBackend
+}
+
+func (c restTrustHub) CreateCustomerProfile(ctx context.Context, params *trusthub.CreateCustomerProfileParams) (*trusthub.TrusthubV1CustomerProfile, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).CreateCustomerProfile(params)
+}
+
+func (c restTrustHub) ListCustomerProfile(ctx context.Context, params *trusthub.ListCustomerProfileParams) ([]trusthub.TrusthubV1CustomerProfile, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).ListCustomerProfile(params)
+}
+
+func (c restTrustHub) FetchCustomerProfile(ctx context.Context, sid string) (*trusthub.TrusthubV1CustomerProfile, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).FetchCustomerProfile(sid)
+}
+
+func (c restTrustHub) UpdateCustomerProfile(ctx context.Context, sid string, params *trusthub.UpdateCustomerProfileParams) (*trusthub.TrusthubV1CustomerProfile, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).UpdateCustomerProfile(sid, params)
+}
+
+func (c restTrustHub) DeleteCustomerProfile(ctx context.Context, sid string) error {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return err
+	}
+	return trusthub.NewApiService(h).DeleteCustomerProfile(sid)
+}
+
+func (c restTrustHub) CreateCustomerProfileEntityAssignment(ctx context.Context, customerProfileSid string, params *trusthub.CreateCustomerProfileEntityAssignmentParams) (*trusthub.TrusthubV1CustomerProfileEntityAssignment, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).CreateCustomerProfileEntityAssignment(customerProfileSid, params)
+}
+
+func (c restTrustHub) ListCustomerProfileEntityAssignment(ctx context.Context, customerProfileSid string, params *trusthub.ListCustomerProfileEntityAssignmentParams) ([]trusthub.TrusthubV1CustomerProfileEntityAssignment, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).ListCustomerProfileEntityAssignment(customerProfileSid, params)
+}
+
+func (c restTrustHub) DeleteCustomerProfileEntityAssignment(ctx context.Context, customerProfileSid string, sid string) error {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return err
+	}
+	return trusthub.NewApiService(h).DeleteCustomerProfileEntityAssignment(customerProfileSid, sid)
+}
+
+func (c restTrustHub) CreateCustomerProfileEvaluation(ctx context.Context, customerProfileSid string, params *trusthub.CreateCustomerProfileEvaluationParams) (*trusthub.TrusthubV1CustomerProfileEvaluation, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).CreateCustomerProfileEvaluation(customerProfileSid, params)
+}
+
+func (c restTrustHub) CreateEndUser(ctx context.Context, params *trusthub.CreateEndUserParams) (*trusthub.TrusthubV1EndUser, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).CreateEndUser(params)
+}
+
+func (c restTrustHub) ListEndUser(ctx context.Context, params *trusthub.ListEndUserParams) ([]trusthub.TrusthubV1EndUser, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).ListEndUser(params)
+}
+
+func (c restTrustHub) DeleteEndUser(ctx context.Context, sid string) error {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return err
+	}
+	return trusthub.NewApiService(h).DeleteEndUser(sid)
+}
+
+func (c restTrustHub) CreateSupportingDocument(ctx context.Context, params *trusthub.CreateSupportingDocumentParams) (*trusthub.TrusthubV1SupportingDocument, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).CreateSupportingDocument(params)
+}
+
+func (c restTrustHub) ListSupportingDocument(ctx context.Context, params *trusthub.ListSupportingDocumentParams) ([]trusthub.TrusthubV1SupportingDocument, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).ListSupportingDocument(params)
+}
+
+func (c restTrustHub) DeleteSupportingDocument(ctx context.Context, sid string) error {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return err
+	}
+	return trusthub.NewApiService(h).DeleteSupportingDocument(sid)
+}
+
+func (c restTrustHub) CreateTrustProduct(ctx context.Context, params *trusthub.CreateTrustProductParams) (*trusthub.TrusthubV1TrustProduct, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).CreateTrustProduct(params)
+}
+
+func (c restTrustHub) ListTrustProduct(ctx context.Context, params *trusthub.ListTrustProductParams) ([]trusthub.TrusthubV1TrustProduct, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).ListTrustProduct(params)
+}
+
+func (c restTrustHub) FetchTrustProduct(ctx context.Context, sid string) (*trusthub.TrusthubV1TrustProduct, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).FetchTrustProduct(sid)
+}
+
+func (c restTrustHub) UpdateTrustProduct(ctx context.Context, sid string, params *trusthub.UpdateTrustProductParams) (*trusthub.TrusthubV1TrustProduct, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).UpdateTrustProduct(sid, params)
+}
+
+func (c restTrustHub) DeleteTrustProduct(ctx context.Context, sid string) error {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return err
+	}
+	return trusthub.NewApiService(h).DeleteTrustProduct(sid)
+}
+
+func (c restTrustHub) CreateTrustProductEntityAssignment(ctx context.Context, trustProductSid string, params *trusthub.CreateTrustProductEntityAssignmentParams) (*trusthub.TrusthubV1TrustProductEntityAssignment, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).CreateTrustProductEntityAssignment(trustProductSid, params)
+}
+
+func (c restTrustHub) ListTrustProductEntityAssignment(ctx context.Context, trustProductSid string, params *trusthub.ListTrustProductEntityAssignmentParams) ([]trusthub.TrusthubV1TrustProductEntityAssignment, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).ListTrustProductEntityAssignment(trustProductSid, params)
+}
+
+func (c restTrustHub) DeleteTrustProductEntityAssignment(ctx context.Context, trustProductSid string, sid string) error {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return err
+	}
+	return trusthub.NewApiService(h).DeleteTrustProductEntityAssignment(trustProductSid, sid)
+}
+
+func (c restTrustHub) CreateTrustProductEvaluation(ctx context.Context, trustProductSid string, params *trusthub.CreateTrustProductEvaluationParams) (*trusthub.TrusthubV1TrustProductEvaluation, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).CreateTrustProductEvaluation(trustProductSid, params)
+}
+
+func (c restTrustHub) FetchPolicies(ctx context.Context, sid string) (*trusthub.TrusthubV1Policies, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return trusthub.NewApiService(h).FetchPolicies(sid)
+}
+
+type restMessaging struct {
+	backend *restBackend
+}
+
+func (c restMessaging) CreateBrandRegistrations(ctx context.Context, params *messaging.CreateBrandRegistrationsParams) (*messaging.MessagingV1BrandRegistrations, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return messaging.NewApiService(h).CreateBrandRegistrations(params)
+}
+
+func (c restMessaging) FetchBrandRegistrations(ctx context.Context, sid string) (*messaging.MessagingV1BrandRegistrations, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return messaging.NewApiService(h).FetchBrandRegistrations(sid)
+}
+
+func (c restMessaging) ListBrandRegistrations(ctx context.Context, params *messaging.ListBrandRegistrationsParams) ([]messaging.MessagingV1BrandRegistrations, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return messaging.NewApiService(h).ListBrandRegistrations(params)
+}
+
+func (c restMessaging) CreateService(ctx context.Context, params *messaging.CreateServiceParams) (*messaging.MessagingV1Service, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return messaging.NewApiService(h).CreateService(params)
+}
+
+func (c restMessaging) ListService(ctx context.Context, params *messaging.ListServiceParams) ([]messaging.MessagingV1Service, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return messaging.NewApiService(h).ListService(params)
+}
+
+func (c restMessaging) UpdateService(ctx context.Context, sid string, params *messaging.UpdateServiceParams) (*messaging.MessagingV1Service, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return messaging.NewApiService(h).UpdateService(sid, params)
+}
+
+func (c restMessaging) DeleteService(ctx context.Context, sid string) error {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return err
+	}
+	return messaging.NewApiService(h).DeleteService(sid)
+}
+
+func (c restMessaging) CreatePhoneNumber(ctx context.Context, serviceSid string, params *messaging.CreatePhoneNumberParams) (*messaging.MessagingV1PhoneNumber, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return messaging.NewApiService(h).CreatePhoneNumber(serviceSid, params)
+}
+
+func (c restMessaging) ListPhoneNumber(ctx context.Context, serviceSid string, params *messaging.ListPhoneNumberParams) ([]messaging.MessagingV1PhoneNumber, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return messaging.NewApiService(h).ListPhoneNumber(serviceSid, params)
+}
+
+func (c restMessaging) FetchUsAppToPersonUsecase(ctx context.Context, messagingServiceSid string, params *messaging.FetchUsAppToPersonUsecaseParams) (*messaging.MessagingV1UsAppToPersonUsecase, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return messaging.NewApiService(h).FetchUsAppToPersonUsecase(messagingServiceSid, params)
+}
+
+func (c restMessaging) CreateUsAppToPerson(ctx context.Context, messagingServiceSid string, params *messaging.CreateUsAppToPersonParams) (*messaging.MessagingV1UsAppToPerson, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return messaging.NewApiService(h).CreateUsAppToPerson(messagingServiceSid, params)
+}
+
+func (c restMessaging) FetchUsAppToPerson(ctx context.Context, messagingServiceSid string, sid string) (*messaging.MessagingV1UsAppToPerson, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return messaging.NewApiService(h).FetchUsAppToPerson(messagingServiceSid, sid)
+}
+
+func (c restMessaging) ListUsAppToPerson(ctx context.Context, messagingServiceSid string, params *messaging.ListUsAppToPersonParams) ([]messaging.MessagingV1UsAppToPerson, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return messaging.NewApiService(h).ListUsAppToPerson(messagingServiceSid, params)
+}
+
+type restAccounts struct {
+	backend *restBackend
+}
+
+func (c restAccounts) CreateAccount(ctx context.Context, params *api.CreateAccountParams) (*api.ApiV2010Account, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return api.NewApiService(h).CreateAccount(params)
+}
+
+func (c restAccounts) ListAccount(ctx context.Context, params *api.ListAccountParams) ([]api.ApiV2010Account, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return api.NewApiService(h).ListAccount(params)
+}
+
+func (c restAccounts) UpdateAccount(ctx context.Context, sid string, params *api.UpdateAccountParams) (*api.ApiV2010Account, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return api.NewApiService(h).UpdateAccount(sid, params)
+}
+
+func (c restAccounts) CreateAddress(ctx context.Context, params *api.CreateAddressParams) (*api.ApiV2010Address, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return api.NewApiService(h).CreateAddress(params)
+}
+
+func (c restAccounts) ListAddress(ctx context.Context, params *api.ListAddressParams) ([]api.ApiV2010Address, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return api.NewApiService(h).ListAddress(params)
+}
+
+func (c restAccounts) DeleteAddress(ctx context.Context, sid string, params *api.DeleteAddressParams) error {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return err
+	}
+	return api.NewApiService(h).DeleteAddress(sid, params)
+}
+
+func (c restAccounts) ListIncomingPhoneNumber(ctx context.Context, params *api.ListIncomingPhoneNumberParams) ([]api.ApiV2010IncomingPhoneNumber, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return api.NewApiService(h).ListIncomingPhoneNumber(params)
+}
+
+func (c restAccounts) ListAvailablePhoneNumberLocal(ctx context.Context, countryCode string, params *api.ListAvailablePhoneNumberLocalParams) ([]api.ApiV2010AvailablePhoneNumberLocal, error) {
+	h, err := c.backend.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return api.NewApiService(h).ListAvailablePhoneNumberLocal(countryCode, params)
+}
+
+var (
+	_ TrustHubClient  = restTrustHub{}
+	_ MessagingClient = restMessaging{}
+	_ AccountsClient  = restAccounts{}
+)
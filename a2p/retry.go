@@ -0,0 +1,259 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/twilio/twilio-go/client"
+)
+
+// RetryError is returned when a Twilio call was retried, or when a failed
+// create was deliberately not retried. Err is the last failure; use errors.As
+// to get at it or at the underlying *client.TwilioRestError.
+type RetryError struct {
+	// Operation is the client method, for example "CreateEndUser".
+	Operation string
+	// Attempts is the number of requests sent, including the first.
+	Attempts int
+	// Unconfirmed is set when a create failed ambiguously (5xx or network
+	// error) and it could not be confirmed that nothing was created, so the
+	// create was not sent again.
+	Unconfirmed bool
+	Err         error
+}
+
+func (e *RetryError) Error() string {
+	if e.Unconfirmed {
+		return fmt.Sprintf("%s not retried, could not confirm that the failed attempt created nothing: %v", e.Operation, e.Err)
+	}
+	return fmt.Sprintf("%s failed after %d attempts: %v", e.Operation, e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+type retryKind int
+
+const (
+	// retryNever covers client errors, cancellation and anything unknown.
+	retryNever retryKind = iota
+	// retrySafe means Twilio rejected the request before acting on it (429).
+	retrySafe
+	// retryAmbiguous means the request may or may not have taken effect
+	// (5xx, network failure).
+	retryAmbiguous
+)
+
+// twilio-go wraps non-JSON error bodies, which is what proxies and load
+// balancers send on 502/503/504, without keeping the status code.
+var undecodedStatusPattern = regexp.MustCompile(`error decoding the response for an HTTP error code: (\d{3})`)
+
+func classifyRetry(err error) retryKind {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrInvalidBaseURL) {
+		return retryNever
+	}
+	var restErr *client.TwilioRestError
+	if errors.As(err, &restErr) {
+		return retryKindForStatus(restErr.Status)
+	}
+	if m := undecodedStatusPattern.FindStringSubmatch(err.Error()); m != nil {
+		status, _ := strconv.Atoi(m[1])
+		return retryKindForStatus(status)
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return retryAmbiguous
+	}
+	return retryNever
+}
+
+func retryKindForStatus(status int) retryKind {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return retrySafe
+	case status >= 500:
+		return retryAmbiguous
+	default:
+		return retryNever
+	}
+}
+
+// retrier applies a RetryPolicy to client calls.
+type retrier struct {
+	policy RetryPolicy
+	logger *slog.Logger
+}
+
+func newRetrier(policy RetryPolicy, logger *slog.Logger) *retrier {
+	return &retrier{policy: policy, logger: logger}
+}
+
+// createdSkew allows for clock differences with Twilio when deciding whether
+// a resource found by an idempotency lookup came from the failed attempt.
+const createdSkew = time.Minute
+
+// retryCall runs an idempotent call (fetch, list, update, evaluation),
+// retrying throttling, 5xx and network failures.
+func retryCall[T any](ctx context.Context, r *retrier, op string, call func(context.Context) (T, error)) (T, error) {
+	return retryDo(ctx, r, op, nil, call)
+}
+
+// retryDelete runs a delete like retryCall. A retried delete whose first
+// attempt went through fails with a 404, which callers should treat as done.
+func retryDelete(ctx context.Context, r *retrier, op string, call func(context.Context) error) error {
+	_, err := retryCall(ctx, r, op, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, call(ctx)
+	})
+	return err
+}
+
+// retryCreate runs a create. Throttled requests are always retried. After an
+// ambiguous failure lookup is asked whether the resource exists anyway: if it
+// does it is returned as the result, if it does not the create is retried,
+// and if the lookup fails the create is abandoned with an Unconfirmed
+// RetryError. lookup gets the earliest creation time that can belong to this
+// call.
+func retryCreate[T any](ctx context.Context, r *retrier, op string, lookup func(context.Context, time.Time) (*T, error), call func(context.Context) (*T, error)) (*T, error) {
+	found := func(ctx context.Context, since time.Time) (*T, bool, error) {
+		existing, err := lookup(ctx, since)
+		return existing, existing != nil, err
+	}
+	return retryDo(ctx, r, op, found, call)
+}
+
+func retryDo[T any](ctx context.Context, r *retrier, op string, lookup func(context.Context, time.Time) (T, bool, error), call func(context.Context) (T, error)) (T, error) {
+	var zero T
+	since := time.Now().Add(-createdSkew)
+	for attempt := 1; ; attempt++ {
+		if err := waitRateLimit(ctx); err != nil {
+			return zero, r.wrap(op, attempt, err)
+		}
+		recorder := &retryAfterRecorder{}
+		result, err := call(context.WithValue(ctx, retryAfterKey{}, recorder))
+		if err == nil {
+			return result, nil
+		}
+		err = describeError(err)
+
+		kind := classifyRetry(err)
+		if kind == retryNever || attempt >= r.policy.MaxAttempts {
+			return zero, r.wrap(op, attempt, err)
+		}
+
+		if kind == retryAmbiguous && lookup != nil {
+			existing, exists, lookupErr := lookup(ctx, since)
+			if lookupErr != nil {
+				r.logger.Warn("not retrying create", "operation", op, "attempt", attempt, "error", err, "lookup_error", lookupErr)
+				return zero, &RetryError{Operation: op, Attempts: attempt, Unconfirmed: true, Err: err}
+			}
+			if exists {
+				r.logger.Info("create succeeded despite error", "operation", op, "attempt", attempt, "error", err)
+				return existing, nil
+			}
+		}
+
+		delay := r.backoff(attempt, recorder.get())
+		r.logger.Warn("retrying Twilio request", "operation", op, "attempt", attempt, "delay", delay, "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return zero, &RetryError{Operation: op, Attempts: attempt, Err: fmt.Errorf("%w (last error: %v)", ctx.Err(), err)}
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *retrier) wrap(op string, attempts int, err error) error {
+	if attempts == 1 {
+		return err
+	}
+	r.logger.Error("Twilio request failed after retries", "operation", op, "attempts", attempts, "error", err)
+	return &RetryError{Operation: op, Attempts: attempts, Err: err}
+}
+
+// backoff returns InitialBackoff doubled per attempt and capped at
+// MaxBackoff, with jitter in [d/2, d]. A Retry-After from Twilio wins when it
+// is longer.
+func (r *retrier) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	d := r.policy.InitialBackoff
+	for i := 1; i < attempt && d < r.policy.MaxBackoff; i++ {
+		d *= 2
+	}
+	if r.policy.MaxBackoff > 0 && d > r.policy.MaxBackoff {
+		d = r.policy.MaxBackoff
+	}
+	if d > 0 {
+		d = d/2 + rand.N(d/2+1)
+	}
+	if retryAfter > d {
+		return retryAfter
+	}
+	return d
+}
+
+// retryAfterKey carries a *retryAfterRecorder through the request context so
+// contextTransport can hand back the Retry-After header, which twilio-go
+// otherwise discards.
+type retryAfterKey struct{}
+
+type retryAfterRecorder struct {
+	mu    sync.Mutex
+	delay time.Duration
+}
+
+func (r *retryAfterRecorder) set(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.delay = d
+}
+
+func (r *retryAfterRecorder) get() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.delay
+}
+
+func recordRetryAfter(ctx context.Context, resp *http.Response) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return
+	}
+	recorder, ok := ctx.Value(retryAfterKey{}).(*retryAfterRecorder)
+	if !ok {
+		return
+	}
+	if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		recorder.set(d)
+	}
+}
+
+// parseRetryAfter accepts both forms allowed by RFC 9110: delay seconds and
+// an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,348 @@
+package a2p
+
+import (
+	"context"
+	"time"
+
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+	messaging "github.com/twilio/twilio-go/rest/messaging/v1"
+	trusthub "github.com/twilio/twilio-go/rest/trusthub/v1"
+)
+
+// The retry decorators below wrap the client interfaces with a retrier.
+// Fetches, lists, updates, evaluations and deletes are safe to repeat. Every
+// create has a lookup, built on the find functions in idempotency.go, that
+// finds the resource a failed attempt may have created.
+
+type retryTrustHub struct {
+	next TrustHubClient
+	r    *retrier
+}
+
+func (c retryTrustHub) CreateCustomerProfile(ctx context.Context, params *trusthub.CreateCustomerProfileParams) (*trusthub.TrusthubV1CustomerProfile, error) {
+	lookup := func(ctx context.Context, since time.Time) (*trusthub.TrusthubV1CustomerProfile, error) {
+		return findCustomerProfile(ctx, c.next, params, since)
+	}
+	return retryCreate(ctx, c.r, "CreateCustomerProfile", lookup, func(ctx context.Context) (*trusthub.TrusthubV1CustomerProfile, error) {
+		return c.next.CreateCustomerProfile(ctx, params)
+	})
+}
+
+func (c retryTrustHub) ListCustomerProfile(ctx context.Context, params *trusthub.ListCustomerProfileParams) ([]trusthub.TrusthubV1CustomerProfile, error) {
+	return retryCall(ctx, c.r, "ListCustomerProfile", func(ctx context.Context) ([]trusthub.TrusthubV1CustomerProfile, error) {
+		return c.next.ListCustomerProfile(ctx, params)
+	})
+}
+
+func (c retryTrustHub) FetchCustomerProfile(ctx context.Context, sid string) (*trusthub.TrusthubV1CustomerProfile, error) {
+	return retryCall(ctx, c.r, "FetchCustomerProfile", func(ctx context.Context) (*trusthub.TrusthubV1CustomerProfile, error) {
+		return c.next.FetchCustomerProfile(ctx, sid)
+	})
+}
+
+func (c retryTrustHub) UpdateCustomerProfile(ctx context.Context, sid string, params *trusthub.UpdateCustomerProfileParams) (*trusthub.TrusthubV1CustomerProfile, error) {
+	return retryCall(ctx, c.r, "UpdateCustomerProfile", func(ctx context.Context) (*trusthub.TrusthubV1CustomerProfile, error) {
+		return c.next.UpdateCustomerProfile(ctx, sid, params)
+	})
+}
+
+func (c retryTrustHub) DeleteCustomerProfile(ctx context.Context, sid string) error {
+	return retryDelete(ctx, c.r, "DeleteCustomerProfile", func(ctx context.Context) error {
+		return c.next.DeleteCustomerProfile(ctx, sid)
+	})
+}
+
+func (c retryTrustHub) CreateCustomerProfileEntityAssignment(ctx context.Context, customerProfileSid string, params *trusthub.CreateCustomerProfileEntityAssignmentParams) (*trusthub.TrusthubV1CustomerProfileEntityAssignment, error) {
+	lookup := func(ctx context.Context, since time.Time) (*trusthub.TrusthubV1CustomerProfileEntityAssignment, error) {
+		return findCustomerProfileEntityAssignment(ctx, c.next, customerProfileSid, params)
+	}
+	return retryCreate(ctx, c.r, "CreateCustomerProfileEntityAssignment", lookup, func(ctx context.Context) (*trusthub.TrusthubV1CustomerProfileEntityAssignment, error) {
+		return c.next.CreateCustomerProfileEntityAssignment(ctx, customerProfileSid, params)
+	})
+}
+
+func (c retryTrustHub) ListCustomerProfileEntityAssignment(ctx context.Context, customerProfileSid string, params *trusthub.ListCustomerProfileEntityAssignmentParams) ([]trusthub.TrusthubV1CustomerProfileEntityAssignment, error) {
+	return retryCall(ctx, c.r, "ListCustomerProfileEntityAssignment", func(ctx context.Context) ([]trusthub.TrusthubV1CustomerProfileEntityAssignment, error) {
+		return c.next.ListCustomerProfileEntityAssignment(ctx, customerProfileSid, params)
+	})
+}
+
+func (c retryTrustHub) DeleteCustomerProfileEntityAssignment(ctx context.Context, customerProfileSid string, sid string) error {
+	return retryDelete(ctx, c.r, "DeleteCustomerProfileEntityAssignment", func(ctx context.Context) error {
+		return c.next.DeleteCustomerProfileEntityAssignment(ctx, customerProfileSid, sid)
+	})
+}
+
+func (c retryTrustHub) CreateCustomerProfileEvaluation(ctx context.Context, customerProfileSid string, params *trusthub.CreateCustomerProfileEvaluationParams) (*trusthub.TrusthubV1CustomerProfileEvaluation, error) {
+	return retryCall(ctx, c.r, "CreateCustomerProfileEvaluation", func(ctx context.Context) (*trusthub.TrusthubV1CustomerProfileEvaluation, error) {
+		return c.next.CreateCustomerProfileEvaluation(ctx, customerProfileSid, params)
+	})
+}
+
+func (c retryTrustHub) CreateEndUser(ctx context.Context, params *trusthub.CreateEndUserParams) (*trusthub.TrusthubV1EndUser, error) {
+	lookup := func(ctx context.Context, since time.Time) (*trusthub.TrusthubV1EndUser, error) {
+		return findEndUser(ctx, c.next, params, since)
+	}
+	return retryCreate(ctx, c.r, "CreateEndUser", lookup, func(ctx context.Context) (*trusthub.TrusthubV1EndUser, error) {
+		return c.next.CreateEndUser(ctx, params)
+	})
+}
+
+func (c retryTrustHub) ListEndUser(ctx context.Context, params *trusthub.ListEndUserParams) ([]trusthub.TrusthubV1EndUser, error) {
+	return retryCall(ctx, c.r, "ListEndUser", func(ctx context.Context) ([]trusthub.TrusthubV1EndUser, error) {
+		return c.next.ListEndUser(ctx, params)
+	})
+}
+
+func (c retryTrustHub) DeleteEndUser(ctx context.Context, sid string) error {
+	return retryDelete(ctx, c.r, "DeleteEndUser", func(ctx context.Context) error {
+		return c.next.DeleteEndUser(ctx, sid)
+	})
+}
+
+func (c retryTrustHub) CreateSupportingDocument(ctx context.Context, params *trusthub.CreateSupportingDocumentParams) (*trusthub.TrusthubV1SupportingDocument, error) {
+	lookup := func(ctx context.Context, since time.Time) (*trusthub.TrusthubV1SupportingDocument, error) {
+		return findSupportingDocument(ctx, c.next, params, since)
+	}
+	return retryCreate(ctx, c.r, "CreateSupportingDocument", lookup, func(ctx context.Context) (*trusthub.TrusthubV1SupportingDocument, error) {
+		return c.next.CreateSupportingDocument(ctx, params)
+	})
+}
+
+func (c retryTrustHub) ListSupportingDocument(ctx context.Context, params *trusthub.ListSupportingDocumentParams) ([]trusthub.TrusthubV1SupportingDocument, error) {
+	return retryCall(ctx, c.r, "ListSupportingDocument", func(ctx context.Context) ([]trusthub.TrusthubV1SupportingDocument, error) {
+		return c.next.ListSupportingDocument(ctx, params)
+	})
+}
+
+func (c retryTrustHub) DeleteSupportingDocument(ctx context.Context, sid string) error {
+	return retryDelete(ctx, c.r, "DeleteSupportingDocument", func(ctx context.Context) error {
+		return c.next.DeleteSupportingDocument(ctx, sid)
+	})
+}
+
+func (c retryTrustHub) CreateTrustProduct(ctx context.Context, params *trusthub.CreateTrustProductParams) (*trusthub.TrusthubV1TrustProduct, error) {
+	lookup := func(ctx context.Context, since time.Time) (*trusthub.TrusthubV1TrustProduct, error) {
+		return findTrustProduct(ctx, c.next, params, since)
+	}
+	return retryCreate(ctx, c.r, "CreateTrustProduct", lookup, func(ctx context.Context) (*trusthub.TrusthubV1TrustProduct, error) {
+		return c.next.CreateTrustProduct(ctx, params)
+	})
+}
+
+func (c retryTrustHub) ListTrustProduct(ctx context.Context, params *trusthub.ListTrustProductParams) ([]trusthub.TrusthubV1TrustProduct, error) {
+	return retryCall(ctx, c.r, "ListTrustProduct", func(ctx context.Context) ([]trusthub.TrusthubV1TrustProduct, error) {
+		return c.next.ListTrustProduct(ctx, params)
+	})
+}
+
+func (c retryTrustHub) FetchTrustProduct(ctx context.Context, sid string) (*trusthub.TrusthubV1TrustProduct, error) {
+	return retryCall(ctx, c.r, "FetchTrustProduct", func(ctx context.Context) (*trusthub.TrusthubV1TrustProduct, error) {
+		return c.next.FetchTrustProduct(ctx, sid)
+	})
+}
+
+func (c retryTrustHub) UpdateTrustProduct(ctx context.Context, sid string, params *trusthub.UpdateTrustProductParams) (*trusthub.TrusthubV1TrustProduct, error) {
+	return retryCall(ctx, c.r, "UpdateTrustProduct", func(ctx context.Context) (*trusthub.TrusthubV1TrustProduct, error) {
+		return c.next.UpdateTrustProduct(ctx, sid, params)
+	})
+}
+
+func (c retryTrustHub) DeleteTrustProduct(ctx context.Context, sid string) error {
+	return retryDelete(ctx, c.r, "DeleteTrustProduct", func(ctx context.Context) error {
+		return c.next.DeleteTrustProduct(ctx, sid)
+	})
+}
+
+func (c retryTrustHub) CreateTrustProductEntityAssignment(ctx context.Context, trustProductSid string, params *trusthub.CreateTrustProductEntityAssignmentParams) (*trusthub.TrusthubV1TrustProductEntityAssignment, error) {
+	lookup := func(ctx context.Context, since time.Time) (*trusthub.TrusthubV1TrustProductEntityAssignment, error) {
+		return findTrustProductEntityAssignment(ctx, c.next, trustProductSid, params)
+	}
+	return retryCreate(ctx, c.r, "CreateTrustProductEntityAssignment", lookup, func(ctx context.Context) (*trusthub.TrusthubV1TrustProductEntityAssignment, error) {
+		return c.next.CreateTrustProductEntityAssignment(ctx, trustProductSid, params)
+	})
+}
+
+func (c retryTrustHub) ListTrustProductEntityAssignment(ctx context.Context, trustProductSid string, params *trusthub.ListTrustProductEntityAssignmentParams) ([]trusthub.TrusthubV1TrustProductEntityAssignment, error) {
+	return retryCall(ctx, c.r, "ListTrustProductEntityAssignment", func(ctx context.Context) ([]trusthub.TrusthubV1TrustProductEntityAssignment, error) {
+		return c.next.ListTrustProductEntityAssignment(ctx, trustProductSid, params)
+	})
+}
+
+func (c retryTrustHub) DeleteTrustProductEntityAssignment(ctx context.Context, trustProductSid string, sid string) error {
+	return retryDelete(ctx, c.r, "DeleteTrustProductEntityAssignment", func(ctx context.Context) error {
+		return c.next.DeleteTrustProductEntityAssignment(ctx, trustProductSid, sid)
+	})
+}
+
+func (c retryTrustHub) CreateTrustProductEvaluation(ctx context.Context, trustProductSid string, params *trusthub.CreateTrustProductEvaluationParams) (*trusthub.TrusthubV1TrustProductEvaluation, error) {
+	return retryCall(ctx, c.r, "CreateTrustProductEvaluation", func(ctx context.Context) (*trusthub.TrusthubV1TrustProductEvaluation, error) {
+		return c.next.CreateTrustProductEvaluation(ctx, trustProductSid, params)
+	})
+}
+
+func (c retryTrustHub) FetchPolicies(ctx context.Context, sid string) (*trusthub.TrusthubV1Policies, error) {
+	return retryCall(ctx, c.r, "FetchPolicies", func(ctx context.Context) (*trusthub.TrusthubV1Policies, error) {
+		return c.next.FetchPolicies(ctx, sid)
+	})
+}
+
+type retryMessaging struct {
+	next MessagingClient
+	r    *retrier
+}
+
+func (c retryMessaging) CreateBrandRegistrations(ctx context.Context, params *messaging.CreateBrandRegistrationsParams) (*messaging.MessagingV1BrandRegistrations, error) {
+	lookup := func(ctx context.Context, since time.Time) (*messaging.MessagingV1BrandRegistrations, error) {
+		return findBrandRegistration(ctx, c.next, params)
+	}
+	return retryCreate(ctx, c.r, "CreateBrandRegistrations", lookup, func(ctx context.Context) (*messaging.MessagingV1BrandRegistrations, error) {
+		return c.next.CreateBrandRegistrations(ctx, params)
+	})
+}
+
+func (c retryMessaging) FetchBrandRegistrations(ctx context.Context, sid string) (*messaging.MessagingV1BrandRegistrations, error) {
+	return retryCall(ctx, c.r, "FetchBrandRegistrations", func(ctx context.Context) (*messaging.MessagingV1BrandRegistrations, error) {
+		return c.next.FetchBrandRegistrations(ctx, sid)
+	})
+}
+
+func (c retryMessaging) ListBrandRegistrations(ctx context.Context, params *messaging.ListBrandRegistrationsParams) ([]messaging.MessagingV1BrandRegistrations, error) {
+	return retryCall(ctx, c.r, "ListBrandRegistrations", func(ctx context.Context) ([]messaging.MessagingV1BrandRegistrations, error) {
+		return c.next.ListBrandRegistrations(ctx, params)
+	})
+}
+
+func (c retryMessaging) CreateService(ctx context.Context, params *messaging.CreateServiceParams) (*messaging.MessagingV1Service, error) {
+	lookup := func(ctx context.Context, since time.Time) (*messaging.MessagingV1Service, error) {
+		return findService(ctx, c.next, params, since)
+	}
+	return retryCreate(ctx, c.r, "CreateService", lookup, func(ctx context.Context) (*messaging.MessagingV1Service, error) {
+		return c.next.CreateService(ctx, params)
+	})
+}
+
+func (c retryMessaging) ListService(ctx context.Context, params *messaging.ListServiceParams) ([]messaging.MessagingV1Service, error) {
+	return retryCall(ctx, c.r, "ListService", func(ctx context.Context) ([]messaging.MessagingV1Service, error) {
+		return c.next.ListService(ctx, params)
+	})
+}
+
+func (c retryMessaging) UpdateService(ctx context.Context, sid string, params *messaging.UpdateServiceParams) (*messaging.MessagingV1Service, error) {
+	return retryCall(ctx, c.r, "UpdateService", func(ctx context.Context) (*messaging.MessagingV1Service, error) {
+		return c.next.UpdateService(ctx, sid, params)
+	})
+}
+
+func (c retryMessaging) DeleteService(ctx context.Context, sid string) error {
+	return retryDelete(ctx, c.r, "DeleteService", func(ctx context.Context) error {
+		return c.next.DeleteService(ctx, sid)
+	})
+}
+
+func (c retryMessaging) CreatePhoneNumber(ctx context.Context, serviceSid string, params *messaging.CreatePhoneNumberParams) (*messaging.MessagingV1PhoneNumber, error) {
+	lookup := func(ctx context.Context, since time.Time) (*messaging.MessagingV1PhoneNumber, error) {
+		return findPhoneNumber(ctx, c.next, serviceSid, params)
+	}
+	return retryCreate(ctx, c.r, "CreatePhoneNumber", lookup, func(ctx context.Context) (*messaging.MessagingV1PhoneNumber, error) {
+		return c.next.CreatePhoneNumber(ctx, serviceSid, params)
+	})
+}
+
+func (c retryMessaging) ListPhoneNumber(ctx context.Context, serviceSid string, params *messaging.ListPhoneNumberParams) ([]messaging.MessagingV1PhoneNumber, error) {
+	return retryCall(ctx, c.r, "ListPhoneNumber", func(ctx context.Context) ([]messaging.MessagingV1PhoneNumber, error) {
+		return c.next.ListPhoneNumber(ctx, serviceSid, params)
+	})
+}
+
+func (c retryMessaging) FetchUsAppToPersonUsecase(ctx context.Context, messagingServiceSid string, params *messaging.FetchUsAppToPersonUsecaseParams) (*messaging.MessagingV1UsAppToPersonUsecase, error) {
+	return retryCall(ctx, c.r, "FetchUsAppToPersonUsecase", func(ctx context.Context) (*messaging.MessagingV1UsAppToPersonUsecase, error) {
+		return c.next.FetchUsAppToPersonUsecase(ctx, messagingServiceSid, params)
+	})
+}
+
+func (c retryMessaging) CreateUsAppToPerson(ctx context.Context, messagingServiceSid string, params *messaging.CreateUsAppToPersonParams) (*messaging.MessagingV1UsAppToPerson, error) {
+	lookup := func(ctx context.Context, since time.Time) (*messaging.MessagingV1UsAppToPerson, error) {
+		return findUsAppToPerson(ctx, c.next, messagingServiceSid, params)
+	}
+	return retryCreate(ctx, c.r, "CreateUsAppToPerson", lookup, func(ctx context.Context) (*messaging.MessagingV1UsAppToPerson, error) {
+		return c.next.CreateUsAppToPerson(ctx, messagingServiceSid, params)
+	})
+}
+
+func (c retryMessaging) FetchUsAppToPerson(ctx context.Context, messagingServiceSid string, sid string) (*messaging.MessagingV1UsAppToPerson, error) {
+	return retryCall(ctx, c.r, "FetchUsAppToPerson", func(ctx context.Context) (*messaging.MessagingV1UsAppToPerson, error) {
+		return c.next.FetchUsAppToPerson(ctx, messagingServiceSid, sid)
+	})
+}
+
+func (c retryMessaging) ListUsAppToPerson(ctx context.Context, messagingServiceSid string, params *messaging.ListUsAppToPersonParams) ([]messaging.MessagingV1UsAppToPerson, error) {
+	return retryCall(ctx, c.r, "ListUsAppToPerson", func(ctx context.Context) ([]messaging.MessagingV1UsAppToPerson, error) {
+		return c.next.ListUsAppToPerson(ctx, messagingServiceSid, params)
+	})
+}
+
+type retryAccounts struct {
+	next AccountsClient
+	r    *retrier
+}
+
+func (c retryAccounts) CreateAccount(ctx context.Context, params *api.CreateAccountParams) (*api.ApiV2010Account, error) {
+	lookup := func(ctx context.Context, since time.Time) (*api.ApiV2010Account, error) {
+		return findAccount(ctx, c.next, params, since)
+	}
+	return retryCreate(ctx, c.r, "CreateAccount", lookup, func(ctx context.Context) (*api.ApiV2010Account, error) {
+		return c.next.CreateAccount(ctx, params)
+	})
+}
+
+func (c retryAccounts) ListAccount(ctx context.Context, params *api.ListAccountParams) ([]api.ApiV2010Account, error) {
+	return retryCall(ctx, c.r, "ListAccount", func(ctx context.Context) ([]api.ApiV2010Account, error) {
+		return c.next.ListAccount(ctx, params)
+	})
+}
+
+func (c retryAccounts) UpdateAccount(ctx context.Context, sid string, params *api.UpdateAccountParams) (*api.ApiV2010Account, error) {
+	return retryCall(ctx, c.r, "UpdateAccount", func(ctx context.Context) (*api.ApiV2010Account, error) {
+		return c.next.UpdateAccount(ctx, sid, params)
+	})
+}
+
+func (c retryAccounts) CreateAddress(ctx context.Context, params *api.CreateAddressParams) (*api.ApiV2010Address, error) {
+	lookup := func(ctx context.Context, since time.Time) (*api.ApiV2010Address, error) {
+		return findAddress(ctx, c.next, params, since)
+	}
+	return retryCreate(ctx, c.r, "CreateAddress", lookup, func(ctx context.Context) (*api.ApiV2010Address, error) {
+		return c.next.CreateAddress(ctx, params)
+	})
+}
+
+func (c retryAccounts) ListAddress(ctx context.Context, params *api.ListAddressParams) ([]api.ApiV2010Address, error) {
+	return retryCall(ctx, c.r, "ListAddress", func(ctx context.Context) ([]api.ApiV2010Address, error) {
+		return c.next.ListAddress(ctx, params)
+	})
+}
+
+func (c retryAccounts) DeleteAddress(ctx context.Context, sid string, params *api.DeleteAddressParams) error {
+	return retryDelete(ctx, c.r, "DeleteAddress", func(ctx context.Context) error {
+		return c.next.DeleteAddress(ctx, sid, params)
+	})
+}
+
+func (c retryAccounts) ListIncomingPhoneNumber(ctx context.Context, params *api.ListIncomingPhoneNumberParams) ([]api.ApiV2010IncomingPhoneNumber, error) {
+	return retryCall(ctx, c.r, "ListIncomingPhoneNumber", func(ctx context.Context) ([]api.ApiV2010IncomingPhoneNumber, error) {
+		return c.next.ListIncomingPhoneNumber(ctx, params)
+	})
+}
+
+func (c retryAccounts) ListAvailablePhoneNumberLocal(ctx context.Context, countryCode string, params *api.ListAvailablePhoneNumberLocalParams) ([]api.ApiV2010AvailablePhoneNumberLocal, error) {
+	return retryCall(ctx, c.r, "ListAvailablePhoneNumberLocal", func(ctx context.Context) ([]api.ApiV2010AvailablePhoneNumberLocal, error) {
+		return c.next.ListAvailablePhoneNumberLocal(ctx, countryCode, params)
+	})
+}
+
+var (
+	_ TrustHubClient  = retryTrustHub{}
+	_ MessagingClient = retryMessaging{}
+	_ AccountsClient  = retryAccounts{}
+)
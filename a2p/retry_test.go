@@ -0,0 +1,179 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/twilio/twilio-go/client"
+)
+
+func TestClassifyRetry(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want retryKind
+	}{
+		{"throttled", &client.TwilioRestError{Status: 429, Code: 20429}, retrySafe},
+		{"server error", &client.TwilioRestError{Status: 500, Code: 20500}, retryAmbiguous},
+		{"unavailable", &client.TwilioRestError{Status: 503}, retryAmbiguous},
+		{"bad request", &client.TwilioRestError{Status: 400, Code: 21211}, retryNever},
+		{"not found", &client.TwilioRestError{Status: 404, Code: 20404}, retryNever},
+		{"wrapped", fmt.Errorf("failed to create end user: %w", &client.TwilioRestError{Status: 429}), retrySafe},
+		{"undecoded gateway error", errors.New("error decoding the response for an HTTP error code: 502: invalid character '<'"), retryAmbiguous},
+		{"undecoded client error", errors.New("error decoding the response for an HTTP error code: 401: EOF"), retryNever},
+		{"network", &url.Error{Op: "Post", URL: "https://trusthub.twilio.com/v1/EndUsers", Err: errors.New("connection reset")}, retryAmbiguous},
+		{"cancelled", fmt.Errorf("request: %w", context.Canceled), retryNever},
+		{"deadline", &url.Error{Op: "Post", URL: "https://trusthub.twilio.com", Err: context.DeadlineExceeded}, retryNever},
+		{"invalid base URL", &url.Error{Op: "Post", URL: "localhost:8080/v1/EndUsers", Err: ErrInvalidBaseURL}, retryNever},
+		{"unknown", errors.New("boom"), retryNever},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyRetry(tt.err); got != tt.want {
+				t.Errorf("classifyRetry(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := newRetrier(RetryPolicy{MaxAttempts: 10, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}, discardLogger())
+
+	tests := []struct {
+		name       string
+		attempt    int
+		retryAfter time.Duration
+		min, max   time.Duration
+	}{
+		{name: "first retry", attempt: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{name: "doubles", attempt: 3, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{name: "capped", attempt: 8, min: 500 * time.Millisecond, max: time.Second},
+		{name: "longer Retry-After wins", attempt: 1, retryAfter: 5 * time.Second, min: 5 * time.Second, max: 5 * time.Second},
+		{name: "shorter Retry-After ignored", attempt: 3, retryAfter: time.Millisecond, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				if d := r.backoff(tt.attempt, tt.retryAfter); d < tt.min || d > tt.max {
+					t.Fatalf("backoff(%d, %v) = %v, want between %v and %v", tt.attempt, tt.retryAfter, d, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"7", 7 * time.Second, true},
+		{"-1", 0, false},
+		{"Wed, 01 May 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 01 May 2024 11:59:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %t; want %v, %t", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRetryCreate(t *testing.T) {
+	throttled := &client.TwilioRestError{Status: 429, Code: 20429, Message: "Too Many Requests"}
+	unavailable := &client.TwilioRestError{Status: 503, Code: 20503, Message: "Service Unavailable"}
+	invalid := &client.TwilioRestError{Status: 400, Code: 21211, Message: "Invalid phone number"}
+	existing := "IT-existing"
+
+	tests := []struct {
+		name string
+		// errs are the results of the create calls in order; calls past the
+		// end succeed.
+		errs      []error
+		lookup    func() (*string, error)
+		want      string
+		wantCalls int
+		// wantAttempts is the RetryError.Attempts expected, 0 for no
+		// RetryError.
+		wantAttempts    int
+		wantUnconfirmed bool
+		wantErr         error
+	}{
+		{name: "success", want: "IT-created", wantCalls: 1},
+		{name: "throttled then created", errs: []error{throttled, throttled}, want: "IT-created", wantCalls: 3},
+		{name: "throttled until the last attempt", errs: []error{throttled, throttled, throttled}, wantCalls: 3, wantAttempts: 3, wantErr: throttled},
+		{name: "client error is not retried", errs: []error{invalid}, wantCalls: 1, wantErr: invalid},
+		{
+			name: "ambiguous failure that created the resource", errs: []error{unavailable},
+			lookup: func() (*string, error) { return &existing, nil },
+			want:   existing, wantCalls: 1,
+		},
+		{
+			name: "ambiguous failure that created nothing", errs: []error{unavailable},
+			lookup: func() (*string, error) { return nil, nil },
+			want:   "IT-created", wantCalls: 2,
+		},
+		{
+			name: "lookup failure abandons the create", errs: []error{unavailable},
+			lookup:    func() (*string, error) { return nil, errors.New("lookup failed") },
+			wantCalls: 1, wantAttempts: 1, wantUnconfirmed: true, wantErr: unavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRetrier(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}, discardLogger())
+			lookup := func(context.Context, time.Time) (*string, error) { return nil, nil }
+			if tt.lookup != nil {
+				lookup = func(context.Context, time.Time) (*string, error) { return tt.lookup() }
+			}
+			calls := 0
+			got, err := retryCreate(context.Background(), r, "CreateEndUser", lookup, func(context.Context) (*string, error) {
+				calls++
+				if calls <= len(tt.errs) {
+					return nil, tt.errs[calls-1]
+				}
+				sid := "IT-created"
+				return &sid, nil
+			})
+
+			if calls != tt.wantCalls {
+				t.Errorf("%d create calls, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("retryCreate: %v", err)
+				}
+				if deref(got) != tt.want {
+					t.Errorf("retryCreate = %q, want %q", deref(got), tt.want)
+				}
+				return
+			}
+			var restErr *client.TwilioRestError
+			if !errors.As(err, &restErr) || restErr != tt.wantErr {
+				t.Errorf("retryCreate error = %v, want %v", err, tt.wantErr)
+			}
+			var retryErr *RetryError
+			if tt.wantAttempts == 0 {
+				if errors.As(err, &retryErr) {
+					t.Errorf("unexpected RetryError: %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &retryErr) {
+				t.Fatalf("retryCreate error %v is not a RetryError", err)
+			}
+			if retryErr.Attempts != tt.wantAttempts || retryErr.Unconfirmed != tt.wantUnconfirmed {
+				t.Errorf("RetryError attempts %d, unconfirmed %t; want %d, %t", retryErr.Attempts, retryErr.Unconfirmed, tt.wantAttempts, tt.wantUnconfirmed)
+			}
+		})
+	}
+}
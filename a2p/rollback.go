@@ -0,0 +1,124 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/twilio/twilio-go/client"
+)
+
+// errBrandRegistrationPermanent is returned by the compensation of stage 4.1:
+// Twilio has no API to delete brand registrations.
+var errBrandRegistrationPermanent = errors.New("brand registrations cannot be deleted")
+
+// rollbackTimeout bounds the cleanup of a failed run. The cleanup runs even
+// when the onboarding context was cancelled.
+const rollbackTimeout = 2 * time.Minute
+
+// RollbackReport lists the resources a failed run created, in the order the
+// rollback handled them.
+type RollbackReport struct {
+	Removed []RollbackResource `json:"removed,omitempty"`
+	// Remaining could not be cleaned up and still exist in Twilio.
+	Remaining []RollbackResource `json:"remaining,omitempty"`
+}
+
+// Complete reports whether everything the run created was cleaned up.
+func (r *RollbackReport) Complete() bool {
+	return len(r.Remaining) == 0
+}
+
+// RollbackResource is one resource handled by a rollback.
+type RollbackResource struct {
+	Stage string `json:"stage"`
+	// Resource is the kind of resource, for example "end_user" or
+	// "customer_profile_entity_assignment".
+	Resource string `json:"resource"`
+	SID      string `json:"sid"`
+	Error    string `json:"error,omitempty"`
+}
+
+// undo compensates the steps completed by this run in reverse order, so a
+// step is handled before the steps it depends on. Compensated steps, and
+// steps with nothing to undo such as evaluations, are removed from the
+// checkpoint; steps whose resource remains are kept so a resumed run does not
+// create it twice.
+func (r *onboardingRun) undo(ctx context.Context) *RollbackReport {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+
+	checkpoint := r.state.checkpoint
+	report := &RollbackReport{}
+	// A brand registration cannot be removed and still references the
+	// bundles, so the steps it depends on are kept. Steps it does not depend
+	// on, such as the messaging service, are removed even when they finished
+	// before it.
+	keep := map[string]error{}
+	for i := len(r.ran) - 1; i >= 0; i-- {
+		step := r.ran[i]
+		stage := step.Name()
+		sid := checkpoint.Stages[stage]
+		_, _, resource, compensates := stepInfo(step)
+		if !compensates {
+			delete(checkpoint.Stages, stage)
+			continue
+		}
+
+		err, kept := keep[stage]
+		if !kept {
+			err = step.Compensate(ctx, r.state, sid)
+		}
+		if errors.Is(err, errBrandRegistrationPermanent) {
+			reason := fmt.Errorf("kept for brand registration %s", sid)
+			for _, dep := range r.dependencies(stage) {
+				keep[dep] = reason
+			}
+		}
+
+		item := RollbackResource{Stage: stage, Resource: resource, SID: sid}
+		if err != nil && !isNotFound(err) {
+			item.Error = err.Error()
+			report.Remaining = append(report.Remaining, item)
+			r.log.Error("onboarding rollback failed", "stage", stage, "resource", resource, "sid", sid, "error", err)
+			continue
+		}
+		delete(checkpoint.Stages, stage)
+		report.Removed = append(report.Removed, item)
+		r.log.Info("onboarding rollback removed resource", "stage", stage, "resource", resource, "sid", sid)
+	}
+	r.ran = nil
+	return report
+}
+
+// dependencies returns the names of the steps the named step waits for,
+// directly or through other steps.
+func (r *onboardingRun) dependencies(name string) []string {
+	var names []string
+	seen := make([]bool, len(r.steps))
+	var visit func(i int)
+	visit = func(i int) {
+		for _, j := range r.deps[i] {
+			if !seen[j] {
+				seen[j] = true
+				names = append(names, r.steps[j].Name())
+				visit(j)
+			}
+		}
+	}
+	for i, step := range r.steps {
+		if step.Name() == name {
+			visit(i)
+		}
+	}
+	return names
+}
+
+// isNotFound reports a 404 from Twilio, which a rollback treats as already
+// removed.
+func isNotFound(err error) bool {
+	var restErr *client.TwilioRestError
+	return errors.As(err, &restErr) && restErr.Status == http.StatusNotFound
+}
@@ -0,0 +1,183 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/twilio/twilio-go/client"
+)
+
+func TestOnboardCustomerRollback(t *testing.T) {
+	errCreate := &client.TwilioRestError{Status: 400, Code: 21211, Message: "rejected"}
+	errDelete := &client.TwilioRestError{Status: 403, Code: 20403, Message: "delete failed"}
+
+	tests := []struct {
+		name string
+		// failOn and failDelete name the fake methods that fail: the create
+		// that stops the run and a delete that fails during the rollback.
+		failOn     string
+		failDelete string
+		// rerun runs the onboarding once without rollback first, so the
+		// run under test reuses the resources of the earlier one.
+		rerun     bool
+		wantStage string
+		// wantRemaining is the kind of every resource the rollback could
+		// not remove, "" when it removes everything.
+		wantRemaining string
+	}{
+		{name: "trust product", failOn: "CreateTrustProduct", wantStage: "3.1"},
+		{name: "brand registration", failOn: "CreateBrandRegistrations", wantStage: "4.1"},
+		{name: "delete fails", failOn: "CreateCustomerProfileEvaluation", failDelete: "DeleteEndUser", wantStage: "2.9", wantRemaining: "end_user"},
+		{name: "reused resources are kept", failOn: "CreateCustomerProfileEvaluation", rerun: true, wantStage: "2.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			trustHub, messagingFake, accounts := NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts()
+			accounts.AddIncomingPhoneNumber(testParams().TwilioPurchasedPhoneNumber)
+			for _, fake := range []interface{ FailOn(string, error) }{trustHub, messagingFake, accounts} {
+				fake.FailOn(tt.failOn, errCreate)
+				if tt.failDelete != "" {
+					fake.FailOn(tt.failDelete, errDelete)
+				}
+			}
+
+			// existing holds the SIDs of resources an earlier run created.
+			existing := map[string]bool{}
+			if tt.rerun {
+				s := NewA2PService(trustHub, messagingFake, accounts, WithLogger(discardLogger()))
+				if _, err := s.OnboardCustomer(ctx, testParams()); err == nil {
+					t.Fatal("first OnboardCustomer succeeded")
+				}
+				for _, sid := range resourceSIDs(trustHub, messagingFake, accounts) {
+					existing[sid] = true
+				}
+				if len(existing) == 0 {
+					t.Fatal("first OnboardCustomer created nothing")
+				}
+			}
+
+			s := NewA2PService(trustHub, messagingFake, accounts, WithLogger(discardLogger()), WithRollback())
+			_, err := s.OnboardCustomer(ctx, testParams())
+			var stageErr *StageError
+			if !errors.As(err, &stageErr) {
+				t.Fatalf("OnboardCustomer error = %v, want a StageError", err)
+			}
+			if stageErr.Stage != tt.wantStage {
+				t.Errorf("failed stage = %s, want %s", stageErr.Stage, tt.wantStage)
+			}
+			report := stageErr.Rollback
+			if report == nil {
+				t.Fatal("no rollback report")
+			}
+
+			for _, resource := range report.Remaining {
+				if resource.Resource != tt.wantRemaining {
+					t.Errorf("remaining %s %s, want only %q", resource.Resource, resource.SID, tt.wantRemaining)
+				}
+			}
+			if report.Complete() != (tt.wantRemaining == "") {
+				t.Errorf("Complete() = %t with %d remaining", report.Complete(), len(report.Remaining))
+			}
+
+			left := resourceSIDs(trustHub, messagingFake, accounts)
+			if want := len(existing) + len(report.Remaining); len(left) != want {
+				t.Errorf("resources left = %v, want %d", left, want)
+			}
+			for _, resource := range report.Removed {
+				if existing[resource.SID] {
+					t.Errorf("rollback removed %s %s, which an earlier run created", resource.Resource, resource.SID)
+				}
+			}
+			for sid := range existing {
+				found := false
+				for _, l := range left {
+					found = found || l == sid
+				}
+				if !found {
+					t.Errorf("%s of an earlier run was removed", sid)
+				}
+			}
+		})
+	}
+}
+
+// resourceSIDs lists the standalone resources onboarding creates in the fakes.
+func resourceSIDs(trustHub *FakeTrustHub, messagingFake *FakeMessaging, accounts *FakeAccounts) []string {
+	var sids []string
+	for sid := range trustHub.CustomerProfiles {
+		sids = append(sids, sid)
+	}
+	for sid := range trustHub.EndUsers {
+		sids = append(sids, sid)
+	}
+	for sid := range trustHub.SupportingDocuments {
+		sids = append(sids, sid)
+	}
+	for sid := range trustHub.TrustProducts {
+		sids = append(sids, sid)
+	}
+	for sid := range messagingFake.Services {
+		sids = append(sids, sid)
+	}
+	for sid := range accounts.Addresses {
+		sids = append(sids, sid)
+	}
+	return sids
+}
+
+func TestOnboardCustomerRollbackKeepsBrandDependencies(t *testing.T) {
+	ctx := context.Background()
+	trustHub, messagingFake, accounts := NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts()
+	accounts.AddIncomingPhoneNumber(testParams().TwilioPurchasedPhoneNumber)
+	// The failing step runs after the brand registration, which the
+	// rollback cannot remove; the messaging service, created concurrently
+	// long before the brand, does not depend on it.
+	errNotify := errors.New("notify failed")
+	pipeline := DefaultPipeline()
+	err := pipeline.Append(&FuncStep{
+		ID:        "notify",
+		DependsOn: []string{"4.1"},
+		RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+			return "", errNotify
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewA2PService(trustHub, messagingFake, accounts, WithLogger(discardLogger()), WithPipeline(pipeline), WithConcurrency(len(pipeline.Steps())), WithRollback())
+
+	_, err = s.OnboardCustomer(ctx, testParams())
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) || stageErr.Stage != "notify" {
+		t.Fatalf("OnboardCustomer error = %v, want a StageError for notify", err)
+	}
+	report := stageErr.Rollback
+	if report == nil {
+		t.Fatal("no rollback report")
+	}
+
+	removed := map[string]bool{}
+	for _, resource := range report.Removed {
+		removed[resource.Stage] = true
+	}
+	if !removed["5.1"] || len(messagingFake.Services) != 0 {
+		t.Errorf("messaging service not removed: removed %v, %d services left", report.Removed, len(messagingFake.Services))
+	}
+	remaining := map[string]bool{}
+	for _, resource := range report.Remaining {
+		remaining[resource.Stage] = true
+	}
+	for _, stage := range []string{"2.1", "2.2", "2.3", "2.4", "2.5", "2.6", "2.7", "2.8", "3.1", "3.2", "3.3", "3.4", "4.1"} {
+		if !remaining[stage] {
+			t.Errorf("stage %s removed, but the brand registration depends on it", stage)
+		}
+	}
+	if len(trustHub.CustomerProfiles) != 1 || len(trustHub.TrustProducts) != 1 {
+		t.Errorf("%d customer profiles and %d trust products left, want the brand's bundles", len(trustHub.CustomerProfiles), len(trustHub.TrustProducts))
+	}
+	if stageErr.Checkpoint.Completed("5.1") || !stageErr.Checkpoint.Completed("4.1") {
+		t.Errorf("checkpoint stages %v, want 4.1 kept and 5.1 removed", stageErr.Checkpoint.Stages)
+	}
+}
@@ -0,0 +1,341 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var (
+	// ErrStoreRequired is returned when a feature needs the service to have a
+	// Store, see WithStore.
+	ErrStoreRequired = errors.New("a2p service has no store")
+	// ErrSchedulerParamsRequired is returned by NewStatusScheduler when
+	// SchedulerOptions.Params is not set, since records do not hold the
+	// subaccount credentials.
+	ErrSchedulerParamsRequired = errors.New("scheduler params are required")
+)
+
+// MonitorOutcome is how the tracking of a brand registration ended.
+type MonitorOutcome string
+
+const (
+	// MonitorCompleted means the brand was approved and CompleteOnboarding
+	// succeeded.
+	MonitorCompleted MonitorOutcome = "completed"
+	// MonitorBrandFailed means Twilio rejected or deleted the brand.
+	MonitorBrandFailed MonitorOutcome = "brand_failed"
+	// MonitorTimedOut means the brand was still pending, or its onboarding
+	// still failing to complete, after SchedulerOptions.Timeout.
+	MonitorTimedOut MonitorOutcome = "timed_out"
+)
+
+// BrandMonitor is the tracking state of one brand registration. It is saved
+// in OnboardingRecord.Monitor, so tracking survives restarts.
+type BrandMonitor struct {
+	BrandRegistrationSID string `json:"brand_registration_sid"`
+	// Status is the last brand registration status fetched from Twilio.
+	Status string `json:"status,omitempty"`
+	// Checks counts the polls made so far.
+	Checks    int       `json:"checks"`
+	StartedAt time.Time `json:"started_at"`
+	LastCheck time.Time `json:"last_check,omitempty"`
+	NextCheck time.Time `json:"next_check"`
+	// Interval is the current wait between polls.
+	Interval  time.Duration `json:"interval"`
+	LastError string        `json:"last_error,omitempty"`
+	// Outcome is empty while the brand is tracked.
+	Outcome MonitorOutcome `json:"outcome,omitempty"`
+}
+
+// BrandStatus is what StatusScheduler.Status reports for a tracked brand.
+type BrandStatus struct {
+	LocationID   string          `json:"location_id"`
+	SubaccountID string          `json:"subaccount_id"`
+	State        OnboardingState `json:"state"`
+	BrandMonitor
+}
+
+// SchedulerOptions configures a StatusScheduler. Zero fields other than Params
+// take the defaults in DefaultSchedulerOptions.
+type SchedulerOptions struct {
+	// MinInterval is the wait before the first poll and after a status
+	// change; every poll without a change multiplies it by Multiplier, up
+	// to MaxInterval. Failed polls back off the same way.
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	Multiplier  float64
+	// Timeout stops tracking a brand still pending, or still failing to
+	// complete, this long after tracking started.
+	Timeout time.Duration
+	// Params returns what CompleteOnboarding needs for a record, including
+	// the subaccount credentials, which records do not hold. It is required.
+	// A failure is recorded in the record's BrandMonitor and retried with
+	// backoff.
+	Params func(ctx context.Context, record *OnboardingRecord) (*FullA2POnboardingParams, error)
+}
+
+// DefaultSchedulerOptions are used for zero SchedulerOptions fields.
+var DefaultSchedulerOptions = SchedulerOptions{
+	MinInterval: 5 * time.Minute,
+	MaxInterval: time.Hour,
+	Multiplier:  2,
+	Timeout:     48 * time.Hour,
+}
+
+// StatusScheduler tracks every brand registration submitted by
+// OnboardCustomer, as found in the service's Store, and calls
+// CompleteOnboarding once the brand is approved. Its state lives in the
+// store, so a restarted scheduler carries on where the last one stopped.
+// It replaces MonitorBrandRegistration.
+type StatusScheduler struct {
+	service *A2PService
+	opts    SchedulerOptions
+	now     func() time.Time
+}
+
+// NewStatusScheduler returns a scheduler for the brands in the store of s. It
+// fails with ErrStoreRequired when s has none, and with
+// ErrSchedulerParamsRequired without opts.Params.
+func (s *A2PService) NewStatusScheduler(opts SchedulerOptions) (*StatusScheduler, error) {
+	if s.store == nil {
+		return nil, ErrStoreRequired
+	}
+	if opts.Params == nil {
+		return nil, ErrSchedulerParamsRequired
+	}
+	if opts.MinInterval <= 0 {
+		opts.MinInterval = DefaultSchedulerOptions.MinInterval
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = DefaultSchedulerOptions.MaxInterval
+	}
+	if opts.MaxInterval < opts.MinInterval {
+		opts.MaxInterval = opts.MinInterval
+	}
+	if opts.Multiplier < 1 {
+		opts.Multiplier = DefaultSchedulerOptions.Multiplier
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultSchedulerOptions.Timeout
+	}
+	return &StatusScheduler{service: s, opts: opts, now: time.Now}, nil
+}
+
+// Run polls every MinInterval until ctx is done.
+func (sc *StatusScheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(sc.opts.MinInterval)
+	defer ticker.Stop()
+	for {
+		if err := sc.Poll(ctx); err != nil {
+			sc.service.logger.Error("brand registration poll failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll checks every tracked brand that is due, for callers that schedule
+// polls themselves. Failures of single brands are recorded in their
+// BrandMonitor and do not stop the others; the error is about reading the
+// store.
+func (sc *StatusScheduler) Poll(ctx context.Context) error {
+	records, err := sc.service.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if tracked(record) {
+			sc.check(ctx, record)
+		}
+	}
+	return nil
+}
+
+// Status returns every brand the scheduler tracks or has tracked.
+func (sc *StatusScheduler) Status(ctx context.Context) ([]BrandStatus, error) {
+	records, err := sc.service.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []BrandStatus
+	for _, record := range records {
+		if record.Monitor == nil || record.Response == nil {
+			continue
+		}
+		statuses = append(statuses, BrandStatus{
+			LocationID:   record.LocationID,
+			SubaccountID: record.SubaccountID,
+			State:        record.Response.State,
+			BrandMonitor: *record.Monitor,
+		})
+	}
+	return statuses, nil
+}
+
+// tracked reports whether the record has a brand awaiting review, or an
+// approved brand whose CompleteOnboarding has not succeeded yet.
+func tracked(record *OnboardingRecord) bool {
+	if record.Response == nil || record.Response.BrandRegistrationSID == "" {
+		return false
+	}
+	if record.Monitor != nil && record.Monitor.Outcome != "" {
+		return false
+	}
+	switch record.Response.State {
+	case StateBrandPending, StateBrandApproved, StateNumberAttached:
+		return true
+	}
+	return false
+}
+
+func (sc *StatusScheduler) check(ctx context.Context, record *OnboardingRecord) {
+	s := sc.service
+	now := sc.now().UTC()
+	monitor := record.Monitor
+	if monitor == nil {
+		monitor = &BrandMonitor{
+			BrandRegistrationSID: record.Response.BrandRegistrationSID,
+			StartedAt:            now,
+			NextCheck:            now,
+			Interval:             sc.opts.MinInterval,
+		}
+	}
+	if now.Before(monitor.NextCheck) {
+		return
+	}
+	log := s.logger.With("location_id", record.LocationID, "brand_registration_sid", monitor.BrandRegistrationSID)
+
+	params, err := sc.opts.Params(ctx, record)
+	if err != nil {
+		log.Error("brand registration check failed", "error", err)
+		sc.failed(monitor, now, fmt.Errorf("failed to get onboarding params: %w", err))
+		sc.timeout(log, monitor, now)
+		sc.save(ctx, log, record, monitor, "")
+		return
+	}
+
+	state := record.Response.State
+	if state == StateBrandPending {
+		state, err = sc.fetch(ctx, params, monitor, now)
+		if err != nil {
+			log.Error("brand registration check failed", "operation", "FetchBrandRegistration", "error", err)
+			sc.failed(monitor, now, err)
+			sc.timeout(log, monitor, now)
+			sc.save(ctx, log, record, monitor, "")
+			return
+		}
+		log.Info("brand registration checked", "operation", "FetchBrandRegistration", "brand_registration_status", monitor.Status)
+	}
+
+	switch state {
+	case StateBrandPending:
+		sc.timeout(log, monitor, now)
+		sc.save(ctx, log, record, monitor, "")
+	case StateBrandFailed:
+		monitor.Outcome = MonitorBrandFailed
+		sc.save(ctx, log, record, monitor, StateBrandFailed)
+	default:
+		// Record the approval first, so a crash during CompleteOnboarding
+		// retries the completion rather than the poll.
+		approved := StateBrandApproved
+		if record.Response.State != StateBrandPending {
+			approved = ""
+		}
+		sc.save(ctx, log, record, monitor, approved)
+		if _, err := s.CompleteOnboarding(ctx, params, monitor.BrandRegistrationSID); err != nil {
+			log.Error("completing onboarding failed", "error", err)
+			sc.failed(monitor, now, err)
+			sc.timeout(log, monitor, now)
+		} else {
+			monitor.Outcome = MonitorCompleted
+		}
+		sc.save(ctx, log, record, monitor, "")
+	}
+}
+
+// fetch polls the brand registration and adapts the interval: it goes back
+// to MinInterval when the status changed and grows otherwise.
+func (sc *StatusScheduler) fetch(ctx context.Context, params *FullA2POnboardingParams, monitor *BrandMonitor, now time.Time) (OnboardingState, error) {
+	sub, err := sc.service.subaccountFor(params)
+	if err != nil {
+		return "", err
+	}
+	status, err := sub.FetchBrandRegistration(ctx, monitor.BrandRegistrationSID)
+	if err != nil {
+		return "", err
+	}
+	state, err := brandRegistrationState(status)
+	if err != nil {
+		return "", err
+	}
+
+	if status != monitor.Status {
+		monitor.Interval = sc.opts.MinInterval
+	} else {
+		monitor.Interval = sc.grow(monitor.Interval)
+	}
+	monitor.Status = status
+	monitor.Checks++
+	monitor.LastCheck = now
+	monitor.LastError = ""
+	monitor.NextCheck = now.Add(monitor.Interval)
+	return state, nil
+}
+
+// timeout ends the tracking of a brand that has been pending, or failing to
+// complete, for longer than Timeout.
+func (sc *StatusScheduler) timeout(log *slog.Logger, monitor *BrandMonitor, now time.Time) {
+	if now.Sub(monitor.StartedAt) >= sc.opts.Timeout {
+		log.Warn("brand registration check timed out")
+		monitor.Outcome = MonitorTimedOut
+	}
+}
+
+// failed records err and backs off.
+func (sc *StatusScheduler) failed(monitor *BrandMonitor, now time.Time, err error) {
+	monitor.Checks++
+	monitor.LastCheck = now
+	monitor.LastError = err.Error()
+	monitor.Interval = sc.grow(monitor.Interval)
+	monitor.NextCheck = now.Add(monitor.Interval)
+}
+
+func (sc *StatusScheduler) grow(interval time.Duration) time.Duration {
+	next := time.Duration(float64(interval) * sc.opts.Multiplier)
+	if next < sc.opts.MinInterval {
+		next = sc.opts.MinInterval
+	}
+	if next > sc.opts.MaxInterval {
+		next = sc.opts.MaxInterval
+	}
+	return next
+}
+
+// save writes the monitor and the last status, and moves the response to
+// state unless it is empty, on top of whatever CompleteOnboarding stored
+// meanwhile.
+func (sc *StatusScheduler) save(ctx context.Context, log *slog.Logger, record *OnboardingRecord, monitor *BrandMonitor, state OnboardingState) {
+	params := &FullA2POnboardingParams{LocationID: record.LocationID, SubaccountID: record.SubaccountID}
+	sc.service.updateRecord(ctx, log, params, func(stored *OnboardingRecord) {
+		copied := *monitor
+		stored.Monitor = &copied
+		if stored.Response == nil {
+			return
+		}
+		if monitor.Status != "" {
+			stored.Response.BrandRegistrationStatus = monitor.Status
+		}
+		if state != "" {
+			stored.Response.advanceTo(log, state)
+		}
+	})
+}
@@ -0,0 +1,266 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/twilio/twilio-go/client"
+)
+
+// schedulerFixture is a service with a store holding one onboarding whose
+// brand registration awaits review, and a clock the scheduler reads.
+type schedulerFixture struct {
+	service   *A2PService
+	store     *FileStore
+	messaging *FakeMessaging
+	brandSID  string
+	now       time.Time
+}
+
+func newSchedulerFixture(t *testing.T) *schedulerFixture {
+	t.Helper()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	trustHub, messagingFake, accounts := NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts()
+	accounts.AddIncomingPhoneNumber(testParams().TwilioPurchasedPhoneNumber)
+	s := NewA2PService(trustHub, messagingFake, accounts, WithLogger(discardLogger()), WithStore(store))
+	resp, err := s.OnboardCustomer(context.Background(), testParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schedulerFixture{
+		service:   s,
+		store:     store,
+		messaging: messagingFake,
+		brandSID:  resp.Data.BrandRegistrationSID,
+		now:       time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+// scheduler returns a scheduler reading the fixture's clock, with params
+// returning testParams unless opts has its own.
+func (f *schedulerFixture) scheduler(t *testing.T, opts SchedulerOptions) *StatusScheduler {
+	t.Helper()
+	if opts.Params == nil {
+		opts.Params = func(ctx context.Context, record *OnboardingRecord) (*FullA2POnboardingParams, error) {
+			return testParams(), nil
+		}
+	}
+	sc, err := f.service.NewStatusScheduler(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.now = func() time.Time { return f.now }
+	return sc
+}
+
+// poll polls at the fixture's clock and returns the stored record.
+func (f *schedulerFixture) poll(t *testing.T, sc *StatusScheduler) *OnboardingRecord {
+	t.Helper()
+	if err := sc.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	record, err := f.store.Load(context.Background(), "location-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Monitor == nil {
+		t.Fatal("no brand monitor stored")
+	}
+	return record
+}
+
+func TestNewStatusScheduler(t *testing.T) {
+	params := func(ctx context.Context, record *OnboardingRecord) (*FullA2POnboardingParams, error) {
+		return testParams(), nil
+	}
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		opts    []Option
+		params  func(ctx context.Context, record *OnboardingRecord) (*FullA2POnboardingParams, error)
+		wantErr error
+	}{
+		{name: "store and params", opts: []Option{WithStore(store)}, params: params},
+		{name: "no store", params: params, wantErr: ErrStoreRequired},
+		{name: "no params", opts: []Option{WithStore(store)}, wantErr: ErrSchedulerParamsRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewA2PService(NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts(), tt.opts...)
+			if _, err := s.NewStatusScheduler(SchedulerOptions{Params: tt.params}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewStatusScheduler error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatusSchedulerAdaptiveInterval(t *testing.T) {
+	f := newSchedulerFixture(t)
+	sc := f.scheduler(t, SchedulerOptions{MinInterval: time.Minute, MaxInterval: 4 * time.Minute, Multiplier: 2})
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		status  string
+		// wantChecks and wantInterval are the monitor after the poll.
+		wantChecks   int
+		wantInterval time.Duration
+	}{
+		{name: "first poll", wantChecks: 1, wantInterval: time.Minute},
+		{name: "not due", advance: 30 * time.Second, wantChecks: 1, wantInterval: time.Minute},
+		{name: "unchanged", advance: 30 * time.Second, wantChecks: 2, wantInterval: 2 * time.Minute},
+		{name: "unchanged again", advance: 2 * time.Minute, wantChecks: 3, wantInterval: 4 * time.Minute},
+		{name: "capped", advance: 4 * time.Minute, wantChecks: 4, wantInterval: 4 * time.Minute},
+		{name: "changed", advance: 4 * time.Minute, status: "IN_REVIEW", wantChecks: 5, wantInterval: time.Minute},
+	}
+	for _, step := range steps {
+		f.now = f.now.Add(step.advance)
+		if step.status != "" {
+			if err := f.messaging.SetBrandStatus(f.brandSID, step.status); err != nil {
+				t.Fatal(err)
+			}
+		}
+		monitor := f.poll(t, sc).Monitor
+		if monitor.Checks != step.wantChecks || monitor.Interval != step.wantInterval {
+			t.Errorf("%s: %d checks every %v, want %d every %v", step.name, monitor.Checks, monitor.Interval, step.wantChecks, step.wantInterval)
+		}
+		if want := monitor.LastCheck.Add(monitor.Interval); !monitor.NextCheck.Equal(want) {
+			t.Errorf("%s: next check %v, want %v", step.name, monitor.NextCheck, want)
+		}
+	}
+}
+
+func TestStatusSchedulerRestartsFromStore(t *testing.T) {
+	f := newSchedulerFixture(t)
+	opts := SchedulerOptions{MinInterval: time.Minute}
+	first := f.poll(t, f.scheduler(t, opts)).Monitor
+
+	// A new scheduler, as after a restart, waits for the stored next check.
+	restarted := f.scheduler(t, opts)
+	f.now = f.now.Add(30 * time.Second)
+	if monitor := f.poll(t, restarted).Monitor; monitor.Checks != first.Checks {
+		t.Errorf("restarted scheduler polled before the stored next check: %d checks", monitor.Checks)
+	}
+	f.now = f.now.Add(time.Minute)
+	monitor := f.poll(t, restarted).Monitor
+	if monitor.Checks != first.Checks+1 {
+		t.Errorf("%d checks after the stored next check, want %d", monitor.Checks, first.Checks+1)
+	}
+	if !monitor.StartedAt.Equal(first.StartedAt) {
+		t.Errorf("tracking restarted at %v, want %v", monitor.StartedAt, first.StartedAt)
+	}
+	statuses, err := restarted.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].BrandRegistrationSID != f.brandSID || statuses[0].State != StateBrandPending {
+		t.Errorf("Status = %+v, want the pending brand %s", statuses, f.brandSID)
+	}
+}
+
+func TestStatusSchedulerOutcomes(t *testing.T) {
+	errParams := errors.New("credentials unavailable")
+	tests := []struct {
+		name   string
+		status string
+		// failOn makes a fake call fail on every attempt.
+		failOn string
+		params func(ctx context.Context, record *OnboardingRecord) (*FullA2POnboardingParams, error)
+		// advance is the time between tracking starting and the poll.
+		advance     time.Duration
+		wantOutcome MonitorOutcome
+		wantState   OnboardingState
+		wantError   bool
+	}{
+		{name: "approved", status: "APPROVED", wantOutcome: MonitorCompleted, wantState: StateCampaignPending},
+		{name: "failed", status: "FAILED", wantOutcome: MonitorBrandFailed, wantState: StateBrandFailed},
+		{name: "pending", wantState: StateBrandPending},
+		{name: "pending too long", advance: 3 * time.Hour, wantOutcome: MonitorTimedOut, wantState: StateBrandPending},
+		{
+			name: "completion fails", status: "APPROVED", failOn: "CreateUsAppToPerson",
+			wantState: StateNumberAttached, wantError: true,
+		},
+		{
+			name: "completion fails too long", status: "APPROVED", failOn: "CreateUsAppToPerson", advance: 3 * time.Hour,
+			wantOutcome: MonitorTimedOut, wantState: StateNumberAttached, wantError: true,
+		},
+		{
+			name: "params fail",
+			params: func(ctx context.Context, record *OnboardingRecord) (*FullA2POnboardingParams, error) {
+				return nil, errParams
+			},
+			wantState: StateBrandPending, wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSchedulerFixture(t)
+			sc := f.scheduler(t, SchedulerOptions{MinInterval: time.Minute, Timeout: 2 * time.Hour, Params: tt.params})
+			// Tracking starts with a pending brand.
+			f.poll(t, sc)
+			if tt.status != "" {
+				if err := f.messaging.SetBrandStatus(f.brandSID, tt.status); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.failOn != "" {
+				f.messaging.FailOn(tt.failOn, &client.TwilioRestError{Status: 400, Code: 21211, Message: "rejected"})
+			}
+			f.now = f.now.Add(time.Minute + tt.advance)
+
+			record := f.poll(t, sc)
+			if record.Monitor.Outcome != tt.wantOutcome {
+				t.Errorf("outcome = %q, want %q", record.Monitor.Outcome, tt.wantOutcome)
+			}
+			if record.Response.State != tt.wantState {
+				t.Errorf("state = %s, want %s", record.Response.State, tt.wantState)
+			}
+			if (record.Monitor.LastError != "") != tt.wantError {
+				t.Errorf("last error = %q, want an error %t", record.Monitor.LastError, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestStatusSchedulerPollsEveryRecord(t *testing.T) {
+	f := newSchedulerFixture(t)
+	ctx := context.Background()
+	// A second record whose params cannot be had does not stop the first.
+	err := f.store.Save(ctx, &OnboardingRecord{
+		LocationID:   "location-0",
+		SubaccountID: "AC2",
+		Response:     &A2POnboardingResponse{LocationID: "location-0", BrandRegistrationSID: "BN404", State: StateBrandPending},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.messaging.SetBrandStatus(f.brandSID, "APPROVED"); err != nil {
+		t.Fatal(err)
+	}
+	sc := f.scheduler(t, SchedulerOptions{Params: func(ctx context.Context, record *OnboardingRecord) (*FullA2POnboardingParams, error) {
+		if record.LocationID == "location-0" {
+			return nil, errors.New("unknown location")
+		}
+		return testParams(), nil
+	}})
+
+	record := f.poll(t, sc)
+	if record.Monitor.Outcome != MonitorCompleted {
+		t.Errorf("outcome = %q, want %q", record.Monitor.Outcome, MonitorCompleted)
+	}
+	failed, err := f.store.Load(ctx, "location-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.Monitor == nil || failed.Monitor.LastError == "" || failed.Monitor.Outcome != "" {
+		t.Errorf("failing record monitor = %+v, want the error recorded and tracking kept", failed.Monitor)
+	}
+}
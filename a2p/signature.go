@@ -0,0 +1,310 @@
+package a2p
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+var (
+	// ErrInvalidSignature is returned when a webhook request is not signed by
+	// Twilio.
+	ErrInvalidSignature = errors.New("invalid Twilio request signature")
+	// ErrUnknownAccount is returned by an AuthTokenSource that has no auth
+	// token for the account.
+	ErrUnknownAccount = errors.New("unknown Twilio account")
+)
+
+// AuthTokenSource returns the auth token Twilio signs the webhooks of an
+// account with.
+type AuthTokenSource interface {
+	AuthToken(ctx context.Context, accountSid string) (string, error)
+}
+
+// StaticAuthTokens maps account SIDs to their auth tokens.
+type StaticAuthTokens map[string]string
+
+func (t StaticAuthTokens) AuthToken(ctx context.Context, accountSid string) (string, error) {
+	token, ok := t[accountSid]
+	if !ok || token == "" {
+		return "", fmt.Errorf("%w: %s", ErrUnknownAccount, accountSid)
+	}
+	return token, nil
+}
+
+// authTokenRefresh limits how often accountAuthTokens lists the accounts
+// again, so requests naming made-up accounts cannot flood the Twilio API.
+const authTokenRefresh = time.Minute
+
+// AccountAuthTokens returns an AuthTokenSource for the service's account and
+// its subaccounts. The auth tokens are listed from the Accounts API and
+// cached; an unknown account, or a signature the cached token does not
+// match after a token rotation, lists them again at most once a minute.
+func (s *A2PService) AccountAuthTokens() AuthTokenSource {
+	return &accountAuthTokens{accounts: s.accounts}
+}
+
+type accountAuthTokens struct {
+	accounts AccountsClient
+
+	mu     sync.Mutex
+	tokens map[string]string
+	listed time.Time
+}
+
+func (t *accountAuthTokens) AuthToken(ctx context.Context, accountSid string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if token, ok := t.tokens[accountSid]; ok {
+		return token, nil
+	}
+	if time.Since(t.listed) < authTokenRefresh {
+		return "", fmt.Errorf("%w: %s", ErrUnknownAccount, accountSid)
+	}
+	if err := t.list(ctx); err != nil {
+		return "", err
+	}
+
+	token, ok := t.tokens[accountSid]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownAccount, accountSid)
+	}
+	return token, nil
+}
+
+// refreshAuthToken returns the token of accountSid once stale failed to
+// validate a signature, listing the accounts again unless they were listed
+// within authTokenRefresh. The returned token equals stale when nothing
+// changed.
+func (t *accountAuthTokens) refreshAuthToken(ctx context.Context, accountSid, stale string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if token, ok := t.tokens[accountSid]; ok && token != stale {
+		return token, nil
+	}
+	if time.Since(t.listed) < authTokenRefresh {
+		return stale, nil
+	}
+	if err := t.list(ctx); err != nil {
+		return "", err
+	}
+
+	token, ok := t.tokens[accountSid]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownAccount, accountSid)
+	}
+	return token, nil
+}
+
+// list replaces the cached tokens, with t.mu held.
+func (t *accountAuthTokens) list(ctx context.Context) error {
+	accounts, err := t.accounts.ListAccount(ctx, &api.ListAccountParams{})
+	if err != nil {
+		return fmt.Errorf("failed to list accounts: %w", err)
+	}
+	t.tokens = make(map[string]string, len(accounts))
+	for _, account := range accounts {
+		if account.Sid != nil && account.AuthToken != nil {
+			t.tokens[*account.Sid] = *account.AuthToken
+		}
+	}
+	t.listed = time.Now()
+	return nil
+}
+
+// authTokenRefresher is implemented by AuthTokenSources whose tokens can go
+// stale, such as the cache of AccountAuthTokens.
+type authTokenRefresher interface {
+	refreshAuthToken(ctx context.Context, accountSid, stale string) (string, error)
+}
+
+// SignatureValidator checks the X-Twilio-Signature header of webhook
+// requests. Form posts are signed over the URL followed by the sorted POST
+// parameters; JSON posts are signed over the URL, whose bodySHA256 query
+// parameter is the hex SHA-256 of the body. The auth token is picked by the
+// AccountSid parameter of the request.
+type SignatureValidator struct {
+	AuthTokens AuthTokenSource
+	// BaseURL is the public scheme, host and optional path prefix Twilio
+	// calls, such as "https://hooks.example.com/twilio", for servers behind a
+	// reverse proxy that rewrites the URL. The request path and query are
+	// appended to it.
+	BaseURL string
+	// TrustForwardedHeaders takes the scheme and host from the
+	// X-Forwarded-Proto and X-Forwarded-Host headers set by a reverse proxy.
+	// It is ignored when BaseURL is set.
+	TrustForwardedHeaders bool
+	Logger                *slog.Logger
+}
+
+// NewSignatureValidator returns a SignatureValidator that looks auth tokens
+// up in tokens.
+func NewSignatureValidator(tokens AuthTokenSource) *SignatureValidator {
+	return &SignatureValidator{AuthTokens: tokens, Logger: slog.Default()}
+}
+
+// SignatureValidator returns a SignatureValidator for webhooks sent to the
+// service's account and its subaccounts, see AccountAuthTokens.
+func (s *A2PService) SignatureValidator() *SignatureValidator {
+	v := NewSignatureValidator(s.AccountAuthTokens())
+	v.Logger = s.logger
+	return v
+}
+
+// Middleware passes requests with a valid signature on to next and rejects
+// the others with 403 Forbidden.
+func (v *SignatureValidator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.Validate(r); err != nil {
+			v.logger().Warn("rejected webhook request", "path", r.URL.Path, "error", err)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Validate checks the signature of r. Form bodies are parsed into r.PostForm
+// and JSON bodies are restored, so handlers can still read them.
+func (v *SignatureValidator) Validate(r *http.Request) error {
+	signature := r.Header.Get("X-Twilio-Signature")
+	if signature == "" {
+		return fmt.Errorf("%w: missing X-Twilio-Signature header", ErrInvalidSignature)
+	}
+
+	var payload strings.Builder
+	accountSid := r.URL.Query().Get("AccountSid")
+	if bodyHash := r.URL.Query().Get("bodySHA256"); bodyHash != "" {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read webhook body: %w", err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		if !hmac.Equal([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(bodyHash))) {
+			return fmt.Errorf("%w: body does not match bodySHA256", ErrInvalidSignature)
+		}
+		if accountSid == "" {
+			var fields struct{ AccountSid string }
+			_ = json.Unmarshal(body, &fields)
+			accountSid = fields.AccountSid
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return fmt.Errorf("failed to parse webhook form: %w", err)
+		}
+		keys := make([]string, 0, len(r.PostForm))
+		for key := range r.PostForm {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, value := range r.PostForm[key] {
+				payload.WriteString(key)
+				payload.WriteString(value)
+			}
+		}
+		if sid := r.PostForm.Get("AccountSid"); sid != "" {
+			accountSid = sid
+		}
+	}
+	if accountSid == "" {
+		return fmt.Errorf("%w: request has no AccountSid", ErrInvalidSignature)
+	}
+
+	token, err := v.AuthTokens.AuthToken(r.Context(), accountSid)
+	if err != nil {
+		return fmt.Errorf("failed to get auth token for %s: %w", accountSid, err)
+	}
+	if v.signed(r, token, payload.String(), signature) {
+		return nil
+	}
+	// The token may have been rotated since it was cached.
+	if refresher, ok := v.AuthTokens.(authTokenRefresher); ok {
+		fresh, err := refresher.refreshAuthToken(r.Context(), accountSid, token)
+		if err != nil {
+			return fmt.Errorf("failed to get auth token for %s: %w", accountSid, err)
+		}
+		if fresh != token && v.signed(r, fresh, payload.String(), signature) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// signed reports whether signature signs payload, sent to one of the signed
+// URLs of r, with token.
+func (v *SignatureValidator) signed(r *http.Request, token, payload, signature string) bool {
+	for _, u := range v.signedURLs(r) {
+		mac := hmac.New(sha1.New, []byte(token))
+		mac.Write([]byte(u + payload))
+		expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		if hmac.Equal([]byte(expected), []byte(signature)) {
+			return true
+		}
+	}
+	return false
+}
+
+// signedURLs returns the URL Twilio called, as given to it in the webhook
+// configuration. Twilio may or may not have included the default port, so
+// both forms are returned.
+func (v *SignatureValidator) signedURLs(r *http.Request) []string {
+	scheme, host, prefix := "http", r.Host, ""
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if v.BaseURL != "" {
+		if base, err := url.Parse(v.BaseURL); err == nil {
+			scheme, host, prefix = base.Scheme, base.Host, strings.TrimSuffix(base.Path, "/")
+		}
+	} else if v.TrustForwardedHeaders {
+		if proto := firstHeaderValue(r.Header.Get("X-Forwarded-Proto")); proto != "" {
+			scheme = proto
+		}
+		if forwarded := firstHeaderValue(r.Header.Get("X-Forwarded-Host")); forwarded != "" {
+			host = forwarded
+		}
+	}
+
+	rest := prefix + r.URL.RequestURI()
+	urls := []string{scheme + "://" + host + rest}
+	defaultPort := map[string]string{"http": "80", "https": "443"}[scheme]
+	switch {
+	case defaultPort == "":
+	case strings.HasSuffix(host, ":"+defaultPort):
+		urls = append(urls, scheme+"://"+strings.TrimSuffix(host, ":"+defaultPort)+rest)
+	case !strings.Contains(strings.TrimPrefix(host, "["), ":") || strings.HasSuffix(host, "]"):
+		urls = append(urls, scheme+"://"+host+":"+defaultPort+rest)
+	}
+	return urls
+}
+
+func firstHeaderValue(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
+}
+
+func (v *SignatureValidator) logger() *slog.Logger {
+	if v.Logger != nil {
+		return v.Logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,226 @@
+package a2p
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/twilio/twilio-go/client"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+const testAuthToken = "12345678901234567890123456789012"
+
+// sign computes X-Twilio-Signature for a request Twilio sends to rawURL with
+// the form params.
+func sign(t *testing.T, token, rawURL string, params url.Values) string {
+	t.Helper()
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	payload := rawURL
+	flat := map[string]string{}
+	for _, key := range keys {
+		for _, value := range params[key] {
+			payload += key + value
+			flat[key] = value
+		}
+	}
+	mac := hmac.New(sha1.New, []byte(token))
+	mac.Write([]byte(payload))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	// Check the helper against the twilio-go validator.
+	validator := client.NewRequestValidator(token)
+	if !validator.Validate(rawURL, flat, signature) {
+		t.Fatalf("test signature for %s does not validate with twilio-go", rawURL)
+	}
+	return signature
+}
+
+func bodyHash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSignatureValidator(t *testing.T) {
+	form := url.Values{"AccountSid": {testSubaccountSID}, "From": {"+15125550100"}, "Body": {"STOP"}, "MessageSid": {"SM1"}}
+	jsonBody := `{"AccountSid":"` + testSubaccountSID + `","BundleSid":"BU1","Status":"twilio-approved"}`
+	jsonURL := "https://hooks.example.com/status?bodySHA256=" + bodyHash(jsonBody)
+
+	tests := []struct {
+		name string
+		// target is the URL the server sees; signedURL the one Twilio
+		// called.
+		target, signedURL string
+		body              string
+		json              bool
+		// signedForm is signed instead of the body form, to tamper with it.
+		signedForm url.Values
+		token      string
+		noHeader   bool
+		header     http.Header
+		configure  func(v *SignatureValidator)
+		wantErr    error
+	}{
+		{name: "form", target: "https://hooks.example.com/inbound", signedURL: "https://hooks.example.com/inbound"},
+		{name: "form with query", target: "https://hooks.example.com/inbound?tenant=1", signedURL: "https://hooks.example.com/inbound?tenant=1"},
+		{name: "default port added by Twilio", target: "https://hooks.example.com/inbound", signedURL: "https://hooks.example.com:443/inbound"},
+		{name: "default port removed by Twilio", target: "https://hooks.example.com:443/inbound", signedURL: "https://hooks.example.com/inbound"},
+		{name: "wrong token", target: "https://hooks.example.com/inbound", signedURL: "https://hooks.example.com/inbound", token: "00000000000000000000000000000000", wantErr: ErrInvalidSignature},
+		{name: "missing header", target: "https://hooks.example.com/inbound", signedURL: "https://hooks.example.com/inbound", noHeader: true, wantErr: ErrInvalidSignature},
+		{
+			name: "tampered form", target: "https://hooks.example.com/inbound", signedURL: "https://hooks.example.com/inbound",
+			signedForm: url.Values{"AccountSid": {testSubaccountSID}, "From": {"+15125550100"}, "Body": {"START"}, "MessageSid": {"SM1"}},
+			wantErr:    ErrInvalidSignature,
+		},
+		{name: "json", target: jsonURL, signedURL: jsonURL, body: jsonBody, json: true},
+		{name: "tampered json", target: jsonURL, signedURL: jsonURL, body: strings.Replace(jsonBody, "approved", "rejected", 1), json: true, wantErr: ErrInvalidSignature},
+		{
+			name: "proxy base URL", target: "http://10.0.0.5:8080/inbound", signedURL: "https://hooks.example.com/twilio/inbound",
+			configure: func(v *SignatureValidator) { v.BaseURL = "https://hooks.example.com/twilio/" },
+		},
+		{
+			name: "forwarded headers", target: "http://10.0.0.5:8080/inbound", signedURL: "https://hooks.example.com/inbound",
+			header:    http.Header{"X-Forwarded-Proto": {"https"}, "X-Forwarded-Host": {"hooks.example.com, 10.0.0.1"}},
+			configure: func(v *SignatureValidator) { v.TrustForwardedHeaders = true },
+		},
+		{
+			name: "untrusted forwarded headers", target: "http://10.0.0.5:8080/inbound", signedURL: "https://hooks.example.com/inbound",
+			header:  http.Header{"X-Forwarded-Proto": {"https"}, "X-Forwarded-Host": {"hooks.example.com"}},
+			wantErr: ErrInvalidSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.token
+			if token == "" {
+				token = testAuthToken
+			}
+			body, signed := form.Encode(), form
+			if tt.signedForm != nil {
+				signed = tt.signedForm
+			}
+			if tt.json {
+				body, signed = tt.body, nil
+			}
+			r := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.json {
+				r.Header.Set("Content-Type", "application/json")
+			}
+			for key, values := range tt.header {
+				r.Header[key] = values
+			}
+			if !tt.noHeader {
+				r.Header.Set("X-Twilio-Signature", sign(t, token, tt.signedURL, signed))
+			}
+
+			v := NewSignatureValidator(StaticAuthTokens{testSubaccountSID: testAuthToken})
+			v.Logger = discardLogger()
+			if tt.configure != nil {
+				tt.configure(v)
+			}
+			err := v.Validate(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.json && err == nil {
+				// The body is restored for the handler.
+				restored, _ := io.ReadAll(r.Body)
+				if string(restored) != tt.body {
+					t.Errorf("body after Validate = %q, want %q", restored, tt.body)
+				}
+			}
+		})
+	}
+}
+
+func TestSignatureMiddleware(t *testing.T) {
+	v := NewSignatureValidator(StaticAuthTokens{testSubaccountSID: testAuthToken})
+	v.Logger = discardLogger()
+	handler := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	form := url.Values{"AccountSid": {testSubaccountSID}, "Body": {"HELP"}}
+
+	tests := []struct {
+		name       string
+		account    string
+		signature  func() string
+		wantStatus int
+	}{
+		{"valid", testSubaccountSID, func() string { return sign(t, testAuthToken, "http://example.com/inbound", form) }, http.StatusNoContent},
+		{"invalid", testSubaccountSID, func() string { return "bm90IGEgc2lnbmF0dXJl" }, http.StatusForbidden},
+		{"unknown account", "AC404", func() string { return sign(t, testAuthToken, "http://example.com/inbound", form) }, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := url.Values{"AccountSid": {tt.account}, "Body": {"HELP"}}
+			r := httptest.NewRequest(http.MethodPost, "http://example.com/inbound", strings.NewReader(body.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.Header.Set("X-Twilio-Signature", tt.signature())
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAccountAuthTokensRotation(t *testing.T) {
+	ctx := context.Background()
+	accounts := NewFakeAccounts()
+	account, err := accounts.CreateAccount(ctx, &api.CreateAccountParams{FriendlyName: ptr("Acme")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sid := *account.Sid
+	s := NewA2PService(NewFakeTrustHub(), NewFakeMessaging(), accounts, WithLogger(discardLogger()))
+	v := s.SignatureValidator()
+	tokens := v.AuthTokens.(*accountAuthTokens)
+
+	validate := func(token string) error {
+		form := url.Values{"AccountSid": {sid}, "Body": {"hi"}}
+		r := httptest.NewRequest(http.MethodPost, "http://example.com/inbound", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("X-Twilio-Signature", sign(t, token, "http://example.com/inbound", form))
+		return v.Validate(r)
+	}
+
+	original := *accounts.Accounts[sid].AuthToken
+	if err := validate(original); err != nil {
+		t.Fatalf("original token: %v", err)
+	}
+	rotated := "abcdefabcdefabcdefabcdefabcdef12"
+	accounts.Accounts[sid] = &api.ApiV2010Account{Sid: ptr(sid), AuthToken: ptr(rotated)}
+
+	// The accounts were listed just now, so the rotation is not seen yet.
+	if err := validate(rotated); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("rotated token within a minute of listing: %v, want ErrInvalidSignature", err)
+	}
+	tokens.mu.Lock()
+	tokens.listed = time.Now().Add(-2 * authTokenRefresh)
+	tokens.mu.Unlock()
+	if err := validate(rotated); err != nil {
+		t.Errorf("rotated token after the refresh interval: %v", err)
+	}
+	if err := validate(original); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("original token after the rotation: %v, want ErrInvalidSignature", err)
+	}
+}
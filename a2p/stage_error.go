@@ -0,0 +1,97 @@
+package a2p
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// StageError is returned by OnboardCustomer and CompleteOnboarding when a
+// stage fails. Use errors.As to get at it:
+//
+//	var stageErr *a2p.StageError
+//	if errors.As(err, &stageErr) && stageErr.Retryable {
+//		// try again later
+//	}
+type StageError struct {
+	// Stage is the onboarding stage ID, for example "2.3".
+	Stage string `json:"stage"`
+	// Operation is the A2PService method that failed.
+	Operation string `json:"operation"`
+	// Code is the Twilio error code, or 0 when the failure did not come from
+	// the Twilio API.
+	Code int `json:"code,omitempty"`
+	// Status is the HTTP status of the failed request, or 0.
+	Status int `json:"status,omitempty"`
+	// Retryable reports whether running the onboarding again may succeed
+	// without changing its input: throttling, Twilio outages and network
+	// failures.
+	Retryable bool `json:"retryable"`
+	// Info is the error catalog entry for the failure, when it came from
+	// the Twilio API.
+	Info *ErrorInfo `json:"info,omitempty"`
+	// Reasons lists the failed requirements of a noncompliant evaluation,
+	// see EvaluationError.
+	Reasons []ErrorInfo `json:"reasons,omitempty"`
+	// CreatedSIDs holds the resources created before the failure, keyed like
+	// the log attributes (customer_profile_sid, trust_product_sid, ...).
+	CreatedSIDs map[string]string `json:"created_sids,omitempty"`
+	// Checkpoint is set for OnboardCustomer failures; pass it to
+	// ResumeOnboarding to continue from Stage.
+	Checkpoint *OnboardingCheckpoint `json:"checkpoint,omitempty"`
+	// Rollback reports the cleanup of the failed run when WithRollback is
+	// set.
+	Rollback *RollbackReport `json:"rollback,omitempty"`
+	Err      error           `json:"-"`
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("error at stage %s (%s): %v", e.Stage, e.Operation, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+func newStageError(stage, operation string, err error, created map[string]string) *StageError {
+	stageErr := &StageError{
+		Stage:       stage,
+		Operation:   operation,
+		Retryable:   isRetryable(err),
+		CreatedSIDs: make(map[string]string, len(created)),
+		Err:         err,
+	}
+	for key, sid := range created {
+		stageErr.CreatedSIDs[key] = sid
+	}
+
+	var evalErr *EvaluationError
+	var apiErr *APIError
+	if errors.As(err, &evalErr) {
+		info := noncompliantInfo
+		stageErr.Info = &info
+		stageErr.Reasons = evalErr.Reasons
+	} else if errors.As(err, &apiErr) {
+		info := apiErr.Info
+		stageErr.Info = &info
+		stageErr.Code = info.Code
+		stageErr.Status = apiErr.Status
+	}
+	return stageErr
+}
+
+// isRetryable treats a create whose outcome is unknown as not retryable: it
+// may have gone through and a blind retry would duplicate it.
+func isRetryable(err error) bool {
+	var retryErr *RetryError
+	if errors.As(err, &retryErr) && retryErr.Unconfirmed {
+		return false
+	}
+	return classifyRetry(err) != retryNever
+}
+
+// stageFailed logs a failed stage and returns it as a *StageError.
+func stageFailed(log *slog.Logger, stage, operation string, err error, created map[string]string) *StageError {
+	logStage(log, stage, operation, err)
+	return newStageError(stage, operation, err, created)
+}
@@ -0,0 +1,263 @@
+package a2p
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ErrInvalidTransition is returned when an onboarding cannot move from its
+// state to the requested one.
+var ErrInvalidTransition = errors.New("invalid onboarding state transition")
+
+// OnboardingState is how far an onboarding has got. It is stored in
+// A2POnboardingResponse.State and only moves along the transitions below:
+//
+//	Draft → ProfileSubmitted → TrustProductSubmitted → BrandPending
+//	BrandPending → BrandApproved | BrandFailed
+//	BrandApproved → NumberAttached → CampaignPending
+//	CampaignPending → Live | CampaignFailed
+//
+// A submitted customer profile or trust product can be rejected until the
+// brand is decided:
+//
+//	ProfileSubmitted | TrustProductSubmitted | BrandPending → ProfileRejected
+//	TrustProductSubmitted | BrandPending → TrustProductRejected
+type OnboardingState string
+
+const (
+	StateDraft                 OnboardingState = "draft"
+	StateProfileSubmitted      OnboardingState = "profile_submitted"
+	StateProfileRejected       OnboardingState = "profile_rejected"
+	StateTrustProductSubmitted OnboardingState = "trust_product_submitted"
+	StateTrustProductRejected  OnboardingState = "trust_product_rejected"
+	StateBrandPending          OnboardingState = "brand_pending"
+	StateBrandApproved         OnboardingState = "brand_approved"
+	StateBrandFailed           OnboardingState = "brand_failed"
+	StateNumberAttached        OnboardingState = "number_attached"
+	StateCampaignPending       OnboardingState = "campaign_pending"
+	StateLive                  OnboardingState = "live"
+	StateCampaignFailed        OnboardingState = "campaign_failed"
+)
+
+var onboardingTransitions = map[OnboardingState][]OnboardingState{
+	StateDraft:                 {StateProfileSubmitted},
+	StateProfileSubmitted:      {StateTrustProductSubmitted, StateProfileRejected},
+	StateProfileRejected:       nil,
+	StateTrustProductSubmitted: {StateBrandPending, StateProfileRejected, StateTrustProductRejected},
+	StateTrustProductRejected:  nil,
+	StateBrandPending:          {StateBrandApproved, StateBrandFailed, StateProfileRejected, StateTrustProductRejected},
+	StateBrandApproved:         {StateNumberAttached},
+	StateBrandFailed:           nil,
+	StateNumberAttached:        {StateCampaignPending},
+	StateCampaignPending:       {StateLive, StateCampaignFailed},
+	StateLive:                  nil,
+	StateCampaignFailed:        nil,
+}
+
+// Valid reports whether s is one of the defined states.
+func (s OnboardingState) Valid() bool {
+	_, ok := onboardingTransitions[s]
+	return ok
+}
+
+// Terminal reports whether no transition leaves s.
+func (s OnboardingState) Terminal() bool {
+	return s.Valid() && len(onboardingTransitions[s]) == 0
+}
+
+// CanTransitionTo reports whether s may move to next. Staying in the same
+// state is allowed, so repeated status checks are harmless.
+func (s OnboardingState) CanTransitionTo(next OnboardingState) bool {
+	if !s.Valid() {
+		return false
+	}
+	if s == next {
+		return true
+	}
+	for _, allowed := range onboardingTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// brandDecided reports whether s is past the brand review.
+func (s OnboardingState) brandDecided() bool {
+	switch s {
+	case StateBrandApproved, StateBrandFailed, StateNumberAttached, StateCampaignPending, StateLive, StateCampaignFailed:
+		return true
+	}
+	return false
+}
+
+// reached reports whether s is target or a state after it.
+func (s OnboardingState) reached(target OnboardingState) bool {
+	seen := map[OnboardingState]bool{target: true}
+	queue := []OnboardingState{target}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		if state == s {
+			return true
+		}
+		for _, next := range onboardingTransitions[state] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return false
+}
+
+// advanceTo is Transition for progress that may be reported more than once:
+// a state s has already reached leaves s as it is. The empty state counts as
+// StateDraft.
+func (s OnboardingState) advanceTo(next OnboardingState) (OnboardingState, error) {
+	if s == "" {
+		s = StateDraft
+	}
+	if s.reached(next) {
+		return s, nil
+	}
+	return s.Transition(next)
+}
+
+// statesTo returns the states an onboarding passes through from StateDraft to
+// target, target included.
+func statesTo(target OnboardingState) []OnboardingState {
+	previous := map[OnboardingState]OnboardingState{}
+	queue := []OnboardingState{StateDraft}
+	for len(queue) > 0 && queue[0] != target {
+		state := queue[0]
+		queue = queue[1:]
+		for _, next := range onboardingTransitions[state] {
+			if _, ok := previous[next]; !ok {
+				previous[next] = state
+				queue = append(queue, next)
+			}
+		}
+	}
+	if _, ok := previous[target]; !ok {
+		return nil
+	}
+	var states []OnboardingState
+	for state := target; state != StateDraft; state = previous[state] {
+		states = append([]OnboardingState{state}, states...)
+	}
+	return states
+}
+
+// Transition returns next, or ErrInvalidTransition if s cannot move to it.
+func (s OnboardingState) Transition(next OnboardingState) (OnboardingState, error) {
+	if !s.CanTransitionTo(next) {
+		return s, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, s, next)
+	}
+	return next, nil
+}
+
+// UnmarshalText rejects unknown states, so a stored response cannot bring
+// one in. The empty state of responses stored without one is accepted.
+func (s *OnboardingState) UnmarshalText(text []byte) error {
+	state := OnboardingState(text)
+	if state != "" && !state.Valid() {
+		return fmt.Errorf("unknown onboarding state %q", text)
+	}
+	*s = state
+	return nil
+}
+
+// Advance moves the response to next and updates Updated_At. An empty State
+// counts as StateDraft.
+func (r *A2POnboardingResponse) Advance(next OnboardingState) error {
+	current := r.State
+	if current == "" {
+		current = StateDraft
+	}
+	state, err := current.Transition(next)
+	if err != nil {
+		return err
+	}
+	r.State = state
+	r.Updated_At = time.Now().UTC()
+	return nil
+}
+
+// advanceTo moves the response to next unless it has reached it already. A
+// transition that is not allowed is logged and leaves the state unchanged.
+func (r *A2POnboardingResponse) advanceTo(log *slog.Logger, next OnboardingState) {
+	state, err := r.State.advanceTo(next)
+	if err != nil {
+		log.Warn("onboarding state not advanced", "state", r.State, "next_state", next, "error", err)
+		return
+	}
+	if state != r.State {
+		r.State = state
+		r.Updated_At = time.Now().UTC()
+	}
+}
+
+// advanceThrough advances the response to each state in turn, skipping those
+// it has reached already. With statesTo it records progress made outside
+// OnboardCustomer, where earlier states may be missing.
+func (r *A2POnboardingResponse) advanceThrough(log *slog.Logger, states ...OnboardingState) {
+	for _, next := range states {
+		r.advanceTo(log, next)
+	}
+}
+
+// brandRegistrationState maps a Twilio brand registration status to the state
+// of an onboarding waiting for it.
+func brandRegistrationState(status string) (OnboardingState, error) {
+	switch status {
+	case "APPROVED":
+		return StateBrandApproved, nil
+	case "FAILED", "DELETED":
+		return StateBrandFailed, nil
+	case "IN_REVIEW", "PENDING":
+		return StateBrandPending, nil
+	default:
+		return "", fmt.Errorf("unknown status: %s", status)
+	}
+}
+
+// resourceState maps the status of a watched resource to the state of the
+// onboarding it belongs to, or "" when the status decides nothing.
+func resourceState(kind ResourceKind, status string) OnboardingState {
+	switch kind {
+	case ResourceCustomerProfile, ResourceTrustProduct:
+		return bundleState(kind, status)
+	case ResourceBrandRegistration:
+		state, _ := brandRegistrationState(status)
+		return state
+	case ResourceCampaign:
+		switch status {
+		case "VERIFIED":
+			return StateLive
+		case "FAILED":
+			return StateCampaignFailed
+		}
+	}
+	return ""
+}
+
+// bundleState maps a TrustHub status of a customer profile or trust product to
+// the state of its onboarding, or "" for none.
+func bundleState(kind ResourceKind, status string) OnboardingState {
+	switch status {
+	case "pending-review", "in-review":
+		if kind == ResourceCustomerProfile {
+			return StateProfileSubmitted
+		}
+		return StateTrustProductSubmitted
+	case bundleRejected:
+		if kind == ResourceCustomerProfile {
+			return StateProfileRejected
+		}
+		return StateTrustProductRejected
+	}
+	return ""
+}
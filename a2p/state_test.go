@@ -0,0 +1,178 @@
+package a2p
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestOnboardingStateTransition(t *testing.T) {
+	tests := []struct {
+		from, to OnboardingState
+		wantErr  bool
+	}{
+		{from: StateDraft, to: StateProfileSubmitted},
+		{from: StateProfileSubmitted, to: StateTrustProductSubmitted},
+		{from: StateTrustProductSubmitted, to: StateBrandPending},
+		{from: StateBrandPending, to: StateBrandApproved},
+		{from: StateBrandPending, to: StateBrandFailed},
+		{from: StateBrandApproved, to: StateNumberAttached},
+		{from: StateNumberAttached, to: StateCampaignPending},
+		{from: StateCampaignPending, to: StateLive},
+		{from: StateCampaignPending, to: StateCampaignFailed},
+		{from: StateProfileSubmitted, to: StateProfileRejected},
+		{from: StateBrandPending, to: StateProfileRejected},
+		{from: StateTrustProductSubmitted, to: StateTrustProductRejected},
+		{from: StateBrandPending, to: StateTrustProductRejected},
+		{from: StateBrandPending, to: StateBrandPending},
+		{from: StateDraft, to: StateBrandPending, wantErr: true},
+		{from: StateProfileSubmitted, to: StateTrustProductRejected, wantErr: true},
+		{from: StateBrandApproved, to: StateProfileRejected, wantErr: true},
+		{from: StateLive, to: StateCampaignPending, wantErr: true},
+		{from: StateBrandFailed, to: StateBrandApproved, wantErr: true},
+		{from: "unknown", to: StateProfileSubmitted, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			got, err := tt.from.Transition(tt.to)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTransition) || got != tt.from {
+					t.Errorf("Transition = %s, %v; want %s, ErrInvalidTransition", got, err, tt.from)
+				}
+				return
+			}
+			if err != nil || got != tt.to {
+				t.Errorf("Transition = %s, %v; want %s", got, err, tt.to)
+			}
+		})
+	}
+}
+
+func TestOnboardingStateTerminal(t *testing.T) {
+	terminal := []OnboardingState{StateProfileRejected, StateTrustProductRejected, StateBrandFailed, StateLive, StateCampaignFailed}
+	for state := range onboardingTransitions {
+		if got, want := state.Terminal(), slices.Contains(terminal, state); got != want {
+			t.Errorf("%s.Terminal() = %t, want %t", state, got, want)
+		}
+	}
+	if OnboardingState("unknown").Terminal() {
+		t.Error("an unknown state is terminal")
+	}
+}
+
+func TestOnboardingStateAdvanceTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to OnboardingState
+		want     OnboardingState
+		wantErr  bool
+	}{
+		{name: "next state", from: StateBrandPending, to: StateBrandApproved, want: StateBrandApproved},
+		{name: "empty is draft", from: "", to: StateProfileSubmitted, want: StateProfileSubmitted},
+		{name: "reported again", from: StateBrandPending, to: StateProfileSubmitted, want: StateBrandPending},
+		{name: "reached long ago", from: StateLive, to: StateBrandPending, want: StateLive},
+		{name: "skipping states", from: StateDraft, to: StateBrandPending, want: StateDraft, wantErr: true},
+		{name: "other branch", from: StateBrandFailed, to: StateBrandApproved, want: StateBrandFailed, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.from.advanceTo(tt.to)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("advanceTo(%s) from %q = %s, %v; want %s, error %t", tt.to, tt.from, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatesTo(t *testing.T) {
+	tests := []struct {
+		target OnboardingState
+		want   []OnboardingState
+	}{
+		{target: StateProfileSubmitted, want: []OnboardingState{StateProfileSubmitted}},
+		{target: StateBrandPending, want: []OnboardingState{StateProfileSubmitted, StateTrustProductSubmitted, StateBrandPending}},
+		{target: StateCampaignPending, want: []OnboardingState{StateProfileSubmitted, StateTrustProductSubmitted, StateBrandPending, StateBrandApproved, StateNumberAttached, StateCampaignPending}},
+		{target: StateProfileRejected, want: []OnboardingState{StateProfileSubmitted, StateProfileRejected}},
+		{target: "unknown", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.target), func(t *testing.T) {
+			if got := statesTo(tt.target); !slices.Equal(got, tt.want) {
+				t.Errorf("statesTo(%s) = %v, want %v", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseAdvance(t *testing.T) {
+	r := &A2POnboardingResponse{}
+	if err := r.Advance(StateProfileSubmitted); err != nil {
+		t.Fatal(err)
+	}
+	if r.State != StateProfileSubmitted || r.Updated_At.IsZero() {
+		t.Errorf("after Advance: state %s, updated at %v", r.State, r.Updated_At)
+	}
+	if err := r.Advance(StateLive); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Advance to live = %v, want ErrInvalidTransition", err)
+	}
+	if r.State != StateProfileSubmitted {
+		t.Errorf("failed Advance changed the state to %s", r.State)
+	}
+
+	// Progress made elsewhere passes through the states in between.
+	r.advanceThrough(discardLogger(), statesTo(StateCampaignPending)...)
+	if r.State != StateCampaignPending {
+		t.Errorf("after advanceThrough: state %s, want %s", r.State, StateCampaignPending)
+	}
+	r.advanceTo(discardLogger(), StateProfileRejected)
+	if r.State != StateCampaignPending {
+		t.Errorf("a disallowed advanceTo changed the state to %s", r.State)
+	}
+}
+
+func TestOnboardingStateUnmarshal(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    OnboardingState
+		wantErr bool
+	}{
+		{data: `{"state":"brand_pending"}`, want: StateBrandPending},
+		{data: `{"state":""}`, want: ""},
+		{data: `{}`, want: ""},
+		{data: `{"state":"approved"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var response A2POnboardingResponse
+		err := json.Unmarshal([]byte(tt.data), &response)
+		if (err != nil) != tt.wantErr || response.State != tt.want {
+			t.Errorf("Unmarshal(%s) = %q, %v; want %q, error %t", tt.data, response.State, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestResourceState(t *testing.T) {
+	tests := []struct {
+		kind   ResourceKind
+		status string
+		want   OnboardingState
+	}{
+		{ResourceCustomerProfile, "pending-review", StateProfileSubmitted},
+		{ResourceCustomerProfile, "twilio-rejected", StateProfileRejected},
+		{ResourceCustomerProfile, "twilio-approved", ""},
+		{ResourceTrustProduct, "in-review", StateTrustProductSubmitted},
+		{ResourceTrustProduct, "twilio-rejected", StateTrustProductRejected},
+		{ResourceBrandRegistration, "APPROVED", StateBrandApproved},
+		{ResourceBrandRegistration, "DELETED", StateBrandFailed},
+		{ResourceBrandRegistration, "IN_REVIEW", StateBrandPending},
+		{ResourceBrandRegistration, "SOMETHING_NEW", ""},
+		{ResourceCampaign, "VERIFIED", StateLive},
+		{ResourceCampaign, "FAILED", StateCampaignFailed},
+		{ResourceCampaign, "PENDING", ""},
+	}
+	for _, tt := range tests {
+		if got := resourceState(tt.kind, tt.status); got != tt.want {
+			t.Errorf("resourceState(%s, %s) = %q, want %q", tt.kind, tt.status, got, tt.want)
+		}
+	}
+}
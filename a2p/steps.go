@@ -0,0 +1,278 @@
+package a2p
+
+import (
+	"context"
+	"fmt"
+
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// defaultSteps are the onboarding stages 2.1 to 5.1. They are named after
+// their stage IDs and read earlier results with state.SID. The resources
+// without inputs (2.2, 2.4, 2.6, 3.1, 3.2 and the messaging service, 5.1)
+// are created concurrently; the attachments, evaluations and submissions
+// wait for what they refer to.
+func defaultSteps() []Step {
+	steps := []Step{
+		// Stage 2.1: Create a secondary customer profile
+		&FuncStep{
+			ID: "2.1", Operation: "CreateSecondaryCustomerProfile", Key: "customer_profile_sid", Resource: "customer_profile",
+			DependsOn: []string{},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.CreateSecondaryCustomerProfile(ctx, CustomerProfileData{
+					FriendlyName:   st.Params.FriendlyName,
+					Email:          st.Params.Email,
+					PolicySid:      "RNdfbf3fae0e1107f8aded0e7cead80bf5",
+					StatusCallback: st.Service.callbacks.StatusCallback,
+					CorrelationKey: st.Params.LocationID,
+				})
+			},
+			CompensateFunc: func(ctx context.Context, st *PipelineState, sid string) error {
+				return st.Service.trustHub.DeleteCustomerProfile(ctx, sid)
+			},
+		},
+		// Stage 2.2: Create an EndUser Business Information resource
+		&FuncStep{
+			ID: "2.2", Operation: "CreateEndUserBusinessInfo", Key: "business_info_end_user_sid", Resource: "end_user",
+			DependsOn: []string{},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.CreateEndUserBusinessInfo(ctx, BusinessInfoData{
+					BusinessName:               st.Params.BusinessName,
+					SocialMediaProfileUrls:     st.Params.SocialMediaProfileURLs,
+					WebsiteUrl:                 st.Params.WebsiteURL,
+					BusinessRegionsOfOperation: st.Params.RegionOfOperation,
+					BusinessType:               st.Params.BusinessType,
+					BusinessRegistrationId:     st.Params.BusinessRegistrationId,
+					BusinessIdentity:           st.Params.BusinessIdentity,
+					BusinessIndustry:           st.Params.BusinessIndustry,
+					BusinessRegistrationNumber: st.Params.BusinessRegistrationNumber,
+					CorrelationKey:             st.Params.LocationID,
+				})
+			},
+			CompensateFunc: deleteEndUser,
+		},
+		// Stage 2.3: Attach EndUser to the Secondary Customer Profile
+		&FuncStep{
+			ID: "2.3", Operation: "AttachEndUserToProfile", Resource: "customer_profile_entity_assignment",
+			DependsOn: []string{"2.1", "2.2"},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.AttachEndUserToProfile(ctx, EndUserAssignmentData{
+					CustomerProfileSid: st.SID("2.1"),
+					EndUserSid:         st.SID("2.2"),
+				})
+			},
+			CompensateFunc: detachFromCustomerProfile,
+		},
+		// Stage 2.4. Create an EndUser resource of type: authorized_representative_1
+		&FuncStep{
+			ID: "2.4", Operation: "CreateEndUserAuthorizedRep1", Key: "authorized_rep_end_user_sid", Resource: "end_user",
+			DependsOn: []string{},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.CreateEndUserAuthorizedRep1(ctx, EndUserAuthorizedRep1BusinessInfoData{
+					Type:           "authorized_representative_1",
+					FirstName:      st.Params.EndUserRepOneFirstName,
+					LastName:       st.Params.EndUserRepOneLastName,
+					Email:          st.Params.EndUserRepOneEmail,
+					PhoneNumber:    st.Params.EndUserRepOnePhoneNumber,
+					Position:       st.Params.EndUserRepOnePosition,
+					BusinessTitle:  st.Params.EndUserRepOneBusinessTitle,
+					FriendlyName:   fmt.Sprintf("%s - Authorized Representative 1", st.Params.CustomerName),
+					CorrelationKey: st.Params.LocationID,
+				})
+			},
+			CompensateFunc: deleteEndUser,
+		},
+		// Stage 2.5: Attach EndUser to the Secondary Customer Profile
+		&FuncStep{
+			ID: "2.5", Operation: "AttachEndUserAuthorizedRep1ToProfile", Resource: "customer_profile_entity_assignment",
+			DependsOn: []string{"2.1", "2.4"},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.AttachEndUserAuthorizedRep1ToProfile(ctx, EndUserAssignmentData{
+					CustomerProfileSid: st.SID("2.1"),
+					EndUserSid:         st.SID("2.4"),
+				})
+			},
+			CompensateFunc: detachFromCustomerProfile,
+		},
+		// Stage 2.6 Create An Address Resource and returns address sid
+		&FuncStep{
+			ID: "2.6", Operation: "CreateAddressResource", Key: "address_sid", Resource: "address",
+			DependsOn: []string{},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.CreateAddressResource(ctx, AddressData{
+					PathAccountSid: st.Params.TwilioUsername,
+					CustomerName:   st.Params.CustomerName,
+					Street:         st.Params.Street,
+					City:           st.Params.City,
+					Region:         st.Params.Region,
+					PostalCode:     st.Params.PostalCode,
+					IsoCountry:     st.Params.IsoCountry,
+					FriendlyName:   fmt.Sprintf("%s - Address Resource", st.Params.CustomerName),
+					CorrelationKey: st.Params.LocationID,
+				})
+			},
+			CompensateFunc: func(ctx context.Context, st *PipelineState, sid string) error {
+				return st.Service.accounts.DeleteAddress(ctx, sid, &api.DeleteAddressParams{})
+			},
+		},
+		// Stage 2.7 Create a supporting document resource and returns supporting_document_sid
+		&FuncStep{
+			ID: "2.7", Operation: "CreateSupportingDocument", Key: "supporting_document_sid", Resource: "supporting_document",
+			DependsOn: []string{"2.6"},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.CreateSupportingDocumentResource(ctx, SupportingDocumentData{
+					FriendlyName:   fmt.Sprintf("%s - Business License Document", st.Params.CustomerName),
+					AddressSid:     st.SID("2.6"),
+					CorrelationKey: st.Params.LocationID,
+				})
+			},
+			CompensateFunc: func(ctx context.Context, st *PipelineState, sid string) error {
+				return st.Service.trustHub.DeleteSupportingDocument(ctx, sid)
+			},
+		},
+		// Stage 2.8 Attach the supporting document to the Secondary Customer Profile
+		&FuncStep{
+			ID: "2.8", Operation: "AttachSupportingDocumentToProfile", Resource: "customer_profile_entity_assignment",
+			DependsOn: []string{"2.1", "2.7"},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				supportingDocumentSID := st.SID("2.7")
+				return st.Service.AttachSupportingDocumentToProfile(ctx, st.SID("2.1"), &supportingDocumentSID)
+			},
+			CompensateFunc: detachFromCustomerProfile,
+		},
+		// Stage 2.9. Evaluate the Secondary Customer Profile
+		&FuncStep{
+			ID: "2.9", Operation: "EvaluateSecondaryCustomerProfile",
+			DependsOn: []string{"2.3", "2.5", "2.8"},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.EvaluateSecondaryCustomerProfile(ctx, st.SID("2.1"))
+			},
+		},
+		// Stage 2.10. Submit the Secondary Customer Profile for review  - status must be set to pending-review
+		&FuncStep{
+			ID: "2.10", Operation: "SubmitSecondaryCustomerProfileForReview",
+			DependsOn: []string{"2.9"},
+			Advances:  StateProfileSubmitted,
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.SubmitSecondaryCustomerProfileForReview(ctx, st.SID("2.1"))
+			},
+		},
+		// Stage 3.1: Create a TrustProduct Resource
+		&FuncStep{
+			ID: "3.1", Operation: "CreateTrustProduct", Key: "trust_product_sid", Resource: "trust_product",
+			DependsOn: []string{},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.CreateTrustProduct(ctx, TrustProductData{
+					FriendlyName:   st.Params.FriendlyName,
+					PolicySid:      "RNdfbf3fae0e1107f8aded0e7cead80bf5",
+					Email:          st.Params.Email,
+					StatusCallback: st.Service.callbacks.StatusCallback,
+					CorrelationKey: st.Params.LocationID,
+				})
+			},
+			CompensateFunc: func(ctx context.Context, st *PipelineState, sid string) error {
+				return st.Service.trustHub.DeleteTrustProduct(ctx, sid)
+			},
+		},
+		// Stage 3.2: Create an EndUser Resource of Type us_a2p_messaging_profile_information
+		&FuncStep{
+			ID: "3.2", Operation: "CreateEndUserMessagingProfile", Key: "messaging_profile_end_user_sid", Resource: "end_user",
+			DependsOn: []string{},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.CreateEndUserMessagingProfile(ctx, EndUserMessagingProfileData{
+					CompanyType:    st.Params.BusinessType,
+					StockExchange:  "",
+					StockTicker:    "",
+					CorrelationKey: st.Params.LocationID,
+				})
+			},
+			CompensateFunc: deleteEndUser,
+		},
+		// Stage 3.3: Attach the EndUser to the TrustProduct
+		&FuncStep{
+			ID: "3.3", Operation: "AttachEndUserToTrustProduct", Resource: "trust_product_entity_assignment",
+			DependsOn: []string{"3.1", "3.2"},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.AttachEndUserToTrustProduct(ctx, st.SID("3.1"), st.SID("3.2"))
+			},
+			CompensateFunc: detachFromTrustProduct,
+		},
+		// Stage 3.4: Attach the Secondary Customer Profile to the TrustProduct
+		&FuncStep{
+			ID: "3.4", Operation: "AttachSecondaryCustomerProfileToTrustProduct", Resource: "trust_product_entity_assignment",
+			DependsOn: []string{"3.1", "2.10"},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.AttachSecondaryCustomerProfileToTrustProduct(ctx, st.SID("3.1"), st.SID("2.1"))
+			},
+			CompensateFunc: detachFromTrustProduct,
+		},
+		// Stage 3.5: Evaluate the TrustProduct
+		&FuncStep{
+			ID: "3.5", Operation: "EvaluateTrustProduct",
+			DependsOn: []string{"3.3", "3.4"},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.EvaluateTrustProduct(ctx, st.SID("3.1"), "RNdfbf3fae0e1107f8aded0e7cead80bf5")
+			},
+		},
+		// Stage 3.6: Submit the TrustProduct for Review  - status must be set to pending-review
+		&FuncStep{
+			ID: "3.6", Operation: "SubmitTrustProductForReview",
+			DependsOn: []string{"3.5"},
+			Advances:  StateTrustProductSubmitted,
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.SubmitTrustProductForReview(ctx, st.SID("3.1"))
+			},
+		},
+		// Stage 4.1: Create a BrandRegistration
+		&FuncStep{
+			ID: "4.1", Operation: "CreateBrandRegistration", Key: "brand_registration_sid", Resource: "brand_registration",
+			DependsOn: []string{"2.10", "3.6"},
+			Advances:  StateBrandPending,
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				sid, status, err := st.Service.CreateBrandRegistration(ctx, BrandRegistrationData{
+					CustomerProfileBundleSid: st.SID("2.1"),
+					A2PProfileBundleSid:      st.SID("3.1"),
+				})
+				st.update(func(checkpoint *OnboardingCheckpoint) {
+					checkpoint.BrandRegistrationStatus = status
+				})
+				return sid, err
+			},
+			CompensateFunc: func(ctx context.Context, st *PipelineState, sid string) error {
+				return errBrandRegistrationPermanent
+			},
+		},
+		// Stage 5.1: Create a MessagingService Resource - This will return MessageServiceSID
+		&FuncStep{
+			ID: "5.1", Operation: "CreateMessagingService", Key: "messaging_service_sid", Resource: "messaging_service",
+			DependsOn: []string{},
+			RunFunc: func(ctx context.Context, st *PipelineState) (string, error) {
+				return st.Service.CreateMessagingService(ctx, MessagingServiceData{
+					FriendlyName:      st.Params.FriendlyName,
+					InboundRequestUrl: st.Service.callbacks.InboundRequestURL,
+					FallbackUrl:       st.Service.callbacks.FallbackURL,
+					CorrelationKey:    st.Params.LocationID,
+				})
+			},
+			CompensateFunc: func(ctx context.Context, st *PipelineState, sid string) error {
+				return st.Service.messaging.DeleteService(ctx, sid)
+			},
+		},
+	}
+	for _, step := range steps {
+		step.(*FuncStep).builtin = true
+	}
+	return steps
+}
+
+func deleteEndUser(ctx context.Context, st *PipelineState, sid string) error {
+	return st.Service.trustHub.DeleteEndUser(ctx, sid)
+}
+
+func detachFromCustomerProfile(ctx context.Context, st *PipelineState, sid string) error {
+	return st.Service.trustHub.DeleteCustomerProfileEntityAssignment(ctx, st.SID("2.1"), sid)
+}
+
+func detachFromTrustProduct(ctx context.Context, st *PipelineState, sid string) error {
+	return st.Service.trustHub.DeleteTrustProductEntityAssignment(ctx, st.SID("3.1"), sid)
+}
@@ -0,0 +1,305 @@
+package a2p
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrRecordNotFound is returned by a Store that has no record for the
+	// given key.
+	ErrRecordNotFound = errors.New("onboarding record not found")
+	// ErrRecordKey is returned when saving a record without a LocationID.
+	ErrRecordKey = errors.New("onboarding record has no location ID")
+)
+
+// OnboardingRecord is what a Store keeps for one customer. It never holds the
+// subaccount auth token.
+type OnboardingRecord struct {
+	LocationID   string `json:"location_id"`
+	SubaccountID string `json:"subaccount_id"`
+	// Checkpoint is written after every stage of OnboardCustomer.
+	Checkpoint *OnboardingCheckpoint `json:"checkpoint,omitempty"`
+	// Response is the latest result of OnboardCustomer or
+	// CompleteOnboarding, with its State.
+	Response *A2POnboardingResponse `json:"response,omitempty"`
+	// Monitor is kept by the StatusScheduler tracking the brand.
+	Monitor *BrandMonitor `json:"monitor,omitempty"`
+	// Bundles holds the TrustHub status callbacks received for the customer
+	// profile and trust product, keyed by bundle SID.
+	Bundles   map[string]BundleStatus `json:"bundles,omitempty"`
+	UpdatedAt time.Time               `json:"updated_at"`
+}
+
+// Store persists onboarding records, keyed by LocationID. Set one with
+// WithStore. Implementations must be safe for concurrent use.
+type Store interface {
+	// Save creates or replaces the record for record.LocationID.
+	Save(ctx context.Context, record *OnboardingRecord) error
+	// Load returns ErrRecordNotFound when there is no record.
+	Load(ctx context.Context, locationID string) (*OnboardingRecord, error)
+	// LoadBySubaccount returns the most recently updated record of the
+	// subaccount, or ErrRecordNotFound.
+	LoadBySubaccount(ctx context.Context, subaccountID string) (*OnboardingRecord, error)
+	// List returns every record, ordered by LocationID.
+	List(ctx context.Context) ([]*OnboardingRecord, error)
+	// Update applies fn to the record of locationID, or to a new record with
+	// only LocationID set when there is none, and saves the result unless fn
+	// fails. Updates and saves of one record are serialized, so concurrent
+	// writers never lose each other's changes.
+	Update(ctx context.Context, locationID string, fn func(record *OnboardingRecord) error) error
+}
+
+// FileStore is a Store that keeps each record in a JSON file named after its
+// LocationID. Files are replaced atomically, so a crash leaves either the old
+// or the new record.
+type FileStore struct {
+	dir string
+	// mu guards locks, which serialize the writes of each file from this
+	// process.
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewFileStore stores records in dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return &FileStore{dir: dir, locks: map[string]*sync.Mutex{}}, nil
+}
+
+func (f *FileStore) Save(ctx context.Context, record *OnboardingRecord) error {
+	if record.LocationID == "" {
+		return ErrRecordKey
+	}
+	path := f.path(record.LocationID)
+	defer f.lock(path)()
+	return f.save(path, record)
+}
+
+func (f *FileStore) Update(ctx context.Context, locationID string, fn func(record *OnboardingRecord) error) error {
+	if locationID == "" {
+		return ErrRecordKey
+	}
+	path := f.path(locationID)
+	defer f.lock(path)()
+	record, err := f.read(path)
+	if errors.Is(err, ErrRecordNotFound) {
+		record, err = &OnboardingRecord{LocationID: locationID}, nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := fn(record); err != nil {
+		return err
+	}
+	record.LocationID = locationID
+	return f.save(path, record)
+}
+
+func (f *FileStore) save(path string, record *OnboardingRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode onboarding record: %w", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to save onboarding record: %w", err)
+	}
+	return nil
+}
+
+// lock locks the file at path against other writes from this process and
+// returns the unlock function.
+func (f *FileStore) lock(path string) func() {
+	f.mu.Lock()
+	if f.locks == nil {
+		f.locks = map[string]*sync.Mutex{}
+	}
+	l, ok := f.locks[path]
+	if !ok {
+		l = &sync.Mutex{}
+		f.locks[path] = l
+	}
+	f.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+func (f *FileStore) Load(ctx context.Context, locationID string) (*OnboardingRecord, error) {
+	return f.read(f.path(locationID))
+}
+
+func (f *FileStore) LoadBySubaccount(ctx context.Context, subaccountID string) (*OnboardingRecord, error) {
+	records, err := f.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var found *OnboardingRecord
+	for _, record := range records {
+		if record.SubaccountID == subaccountID && (found == nil || record.UpdatedAt.After(found.UpdatedAt)) {
+			found = record
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: subaccount %s", ErrRecordNotFound, subaccountID)
+	}
+	return found, nil
+}
+
+func (f *FileStore) List(ctx context.Context) ([]*OnboardingRecord, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list onboarding records: %w", err)
+	}
+	var records []*OnboardingRecord
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		record, err := f.read(filepath.Join(f.dir, entry.Name()))
+		if errors.Is(err, ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].LocationID < records[j].LocationID
+	})
+	return records, nil
+}
+
+func (f *FileStore) path(locationID string) string {
+	return filepath.Join(f.dir, url.PathEscape(locationID)+".json")
+}
+
+func (f *FileStore) read(path string) (*OnboardingRecord, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrRecordNotFound, filepath.Base(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read onboarding record: %w", err)
+	}
+	var record OnboardingRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse onboarding record %s: %w", path, err)
+	}
+	return &record, nil
+}
+
+// writeFileAtomic replaces path with data through a synced temporary file in
+// the same directory.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".record-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// sid returns the SID of a pipeline stage, from the response or else the
+// checkpoint.
+func (r *OnboardingRecord) sid(stage string, fromResponse func(*A2POnboardingResponse) string) string {
+	if r.Response != nil {
+		if sid := fromResponse(r.Response); sid != "" {
+			return sid
+		}
+	}
+	if r.Checkpoint != nil {
+		return r.Checkpoint.Stages[stage]
+	}
+	return ""
+}
+
+// response returns the record's response, starting one for params if there
+// is none.
+func (r *OnboardingRecord) response(params *FullA2POnboardingParams) *A2POnboardingResponse {
+	if r.Response == nil {
+		r.Response = &A2POnboardingResponse{
+			LocationID:           params.LocationID,
+			SubaccountID:         params.SubaccountID,
+			TwilioUsername:       params.TwilioUsername,
+			MessagingServiceSID:  params.MessagingServiceSID,
+			TwilioPhoneNumber:    params.TwilioPurchasedPhoneNumber,
+			TwilioPhoneNumberSID: params.TwilioPurchasedPhoneNumberSID,
+			Created_At:           time.Now().UTC(),
+		}
+	}
+	return r.Response
+}
+
+// updateRecord applies update to the stored record of params, creating it if
+// needed. Without a store it does nothing. Store failures are logged, not
+// returned: the onboarding itself succeeded.
+func (s *A2PService) updateRecord(ctx context.Context, log *slog.Logger, params *FullA2POnboardingParams, update func(record *OnboardingRecord)) {
+	if s.store == nil {
+		return
+	}
+	err := s.store.Update(ctx, params.LocationID, func(record *OnboardingRecord) error {
+		if params.SubaccountID != "" {
+			record.SubaccountID = params.SubaccountID
+		}
+		update(record)
+		now := time.Now().UTC()
+		if record.Response != nil {
+			response := *record.Response
+			response.TwilioPassword = ""
+			if response.Created_At.IsZero() {
+				response.Created_At = now
+			}
+			response.Updated_At = now
+			record.Response = &response
+		}
+		record.UpdatedAt = now
+		return nil
+	})
+	if err != nil {
+		log.Error("failed to update onboarding record", "error", err)
+	}
+}
+
+// storedCheckpoint returns the checkpoint saved for params, or nil.
+func (s *A2PService) storedCheckpoint(ctx context.Context, params *FullA2POnboardingParams) (*OnboardingCheckpoint, error) {
+	if s.store == nil {
+		return nil, nil
+	}
+	record, err := s.store.Load(ctx, params.LocationID)
+	if errors.Is(err, ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if record.SubaccountID != params.SubaccountID {
+		return nil, nil
+	}
+	return record.Checkpoint, nil
+}
+
+var _ Store = (*FileStore)(nil)
@@ -0,0 +1,240 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileStoreSaveLoad(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		locationID string
+		wantErr    error
+	}{
+		{name: "plain", locationID: "location-1"},
+		{name: "path separators", locationID: "../agency/location 2"},
+		{name: "no location", locationID: "", wantErr: ErrRecordKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := NewFileStore(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			record := &OnboardingRecord{
+				LocationID:   tt.locationID,
+				SubaccountID: testSubaccountSID,
+				Checkpoint:   &OnboardingCheckpoint{Stages: map[string]string{"2.1": "BU1"}},
+			}
+			err = store.Save(ctx, record)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Save error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			loaded, err := store.Load(ctx, tt.locationID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.LocationID != tt.locationID || loaded.Checkpoint.Stages["2.1"] != "BU1" {
+				t.Errorf("loaded %+v, want the saved record", loaded)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("store directory holds %d entries, want only the record", len(entries))
+			}
+		})
+	}
+}
+
+func TestFileStoreLoadMissing(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(ctx, "nowhere"); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Load error = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := store.LoadBySubaccount(ctx, testSubaccountSID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("LoadBySubaccount error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestFileStoreAtomicSave(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A temporary file left behind by a crash mid-write is not a record.
+	if err := os.WriteFile(filepath.Join(dir, ".record-123"), []byte(`{"location_id": "partial`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := store.Save(ctx, &OnboardingRecord{LocationID: "location-1", SubaccountID: fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	records, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(records) != 1 || records[0].SubaccountID != "2" {
+		t.Errorf("List = %+v, want the last saved record only", records)
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, ".record-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Errorf("temporary files %v, want only the one left by the crash", matches)
+	}
+}
+
+func TestFileStoreConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const writers = 50
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := store.Update(ctx, "location-1", func(record *OnboardingRecord) error {
+				if record.Bundles == nil {
+					record.Bundles = map[string]BundleStatus{}
+				}
+				record.Bundles[fmt.Sprintf("BU%d", i)] = BundleStatus{Status: "pending-review"}
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	record, err := store.Load(ctx, "location-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(record.Bundles) != writers {
+		t.Errorf("%d updates kept, want %d", len(record.Bundles), writers)
+	}
+}
+
+func TestFileStoreUpdateError(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	errAbort := errors.New("abort")
+	err = store.Update(ctx, "location-1", func(record *OnboardingRecord) error {
+		record.SubaccountID = testSubaccountSID
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Errorf("Update error = %v, want %v", err, errAbort)
+	}
+	if _, err := store.Load(ctx, "location-1"); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("failed update saved a record: %v", err)
+	}
+}
+
+func TestFileStoreLoadBySubaccount(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	records := []*OnboardingRecord{
+		{LocationID: "old", SubaccountID: testSubaccountSID, UpdatedAt: now.Add(-time.Hour)},
+		{LocationID: "new", SubaccountID: testSubaccountSID, UpdatedAt: now},
+		{LocationID: "other", SubaccountID: "AC2", UpdatedAt: now.Add(time.Hour)},
+	}
+	for _, record := range records {
+		if err := store.Save(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record, err := store.LoadBySubaccount(ctx, testSubaccountSID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.LocationID != "new" {
+		t.Errorf("LoadBySubaccount = %s, want the most recently updated record", record.LocationID)
+	}
+}
+
+func TestUpdateRecordStripsCredentials(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(record *OnboardingRecord, params *FullA2POnboardingParams)
+	}{
+		{"response with password", func(record *OnboardingRecord, params *FullA2POnboardingParams) {
+			record.Response = &A2POnboardingResponse{LocationID: params.LocationID, TwilioPassword: params.TwilioPassword}
+		}},
+		{"started response", func(record *OnboardingRecord, params *FullA2POnboardingParams) {
+			record.response(params).TwilioPassword = params.TwilioPassword
+		}},
+		{"checkpoint only", func(record *OnboardingRecord, params *FullA2POnboardingParams) {
+			record.Checkpoint = NewOnboardingCheckpoint(params)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			store, err := NewFileStore(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := NewA2PService(NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts(), WithLogger(discardLogger()), WithStore(store))
+			params := testParams()
+			s.updateRecord(ctx, s.logger, params, func(record *OnboardingRecord) {
+				tt.update(record, params)
+			})
+
+			record, err := store.Load(ctx, params.LocationID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record.SubaccountID != params.SubaccountID {
+				t.Errorf("SubaccountID = %q, want %q", record.SubaccountID, params.SubaccountID)
+			}
+			if record.Response != nil && record.Response.TwilioPassword != "" {
+				t.Error("stored response keeps the password")
+			}
+			data, err := os.ReadFile(filepath.Join(dir, params.LocationID+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(data), params.TwilioPassword) {
+				t.Errorf("record file contains the password:\n%s", data)
+			}
+		})
+	}
+}
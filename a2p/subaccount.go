@@ -2,7 +2,10 @@
 package a2p
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	api "github.com/twilio/twilio-go/rest/api/v2010"
 )
@@ -12,14 +15,39 @@ type SubAccountData struct {
 }
 
 // Step 1.0: Automate Subaccount Creation
-func (s *A2PService) CreateSubAccount(data SubAccountData) (string, string, error) {
+func (s *A2PService) CreateSubAccount(ctx context.Context, data SubAccountData) (string, string, error) {
 	params := &api.CreateAccountParams{}
 	params.SetFriendlyName(data.FriendlyName)
 
-	resp, err := s.client.Api.CreateAccount(params)
+	resp, err := reuseOrCreate(ctx, s, "subaccount", func(ctx context.Context) (*api.ApiV2010Account, error) {
+		return findAccount(ctx, s.accounts, params, time.Time{})
+	}, func(ctx context.Context) (*api.ApiV2010Account, error) {
+		return s.accounts.CreateAccount(ctx, params)
+	})
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create subaccount: %w", err)
 	}
 
 	return *resp.Sid, *resp.AuthToken, nil
 }
+
+// ForSubaccount returns a copy of the service that authenticates as the
+// subaccount sid with its auth token, so every resource it creates belongs to
+// that subaccount. Options such as the logger, retry policy, HTTP client and
+// callback URLs carry over. A service built with NewA2PService returns
+// itself, since its clients are not tied to an account.
+func (s *A2PService) ForSubaccount(sid, token string) (*A2PService, error) {
+	if !strings.HasPrefix(sid, "AC") || token == "" {
+		return nil, ErrSubaccountCredentials
+	}
+	return s.forAccount(sid, token), nil
+}
+
+// subaccountFor scopes the service to the subaccount credentials in params.
+// Callers that already hold a ForSubaccount service may leave them empty.
+func (s *A2PService) subaccountFor(params *FullA2POnboardingParams) (*A2PService, error) {
+	if params.TwilioUsername == "" && params.TwilioPassword == "" {
+		return s, nil
+	}
+	return s.ForSubaccount(params.TwilioUsername, params.TwilioPassword)
+}
@@ -2,22 +2,28 @@
 package a2p
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	trusthub "github.com/twilio/twilio-go/rest/trusthub/v1"
 )
 
 // Step 3.1: Create a TrustProduct Resource
-func (s *A2PService) CreateTrustProduct(data TrustProductData) (string, error) {
+func (s *A2PService) CreateTrustProduct(ctx context.Context, data TrustProductData) (string, error) {
 	params := &trusthub.CreateTrustProductParams{}
-	params.SetFriendlyName(data.FriendlyName)
+	params.SetFriendlyName(correlatedName(data.FriendlyName, data.CorrelationKey))
 	params.SetPolicySid(data.PolicySid)
 	params.SetEmail(data.Email)
 	if data.StatusCallback != "" {
 		params.SetStatusCallback(data.StatusCallback)
 	}
 
-	resp, err := s.client.TrusthubV1.CreateTrustProduct(params)
+	resp, err := reuseOrCreate(ctx, s, "trust product", func(ctx context.Context) (*trusthub.TrusthubV1TrustProduct, error) {
+		return findTrustProduct(ctx, s.trustHub, params, time.Time{})
+	}, func(ctx context.Context) (*trusthub.TrusthubV1TrustProduct, error) {
+		return s.trustHub.CreateTrustProduct(ctx, params)
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to create TrustProduct: %w", err)
 	}
@@ -27,17 +33,17 @@ func (s *A2PService) CreateTrustProduct(data TrustProductData) (string, error) {
 
 // Step 3.2: Create an EndUser Resource of Type us_a2p_messaging_profile_information
 // Note :  a2p_messaging_profile_sid will be the id returned from this function
-func (s *A2PService) CreateEndUserMessagingProfile(data EndUserMessagingProfileData) (string, error) {
+func (s *A2PService) CreateEndUserMessagingProfile(ctx context.Context, data EndUserMessagingProfileData) (string, error) {
 	params := &trusthub.CreateEndUserParams{}
 	params.SetAttributes(map[string]interface{}{
 		"company_type":   data.CompanyType,
 		"stock_exchange": data.StockExchange,
 		"stock_ticker":   data.StockTicker,
 	})
-	params.SetFriendlyName(fmt.Sprintf("%s Messaging Profile EndUser", data.CompanyType))
+	params.SetFriendlyName(correlatedName(fmt.Sprintf("%s Messaging Profile EndUser", data.CompanyType), data.CorrelationKey))
 	params.SetType("us_a2p_messaging_profile_information")
 
-	resp, err := s.client.TrusthubV1.CreateEndUser(params)
+	resp, err := s.reuseOrCreateEndUser(ctx, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to create EndUser messaging profile: %w", err)
 	}
@@ -46,11 +52,11 @@ func (s *A2PService) CreateEndUserMessagingProfile(data EndUserMessagingProfileD
 }
 
 // Step 3.3: Attach the EndUser to the TrustProduct
-func (s *A2PService) AttachEndUserToTrustProduct(trustProductSid, endUserSid string) (string, error) {
+func (s *A2PService) AttachEndUserToTrustProduct(ctx context.Context, trustProductSid, endUserSid string) (string, error) {
 	params := &trusthub.CreateTrustProductEntityAssignmentParams{}
 	params.SetObjectSid(endUserSid)
 
-	resp, err := s.client.TrusthubV1.CreateTrustProductEntityAssignment(trustProductSid, params)
+	resp, err := s.reuseOrAttachToTrustProduct(ctx, trustProductSid, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to attach EndUser to TrustProduct: %w", err)
 	}
@@ -59,11 +65,11 @@ func (s *A2PService) AttachEndUserToTrustProduct(trustProductSid, endUserSid str
 }
 
 // Step 3.4: Attach the Secondary Customer Profile to the TrustProduct
-func (s *A2PService) AttachSecondaryCustomerProfileToTrustProduct(trustProductSid, customerProfileSid string) (string, error) {
+func (s *A2PService) AttachSecondaryCustomerProfileToTrustProduct(ctx context.Context, trustProductSid, customerProfileSid string) (string, error) {
 	params := &trusthub.CreateTrustProductEntityAssignmentParams{}
 	params.SetObjectSid(customerProfileSid)
 
-	resp, err := s.client.TrusthubV1.CreateTrustProductEntityAssignment(trustProductSid, params)
+	resp, err := s.reuseOrAttachToTrustProduct(ctx, trustProductSid, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to attach Customer Profile to TrustProduct: %w", err)
 	}
@@ -72,27 +78,40 @@ func (s *A2PService) AttachSecondaryCustomerProfileToTrustProduct(trustProductSi
 }
 
 // Step 3.5: Evaluate the TrustProduct
-func (s *A2PService) EvaluateTrustProduct(trustProductSid, policySid string) (string, error) {
+func (s *A2PService) EvaluateTrustProduct(ctx context.Context, trustProductSid, policySid string) (string, error) {
 	params := &trusthub.CreateTrustProductEvaluationParams{}
 	params.SetPolicySid(policySid)
 
-	resp, err := s.client.TrusthubV1.CreateTrustProductEvaluation(trustProductSid, params)
+	resp, err := s.trustHub.CreateTrustProductEvaluation(ctx, trustProductSid, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to evaluate TrustProduct: %w", err)
 	}
+	if err := evaluationError(trustProductSid, resp.Sid, resp.Status, resp.Results); err != nil {
+		return "", fmt.Errorf("failed to evaluate TrustProduct: %w", err)
+	}
 
 	return *resp.Sid, nil
 }
 
 // Step 3.6: Submit the TrustProduct for Review  - status must be set to pending-review
-func (s *A2PService) SubmitTrustProductForReview(trustProductSid string) (string, error) {
+func (s *A2PService) SubmitTrustProductForReview(ctx context.Context, trustProductSid string) (string, error) {
 	params := &trusthub.UpdateTrustProductParams{}
 	params.SetStatus("pending-review")
 
-	resp, err := s.client.TrusthubV1.UpdateTrustProduct(trustProductSid, params)
+	resp, err := s.trustHub.UpdateTrustProduct(ctx, trustProductSid, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to submit TrustProduct for review: %w", err)
 	}
 
 	return *resp.Sid, nil
 }
+
+// reuseOrAttachToTrustProduct attaches params.ObjectSid to the trust product
+// unless it is attached already.
+func (s *A2PService) reuseOrAttachToTrustProduct(ctx context.Context, trustProductSid string, params *trusthub.CreateTrustProductEntityAssignmentParams) (*trusthub.TrusthubV1TrustProductEntityAssignment, error) {
+	return reuseOrCreate(ctx, s, "trust product entity assignment", func(ctx context.Context) (*trusthub.TrusthubV1TrustProductEntityAssignment, error) {
+		return findTrustProductEntityAssignment(ctx, s.trustHub, trustProductSid, params)
+	}, func(ctx context.Context) (*trusthub.TrusthubV1TrustProductEntityAssignment, error) {
+		return s.trustHub.CreateTrustProductEntityAssignment(ctx, trustProductSid, params)
+	})
+}
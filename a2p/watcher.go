@@ -0,0 +1,377 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrWatcherStarted is returned by StatusWatcher.Run when Run was called
+// before: the Changes channel is closed when the first Run returns.
+var ErrWatcherStarted = errors.New("status watcher already started")
+
+// ResourceKind names the resources a StatusWatcher follows.
+type ResourceKind string
+
+const (
+	ResourceCustomerProfile   ResourceKind = "customer_profile"
+	ResourceTrustProduct      ResourceKind = "trust_product"
+	ResourceBrandRegistration ResourceKind = "brand_registration"
+	ResourceCampaign          ResourceKind = "campaign"
+)
+
+// StatusChange is emitted by a StatusWatcher when a resource is first seen
+// and whenever its status changes.
+type StatusChange struct {
+	LocationID string       `json:"location_id"`
+	Kind       ResourceKind `json:"kind"`
+	SID        string       `json:"sid"`
+	// OldStatus is empty for the first observation.
+	OldStatus string `json:"old_status,omitempty"`
+	NewStatus string `json:"new_status"`
+	// Reasons explains a rejection, from the errors Twilio attached to the
+	// resource and, for brands, the brand feedback.
+	Reasons []ErrorInfo `json:"reasons,omitempty"`
+	// Final is set for statuses that do not change any more, such as
+	// "twilio-approved"; the resource is not polled afterwards.
+	Final bool      `json:"final,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// WatchTarget lists the resources of one customer to follow. Empty SIDs are
+// skipped. TwilioUsername and TwilioPassword are the subaccount credentials;
+// leave them empty when the service is already authenticated as it.
+type WatchTarget struct {
+	LocationID           string
+	TwilioUsername       string
+	TwilioPassword       string
+	CustomerProfileSID   string
+	TrustProductSID      string
+	BrandRegistrationSID string
+	MessagingServiceSID  string
+	CampaignSID          string
+}
+
+// WatchTargetFromRecord returns the resources recorded in a Store record,
+// without credentials.
+func WatchTargetFromRecord(record *OnboardingRecord) WatchTarget {
+	target := WatchTarget{LocationID: record.LocationID}
+	if record.Checkpoint != nil {
+		target.CustomerProfileSID = record.Checkpoint.Stages["2.1"]
+		target.TrustProductSID = record.Checkpoint.Stages["3.1"]
+	}
+	target.BrandRegistrationSID = record.sid("4.1", func(r *A2POnboardingResponse) string { return r.BrandRegistrationSID })
+	target.MessagingServiceSID = record.sid("5.1", func(r *A2POnboardingResponse) string { return r.MessagingServiceSID })
+	if record.Response != nil && record.Response.A2pMessageCampaignSID != "not submitted" {
+		target.CampaignSID = record.Response.A2pMessageCampaignSID
+	}
+	return target
+}
+
+// finalStatuses are the statuses after which a resource is no longer polled.
+var finalStatuses = map[ResourceKind]map[string]bool{
+	ResourceCustomerProfile:   {"twilio-approved": true, "twilio-rejected": true},
+	ResourceTrustProduct:      {"twilio-approved": true, "twilio-rejected": true},
+	ResourceBrandRegistration: {"APPROVED": true, "FAILED": true, "DELETED": true},
+	ResourceCampaign:          {"VERIFIED": true, "FAILED": true},
+}
+
+type watchKey struct {
+	kind ResourceKind
+	sid  string
+}
+
+// keys returns the watch keys of the resources target lists.
+func (target WatchTarget) keys() []watchKey {
+	var keys []watchKey
+	for _, key := range []watchKey{
+		{ResourceCustomerProfile, target.CustomerProfileSID},
+		{ResourceTrustProduct, target.TrustProductSID},
+		{ResourceBrandRegistration, target.BrandRegistrationSID},
+		{ResourceCampaign, target.CampaignSID},
+	} {
+		if key.sid != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// StatusWatcher follows the customer profile, trust product, brand
+// registration and campaign of every watched customer and reports their
+// status changes. When the service has a Store, the changes also advance the
+// stored onboarding state:
+//
+//	w := s.NewStatusWatcher(10*time.Minute, 16)
+//	w.Watch(a2p.WatchTargetFromRecord(record))
+//	go w.Run(ctx)
+//	for change := range w.Changes() {
+//		...
+//	}
+type StatusWatcher struct {
+	service  *A2PService
+	interval time.Duration
+	changes  chan StatusChange
+
+	mu      sync.Mutex
+	started bool
+	targets map[string]WatchTarget
+	// last holds the last status seen per watched resource.
+	last map[watchKey]string
+}
+
+// NewStatusWatcher returns a watcher polling every interval whose Changes
+// channel buffers buffer events.
+func (s *A2PService) NewStatusWatcher(interval time.Duration, buffer int) *StatusWatcher {
+	return &StatusWatcher{
+		service:  s,
+		interval: interval,
+		changes:  make(chan StatusChange, buffer),
+		targets:  map[string]WatchTarget{},
+		last:     map[watchKey]string{},
+	}
+}
+
+// Watch starts following target, replacing any target with its LocationID.
+// Resources the replaced target shares with target keep their last status.
+func (w *StatusWatcher) Watch(target WatchTarget) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.targets[target.LocationID] = target
+	w.prune()
+}
+
+// Unwatch stops following the customer and forgets the statuses seen, so
+// watching it again reports every resource as first observed.
+func (w *StatusWatcher) Unwatch(locationID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.targets, locationID)
+	w.prune()
+}
+
+// prune drops the last statuses of resources no target lists any more, with
+// the lock held.
+func (w *StatusWatcher) prune() {
+	watched := map[watchKey]bool{}
+	for _, target := range w.targets {
+		for _, key := range target.keys() {
+			watched[key] = true
+		}
+	}
+	for key := range w.last {
+		if !watched[key] {
+			delete(w.last, key)
+		}
+	}
+}
+
+// Changes returns the channel Run sends changes to. It is closed when Run
+// returns.
+func (w *StatusWatcher) Changes() <-chan StatusChange {
+	return w.changes
+}
+
+// Run polls every interval and sends the changes until ctx is done. It can
+// only be called once; later calls return ErrWatcherStarted. Use Poll to
+// schedule polls otherwise.
+func (w *StatusWatcher) Run(ctx context.Context) error {
+	w.mu.Lock()
+	started := w.started
+	w.started = true
+	w.mu.Unlock()
+	if started {
+		return ErrWatcherStarted
+	}
+	defer close(w.changes)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		changes, err := w.Poll(ctx)
+		if err != nil {
+			w.service.logger.Error("status watch failed", "error", err)
+		}
+		for _, change := range changes {
+			select {
+			case w.changes <- change:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches every watched resource not in a final status once and returns
+// the changes, for callers that schedule polls themselves. Resources that
+// could not be fetched are reported in the error and tried again next time.
+func (w *StatusWatcher) Poll(ctx context.Context) ([]StatusChange, error) {
+	w.mu.Lock()
+	targets := make([]WatchTarget, 0, len(w.targets))
+	for _, target := range w.targets {
+		targets = append(targets, target)
+	}
+	w.mu.Unlock()
+
+	var changes []StatusChange
+	var errs []error
+	for _, target := range targets {
+		if err := ctx.Err(); err != nil {
+			return changes, err
+		}
+		targetChanges, err := w.poll(ctx, target)
+		changes = append(changes, targetChanges...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("location %s: %w", target.LocationID, err))
+		}
+	}
+	return changes, errors.Join(errs...)
+}
+
+func (w *StatusWatcher) poll(ctx context.Context, target WatchTarget) ([]StatusChange, error) {
+	s := w.service
+	if target.TwilioUsername != "" || target.TwilioPassword != "" {
+		sub, err := s.ForSubaccount(target.TwilioUsername, target.TwilioPassword)
+		if err != nil {
+			return nil, err
+		}
+		s = sub
+	}
+
+	type watched struct {
+		kind  ResourceKind
+		sid   string
+		fetch func(ctx context.Context) (string, []ErrorInfo, error)
+	}
+	resources := []watched{
+		{ResourceCustomerProfile, target.CustomerProfileSID, func(ctx context.Context) (string, []ErrorInfo, error) {
+			resp, err := s.trustHub.FetchCustomerProfile(ctx, target.CustomerProfileSID)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to fetch customer profile: %w", err)
+			}
+			return deref(resp.Status), rejectionReasons(resp.Errors), nil
+		}},
+		{ResourceTrustProduct, target.TrustProductSID, func(ctx context.Context) (string, []ErrorInfo, error) {
+			resp, err := s.trustHub.FetchTrustProduct(ctx, target.TrustProductSID)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to fetch trust product: %w", err)
+			}
+			return deref(resp.Status), rejectionReasons(resp.Errors), nil
+		}},
+		{ResourceBrandRegistration, target.BrandRegistrationSID, func(ctx context.Context) (string, []ErrorInfo, error) {
+			resp, err := s.messaging.FetchBrandRegistrations(ctx, target.BrandRegistrationSID)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to fetch BrandRegistration: %w", err)
+			}
+			return deref(resp.Status), append(rejectionReasons(resp.Errors), brandFeedback(resp)...), nil
+		}},
+		{ResourceCampaign, target.CampaignSID, func(ctx context.Context) (string, []ErrorInfo, error) {
+			resp, err := s.messaging.FetchUsAppToPerson(ctx, target.MessagingServiceSID, target.CampaignSID)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to check A2P Campaign status: %w", err)
+			}
+			return deref(resp.CampaignStatus), rejectionReasons(resp.Errors), nil
+		}},
+	}
+
+	var changes []StatusChange
+	var errs []error
+	for _, resource := range resources {
+		key := watchKey{resource.kind, resource.sid}
+		w.mu.Lock()
+		old := w.last[key]
+		w.mu.Unlock()
+		if resource.sid == "" || finalStatuses[resource.kind][old] {
+			continue
+		}
+
+		status, reasons, err := resource.fetch(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if status == old {
+			continue
+		}
+		w.mu.Lock()
+		// The target may have been unwatched or replaced while it was
+		// fetched.
+		current, watched := w.targets[target.LocationID]
+		watched = watched && slices.Contains(current.keys(), key)
+		if watched {
+			w.last[key] = status
+		}
+		w.mu.Unlock()
+		if !watched {
+			continue
+		}
+		change := StatusChange{
+			LocationID: target.LocationID,
+			Kind:       resource.kind,
+			SID:        resource.sid,
+			OldStatus:  old,
+			NewStatus:  status,
+			Reasons:    reasons,
+			Final:      finalStatuses[resource.kind][status],
+			At:         time.Now().UTC(),
+		}
+		w.record(ctx, change)
+		changes = append(changes, change)
+	}
+	return changes, errors.Join(errs...)
+}
+
+// record advances the stored onboarding of change, when the service has a
+// Store, to the state the new status decides, such as StateProfileRejected
+// for a rejected customer profile or StateLive for a verified campaign.
+func (w *StatusWatcher) record(ctx context.Context, change StatusChange) {
+	state := resourceState(change.Kind, change.NewStatus)
+	if state == "" || change.LocationID == "" {
+		return
+	}
+	log := w.service.logger.With("location_id", change.LocationID, "kind", change.Kind, "sid", change.SID)
+	params := &FullA2POnboardingParams{LocationID: change.LocationID}
+	w.service.updateRecord(ctx, log, params, func(record *OnboardingRecord) {
+		record.response(params).advanceThrough(log, statesTo(state)...)
+	})
+}
+
+// rejectionReasons explains the errors Twilio lists on a rejected resource.
+// Entries are objects with a code and a message, or bare codes; codes
+// missing from the catalog keep Twilio's message.
+func rejectionReasons(errs *[]interface{}) []ErrorInfo {
+	if errs == nil {
+		return nil
+	}
+	var reasons []ErrorInfo
+	for _, entry := range *errs {
+		var code int
+		var message string
+		switch entry := entry.(type) {
+		case map[string]interface{}:
+			if value, ok := entry["code"].(float64); ok {
+				code = int(value)
+			}
+			message, _ = entry["message"].(string)
+		case float64:
+			code = int(entry)
+		case string:
+			message = entry
+		default:
+			message = fmt.Sprint(entry)
+		}
+		info, ok := LookupErrorCode(code)
+		if !ok {
+			info = ErrorInfo{Code: code, Category: CategoryCompliance, Description: message, Remediation: "Fix the reported details and submit the resource for review again."}
+		}
+		reasons = append(reasons, info)
+	}
+	return reasons
+}
@@ -0,0 +1,192 @@
+package a2p
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newWatchedOnboarding onboards testParams against fakes with a store and
+// returns the service, the fakes and the watch target of the record.
+func newWatchedOnboarding(t *testing.T) (*A2PService, *FakeTrustHub, *FakeMessaging, WatchTarget) {
+	t.Helper()
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	trustHub, messagingFake, accounts := NewFakeTrustHub(), NewFakeMessaging(), NewFakeAccounts()
+	accounts.AddIncomingPhoneNumber(testParams().TwilioPurchasedPhoneNumber)
+	s := NewA2PService(trustHub, messagingFake, accounts, WithLogger(discardLogger()), WithStore(store))
+	if _, err := s.OnboardCustomer(ctx, testParams()); err != nil {
+		t.Fatal(err)
+	}
+	record, err := store.Load(ctx, "location-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, trustHub, messagingFake, WatchTargetFromRecord(record)
+}
+
+// changesByKind returns changes keyed by their kind.
+func changesByKind(changes []StatusChange) map[ResourceKind]StatusChange {
+	byKind := map[ResourceKind]StatusChange{}
+	for _, change := range changes {
+		byKind[change.Kind] = change
+	}
+	return byKind
+}
+
+func TestStatusWatcherPoll(t *testing.T) {
+	ctx := context.Background()
+	s, trustHub, messagingFake, target := newWatchedOnboarding(t)
+	w := s.NewStatusWatcher(time.Minute, 8)
+	w.Watch(target)
+
+	steps := []struct {
+		name   string
+		change func(t *testing.T)
+		// want maps the kinds expected to change to their new status.
+		want      map[ResourceKind]string
+		wantFinal map[ResourceKind]bool
+		wantState OnboardingState
+	}{
+		{
+			name: "first observation",
+			want: map[ResourceKind]string{
+				ResourceCustomerProfile:   "pending-review",
+				ResourceTrustProduct:      "pending-review",
+				ResourceBrandRegistration: "PENDING",
+			},
+			wantState: StateBrandPending,
+		},
+		{name: "nothing changed", wantState: StateBrandPending},
+		{
+			name: "profile rejected",
+			change: func(t *testing.T) {
+				err := trustHub.SetBundleStatus(target.CustomerProfileSID, "twilio-rejected", map[string]interface{}{"code": float64(22215), "message": "invalid EIN"})
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+			want:      map[ResourceKind]string{ResourceCustomerProfile: "twilio-rejected"},
+			wantFinal: map[ResourceKind]bool{ResourceCustomerProfile: true},
+			wantState: StateProfileRejected,
+		},
+		{
+			name: "final statuses are not fetched",
+			change: func(t *testing.T) {
+				trustHub.FailOn("FetchCustomerProfile", errors.New("fetched a final status"))
+			},
+			wantState: StateProfileRejected,
+		},
+		{
+			name: "brand approved",
+			change: func(t *testing.T) {
+				if err := messagingFake.SetBrandStatus(target.BrandRegistrationSID, "APPROVED"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want:      map[ResourceKind]string{ResourceBrandRegistration: "APPROVED"},
+			wantFinal: map[ResourceKind]bool{ResourceBrandRegistration: true},
+			wantState: StateProfileRejected,
+		},
+	}
+	for _, step := range steps {
+		if step.change != nil {
+			step.change(t)
+		}
+		changes, err := w.Poll(ctx)
+		if err != nil {
+			t.Fatalf("%s: Poll: %v", step.name, err)
+		}
+		byKind := changesByKind(changes)
+		if len(changes) != len(step.want) {
+			t.Errorf("%s: changes %+v, want %v", step.name, changes, step.want)
+		}
+		for kind, status := range step.want {
+			change, ok := byKind[kind]
+			if !ok || change.NewStatus != status || change.Final != step.wantFinal[kind] {
+				t.Errorf("%s: %s change %+v, want status %s, final %t", step.name, kind, change, status, step.wantFinal[kind])
+			}
+		}
+		if change, ok := byKind[ResourceCustomerProfile]; ok && change.NewStatus == "twilio-rejected" && len(change.Reasons) == 0 {
+			t.Errorf("%s: rejection without reasons", step.name)
+		}
+
+		record, err := s.store.Load(ctx, "location-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Response.State != step.wantState {
+			t.Errorf("%s: stored state = %s, want %s", step.name, record.Response.State, step.wantState)
+		}
+	}
+}
+
+func TestStatusWatcherUnwatch(t *testing.T) {
+	ctx := context.Background()
+	s, _, _, target := newWatchedOnboarding(t)
+	w := s.NewStatusWatcher(time.Minute, 8)
+	w.Watch(target)
+	first, err := w.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replacing the target forgets the resources it no longer lists.
+	withoutBrand := target
+	withoutBrand.BrandRegistrationSID = ""
+	w.Watch(withoutBrand)
+	if _, ok := w.last[watchKey{ResourceBrandRegistration, target.BrandRegistrationSID}]; ok {
+		t.Error("brand status kept after the target dropped it")
+	}
+	if changes, err := w.Poll(ctx); err != nil || len(changes) != 0 {
+		t.Errorf("Poll after replacing the target = %+v, %v; want no changes", changes, err)
+	}
+
+	w.Unwatch(target.LocationID)
+	if len(w.last) != 0 {
+		t.Errorf("%d statuses kept after Unwatch", len(w.last))
+	}
+	w.Watch(target)
+	again, err := w.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != len(first) {
+		t.Errorf("%d changes after watching again, want the %d first observations", len(again), len(first))
+	}
+	for _, change := range again {
+		if change.OldStatus != "" {
+			t.Errorf("%s change from %q after watching again, want a first observation", change.Kind, change.OldStatus)
+		}
+	}
+}
+
+func TestStatusWatcherRun(t *testing.T) {
+	s, _, _, target := newWatchedOnboarding(t)
+	w := s.NewStatusWatcher(time.Hour, 8)
+	w.Watch(target)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	change := <-w.Changes()
+	if change.LocationID != target.LocationID || change.OldStatus != "" {
+		t.Errorf("first change %+v, want a first observation of %s", change, target.LocationID)
+	}
+	// The first Run has started, since it sent a change.
+	if err := w.Run(ctx); !errors.Is(err, ErrWatcherStarted) {
+		t.Errorf("second Run = %v, want ErrWatcherStarted", err)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run = %v, want context.Canceled", err)
+	}
+	for range w.Changes() {
+	}
+}